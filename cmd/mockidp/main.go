@@ -0,0 +1,35 @@
+// mockidp run the in-process OIDC provider as standalone server for local development.
+// every authorization is auto approved as the configured user.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"rakamin-evermos/utils/oidcmock"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9999", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer url, must match OIDC_<NAME>_ISSUER")
+	clientID := flag.String("client-id", "rakamin-evermos", "client id")
+	clientSecret := flag.String("client-secret", "secret", "client secret")
+	redirectURL := flag.String("redirect-url", "http://localhost:8080/api/v1/auth/oidc/mock/callback", "registered redirect url")
+	subject := flag.String("sub", "mock-user-1", "subject of logged in user")
+	email := flag.String("email", "mock.user@example.com", "email of logged in user")
+	name := flag.String("name", "Mock User", "name of logged in user")
+	flag.Parse()
+
+	server, err := oidcmock.NewServer(*issuer)
+	if err != nil {
+		log.Fatal("failed create mock idp:", err)
+	}
+	server.AddClient(*clientID, *clientSecret, *redirectURL)
+	server.SetUser(oidcmock.User{Subject: *subject, Email: *email, EmailVerified: true, Name: *name})
+
+	log.Printf("Mock OIDC provider running in %s\n", *issuer)
+	if err := http.ListenAndServe(*addr, server.Handler()); err != nil {
+		log.Fatal("Failed running mock idp:", err)
+	}
+}
@@ -0,0 +1,11 @@
+// Package data hold dataset bundled into the binary.
+package data
+
+import "embed"
+
+// Region is Indonesian region reference (provinsi, kota, kecamatan, kelurahan with kode pos), id follow Kemendagri code.
+// bundled file is only a sample of the csv format (all provinsi, some kota and below) used by test,
+// server refuse to start without REGION_DATA_DIR pointing to the full dataset.
+//
+//go:embed region/*.csv
+var Region embed.FS
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler interface {
+	ExportData(c *gin.Context)
+	DeleteAccount(c *gin.Context)
+	CancelDeletion(c *gin.Context)
+}
+
+type accountHandler struct {
+	accountUsecase usecase.AccountUsecase
+}
+
+func NewAccountHandler(accountUsecase usecase.AccountUsecase) AccountHandler {
+	return &accountHandler{accountUsecase}
+}
+
+// ?format=json (default) or ?format=zip (with uploaded photo)
+func (h *accountHandler) ExportData(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		export, err := h.accountUsecase.ExportData(userID.(uint))
+		if err != nil {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.SendSuccessResponse(c, "Success export data", export)
+	case "zip":
+		// build in memory first, so error can still be sent as json
+		var buf bytes.Buffer
+		if err := h.accountUsecase.ExportZip(userID.(uint), &buf); err != nil {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		fileName := fmt.Sprintf("account-export-%d-%s.zip", userID.(uint), time.Now().Format("20060102"))
+		c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+		c.Data(http.StatusOK, "application/zip", buf.Bytes())
+	default:
+		utils.SendErrorResponse(c, http.StatusBadRequest, "format must be json or zip")
+	}
+}
+
+// only schedule deletion, account anonymized after grace period
+func (h *accountHandler) DeleteAccount(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	issuedAt, _ := c.Get("currentTokenIssuedAt")
+	tokenIssuedAt, _ := issuedAt.(time.Time)
+
+	scheduledAt, err := h.accountUsecase.RequestDeletion(userID.(uint), tokenIssuedAt, c.ClientIP())
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data := gin.H{"deletion_scheduled_at": scheduledAt}
+	utils.SendSuccessResponse(c, "Account will be deleted, login and cancel before this time to keep it", data)
+}
+
+func (h *accountHandler) CancelDeletion(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	if err := h.accountUsecase.CancelDeletion(userID.(uint), c.ClientIP()); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Account deletion cancelled", nil)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -17,6 +18,35 @@ type AddressInput struct {
 	NamaPenerima string `json:"nama_penerima" binding:"required"`
 	NoTelp       string `json:"no_telp" binding:"required"`
 	DetailAlamat string `json:"detail_alamat" binding:"required"`
+	IDProvinsi   uint   `json:"id_provinsi"`
+	IDKota       uint   `json:"id_kota"`
+	IDKecamatan  uint   `json:"id_kecamatan"`
+	IDKelurahan  uint64 `json:"id_kelurahan"`
+	KodePos      string   `json:"kode_pos"`
+	Jalan        string   `json:"jalan"`
+	Catatan      string   `json:"catatan"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+	IsDefault    bool     `json:"is_default"`
+}
+
+func (input AddressInput) toAlamat() model.Alamat {
+	return model.Alamat{
+		JudulAlamat:  input.JudulAlamat,
+		NamaPenerima: input.NamaPenerima,
+		NoTelp:       input.NoTelp,
+		DetailAlamat: input.DetailAlamat,
+		IDProvinsi:   input.IDProvinsi,
+		IDKota:       input.IDKota,
+		IDKecamatan:  input.IDKecamatan,
+		IDKelurahan:  input.IDKelurahan,
+		KodePos:      input.KodePos,
+		Jalan:        input.Jalan,
+		Catatan:      input.Catatan,
+		Latitude:     input.Latitude,
+		Longitude:    input.Longitude,
+		IsDefault:    input.IsDefault,
+	}
 }
 
 type AddressHandler interface {
@@ -24,9 +54,14 @@ type AddressHandler interface {
 	GetAddresses(c *gin.Context)
 	GetAddressByID(c *gin.Context)
 	UpdateAddress(c *gin.Context)
+	SetDefaultAddress(c *gin.Context)
 	DeleteAddress(c *gin.Context)
 }
 
+func isAddressInputError(err error) bool {
+	return errors.Is(err, usecase.ErrRegionNotValid) || errors.Is(err, usecase.ErrAddressNotValid)
+}
+
 type addressHandler struct {
 	addressUsecase usecase.AddressUsecase
 }
@@ -46,15 +81,14 @@ func (h *addressHandler) CreateAddress(c *gin.Context) {
 	}
 
 	// change DTO to model.Alamat
-	alamat := model.Alamat{
-		JudulAlamat:  input.JudulAlamat,
-		NamaPenerima: input.NamaPenerima,
-		NoTelp:       input.NoTelp,
-		DetailAlamat: input.DetailAlamat,
-	}
+	alamat := input.toAlamat()
 
 	savedAlamat, err := h.addressUsecase.CreateAddress(userID.(uint), alamat)
 	if err != nil {
+		if isAddressInputError(err) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -108,15 +142,14 @@ func (h *addressHandler) UpdateAddress(c *gin.Context) {
 	}
 
 	// Mapping DTO to Model
-	inputAlamat := model.Alamat{
-		JudulAlamat:  input.JudulAlamat,
-		NamaPenerima: input.NamaPenerima,
-		NoTelp:       input.NoTelp,
-		DetailAlamat: input.DetailAlamat,
-	}
+	inputAlamat := input.toAlamat()
 
 	updatedAlamat, err := h.addressUsecase.UpdateAddress(uint(addressID), userID.(uint), inputAlamat)
 	if err != nil {
+		if isAddressInputError(err) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
 		return
 	}
@@ -124,6 +157,23 @@ func (h *addressHandler) UpdateAddress(c *gin.Context) {
 	utils.SendSuccessResponse(c, "Success update Alamat", updatedAlamat)
 }
 
+func (h *addressHandler) SetDefaultAddress(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	addressID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID alamat not valid")
+		return
+	}
+
+	alamat, err := h.addressUsecase.SetDefaultAddress(uint(addressID), userID.(uint))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success set default Alamat", alamat)
+}
+
 func (h *addressHandler) DeleteAddress(c *gin.Context) {
 	userID, _ := c.Get("currentUserID")
 	addressID, err := strconv.Atoi(c.Param("id"))
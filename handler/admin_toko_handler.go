@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"rakamin-evermos/repository"
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewTokoInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type AdminTokoHandler interface {
+	GetReviewQueue(c *gin.Context)
+	GetTokoByID(c *gin.Context)
+	GetDokumenFile(c *gin.Context)
+	ApproveToko(c *gin.Context)
+	RejectToko(c *gin.Context)
+	SuspendToko(c *gin.Context)
+	UnsuspendToko(c *gin.Context)
+}
+
+type adminTokoHandler struct {
+	adminTokoUsecase usecase.AdminTokoUsecase
+}
+
+func NewAdminTokoHandler(adminTokoUsecase usecase.AdminTokoUsecase) AdminTokoHandler {
+	return &adminTokoHandler{adminTokoUsecase}
+}
+
+func parseTokoID(c *gin.Context) (uint, bool) {
+	tokoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID toko not valid")
+		return 0, false
+	}
+	return uint(tokoID), true
+}
+
+// filter: ?status=pending_review&search=, oldest submission first
+func (h *adminTokoHandler) GetReviewQueue(c *gin.Context) {
+	pagination := utils.GetPaginationFromQuery(c)
+	filter := repository.TokoReviewFilterInput{
+		Status: c.Query("status"),
+		Search: c.Query("search"),
+	}
+
+	result, err := h.adminTokoUsecase.GetReviewQueue(pagination, filter)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get toko", result)
+}
+
+func (h *adminTokoHandler) GetTokoByID(c *gin.Context) {
+	tokoID, ok := parseTokoID(c)
+	if !ok {
+		return
+	}
+
+	toko, err := h.adminTokoUsecase.GetTokoByID(tokoID)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get toko", toko)
+}
+
+// dokumen file not served as static file, only through this endpoint
+func (h *adminTokoHandler) GetDokumenFile(c *gin.Context) {
+	tokoID, ok := parseTokoID(c)
+	if !ok {
+		return
+	}
+	dokumenID, err := strconv.Atoi(c.Param("dokumenId"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID dokumen not valid")
+		return
+	}
+
+	dokumen, err := h.adminTokoUsecase.GetDokumen(tokoID, uint(dokumenID))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(dokumen.Url, dokumen.NamaFile)
+}
+
+func (h *adminTokoHandler) ApproveToko(c *gin.Context) {
+	actorID, _ := c.Get("currentUserID")
+	tokoID, ok := parseTokoID(c)
+	if !ok {
+		return
+	}
+
+	toko, err := h.adminTokoUsecase.ApproveToko(actorID.(uint), tokoID, c.ClientIP())
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success approve toko", toko)
+}
+
+func (h *adminTokoHandler) RejectToko(c *gin.Context) {
+	actorID, _ := c.Get("currentUserID")
+	tokoID, ok := parseTokoID(c)
+	if !ok {
+		return
+	}
+
+	var input ReviewTokoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	toko, err := h.adminTokoUsecase.RejectToko(actorID.(uint), tokoID, input.Reason, c.ClientIP())
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success reject toko", toko)
+}
+
+func (h *adminTokoHandler) SuspendToko(c *gin.Context) {
+	actorID, _ := c.Get("currentUserID")
+	tokoID, ok := parseTokoID(c)
+	if !ok {
+		return
+	}
+
+	var input ReviewTokoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	toko, err := h.adminTokoUsecase.SuspendToko(actorID.(uint), tokoID, input.Reason, c.ClientIP())
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success suspend toko", toko)
+}
+
+func (h *adminTokoHandler) UnsuspendToko(c *gin.Context) {
+	actorID, _ := c.Get("currentUserID")
+	tokoID, ok := parseTokoID(c)
+	if !ok {
+		return
+	}
+
+	toko, err := h.adminTokoUsecase.UnsuspendToko(actorID.(uint), tokoID, c.ClientIP())
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success unsuspend toko", toko)
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuspendUserInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// user data for admin, no password
+type AdminUserResponse struct {
+	ID              uint       `json:"id"`
+	Nama            string     `json:"nama"`
+	Email           string     `json:"email"`
+	NoTelp          string     `json:"no_telp"`
+	IsAdmin         bool       `json:"is_admin"`
+	Roles           []string   `json:"roles"`
+	SuspendedAt     *time.Time `json:"suspended_at"`
+	SuspendedReason string     `json:"suspended_reason"`
+	CreatedAtDate   time.Time  `json:"created_at_date"`
+	UpdatedAtDate   time.Time  `json:"updated_at_date"`
+}
+
+func toAdminUserResponse(user model.User) AdminUserResponse {
+	roles := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roles = append(roles, role.Name)
+	}
+	return AdminUserResponse{
+		ID:              user.ID,
+		Nama:            user.Nama,
+		Email:           user.Email,
+		NoTelp:          user.NoTelp,
+		IsAdmin:         user.IsAdmin,
+		Roles:           roles,
+		SuspendedAt:     user.SuspendedAt,
+		SuspendedReason: user.SuspendedReason,
+		CreatedAtDate:   user.CreatedAtDate,
+		UpdatedAtDate:   user.UpdatedAtDate,
+	}
+}
+
+type AdminUserHandler interface {
+	GetUsers(c *gin.Context)
+	GetUserByID(c *gin.Context)
+	SuspendUser(c *gin.Context)
+	UnsuspendUser(c *gin.Context)
+	GetAuditLogs(c *gin.Context)
+}
+
+type adminUserHandler struct {
+	adminUserUsecase usecase.AdminUserUsecase
+}
+
+func NewAdminUserHandler(adminUserUsecase usecase.AdminUserUsecase) AdminUserHandler {
+	return &adminUserHandler{adminUserUsecase}
+}
+
+// "true" / "false" query become *bool, other value ignored
+func parseBoolQuery(c *gin.Context, key string) *bool {
+	value, err := strconv.ParseBool(c.Query(key))
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// filter: ?search=&role=&is_admin=&suspended=
+func (h *adminUserHandler) GetUsers(c *gin.Context) {
+	pagination := utils.GetPaginationFromQuery(c)
+	filter := repository.UserFilterInput{
+		Search:    c.Query("search"),
+		Role:      c.Query("role"),
+		IsAdmin:   parseBoolQuery(c, "is_admin"),
+		Suspended: parseBoolQuery(c, "suspended"),
+	}
+
+	result, err := h.adminUserUsecase.GetUsers(pagination, filter)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	users, _ := result.Data.([]model.User)
+	response := make([]AdminUserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, toAdminUserResponse(user))
+	}
+	result.Data = response
+
+	utils.SendSuccessResponse(c, "Success get users", result)
+}
+
+func (h *adminUserHandler) GetUserByID(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID user not valid")
+		return
+	}
+
+	user, err := h.adminUserUsecase.GetUserByID(uint(userID))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get user", toAdminUserResponse(user))
+}
+
+func (h *adminUserHandler) SuspendUser(c *gin.Context) {
+	actorID, _ := c.Get("currentUserID")
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID user not valid")
+		return
+	}
+
+	var input SuspendUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.adminUserUsecase.SuspendUser(actorID.(uint), uint(userID), input.Reason, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, usecase.ErrSuspendNotAllowed) {
+			utils.SendErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success suspend user", toAdminUserResponse(user))
+}
+
+func (h *adminUserHandler) UnsuspendUser(c *gin.Context) {
+	actorID, _ := c.Get("currentUserID")
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID user not valid")
+		return
+	}
+
+	user, err := h.adminUserUsecase.UnsuspendUser(actorID.(uint), uint(userID), c.ClientIP())
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success unsuspend user", toAdminUserResponse(user))
+}
+
+// filter: ?actor_id=&action=&target_type=&target_id=
+func (h *adminUserHandler) GetAuditLogs(c *gin.Context) {
+	pagination := utils.GetPaginationFromQuery(c)
+	actorID, _ := strconv.Atoi(c.Query("actor_id"))
+	targetID, _ := strconv.Atoi(c.Query("target_id"))
+	filter := repository.AuditLogFilter{
+		ActorID:    uint(actorID),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   uint(targetID),
+	}
+
+	result, err := h.adminUserUsecase.GetAuditLogs(filter, pagination)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get audit logs", result)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyInput struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// define api key response, hash never returned
+type APIKeyResponse struct {
+	ID            uint       `json:"id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`
+	Scopes        []string   `json:"scopes"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAtDate time.Time  `json:"created_at_date"`
+	Key           string     `json:"key,omitempty"` // only filled on create
+}
+
+func toAPIKeyResponse(apiKey model.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:            apiKey.ID,
+		Name:          apiKey.Name,
+		Prefix:        apiKey.Prefix,
+		Scopes:        apiKey.ScopeList(),
+		LastUsedAt:    apiKey.LastUsedAt,
+		ExpiresAt:     apiKey.ExpiresAt,
+		RevokedAt:     apiKey.RevokedAt,
+		CreatedAtDate: apiKey.CreatedAtDate,
+	}
+}
+
+type APIKeyHandler interface {
+	CreateAPIKey(c *gin.Context)
+	GetAPIKeys(c *gin.Context)
+	RevokeAPIKey(c *gin.Context)
+}
+
+type apiKeyHandler struct {
+	apiKeyUsecase usecase.APIKeyUsecase
+}
+
+func NewAPIKeyHandler(apiKeyUsecase usecase.APIKeyUsecase) APIKeyHandler {
+	return &apiKeyHandler{apiKeyUsecase}
+}
+
+func (h *apiKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	var input APIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apiKey, rawKey, err := h.apiKeyUsecase.CreateAPIKey(userID.(uint), input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// raw key only shown once, save it now
+	response := toAPIKeyResponse(apiKey)
+	response.Key = rawKey
+	utils.SendCreatedResponse(c, "Success create api key", response)
+}
+
+func (h *apiKeyHandler) GetAPIKeys(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	apiKeys, err := h.apiKeyUsecase.GetAPIKeys(userID.(uint))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		response = append(response, toAPIKeyResponse(apiKey))
+	}
+	utils.SendSuccessResponse(c, "Success get api keys", response)
+}
+
+func (h *apiKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	apiKeyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID api key not valid")
+		return
+	}
+
+	if err := h.apiKeyUsecase.RevokeAPIKey(userID.(uint), uint(apiKeyID)); err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success revoke api key", nil)
+}
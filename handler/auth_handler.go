@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"rakamin-evermos/model"
 	"rakamin-evermos/usecase"
@@ -23,9 +25,27 @@ type LoginInput struct {
 	KataSandi string `json:"kata_sandi" binding:"required"`
 }
 
+type VerifyTwoFactorInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type UnlockLoginInput struct {
+	Email     string `json:"email"`
+	IPAddress string `json:"ip_address"`
+}
+
 type AuthHandler interface {
 	Register(c *gin.Context)
 	Login(c *gin.Context)
+	VerifyTwoFactor(c *gin.Context)
+	JWKS(c *gin.Context)
+	OIDCLogin(c *gin.Context)
+	OIDCCallback(c *gin.Context)
+
+	// admin only
+	GetLoginAttempts(c *gin.Context)
+	UnlockLogin(c *gin.Context)
 }
 
 type authHandler struct {
@@ -68,12 +88,138 @@ func (h *authHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authUsecase.Login(input.Email, input.KataSandi)
+	result, err := h.authUsecase.Login(input.Email, input.KataSandi, c.ClientIP())
+	if err != nil {
+		sendLoginError(c, err)
+		return
+	}
+
+	if result.TwoFactorRequired {
+		utils.SendSuccessResponse(c, "Kode 2FA dibutuhkan", result)
+		return
+	}
+
+	data := gin.H{"token": result.Token}
+	utils.SendSuccessResponse(c, "Login berhasil", data)
+}
+
+func (h *authHandler) VerifyTwoFactor(c *gin.Context) {
+	var input VerifyTwoFactorInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token, err := h.authUsecase.VerifyTwoFactor(input.ChallengeToken, input.Code, c.ClientIP())
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusUnauthorized, err.Error())
+		sendLoginError(c, err)
 		return
 	}
 
 	data := gin.H{"token": token}
 	utils.SendSuccessResponse(c, "Login berhasil", data)
-}
\ No newline at end of file
+}
+
+// public keys for other service to verify our token
+// not wrapped in APIResponse, client library expect plain JWKS format
+func (h *authHandler) JWKS(c *gin.Context) {
+	jwks, err := utils.GetJWKS()
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
+
+// return authorization url of provider, ?redirect=true send browser there directly
+func (h *authHandler) OIDCLogin(c *gin.Context) {
+	authURL, err := h.authUsecase.StartOIDCLogin(c.Param("provider"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if c.Query("redirect") == "true" {
+		c.Redirect(http.StatusFound, authURL)
+		return
+	}
+
+	data := gin.H{"authorization_url": authURL}
+	utils.SendSuccessResponse(c, "Success create login url", data)
+}
+
+// redirect_uri registered at provider must point here
+func (h *authHandler) OIDCCallback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "login provider error: "+errCode+" "+c.Query("error_description"))
+		return
+	}
+	if c.Query("code") == "" || c.Query("state") == "" {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "code and state is required")
+		return
+	}
+
+	result, err := h.authUsecase.FinishOIDCLogin(c.Param("provider"), c.Query("code"), c.Query("state"), c.ClientIP())
+	if err != nil {
+		sendLoginError(c, err)
+		return
+	}
+
+	if result.TwoFactorRequired {
+		utils.SendSuccessResponse(c, "Kode 2FA dibutuhkan", result)
+		return
+	}
+
+	data := gin.H{"token": result.Token}
+	utils.SendSuccessResponse(c, "Login berhasil", data)
+}
+
+// 429 with Retry-After when locked, 403 when suspended, 409 when email must be linked first, else 401
+func sendLoginError(c *gin.Context, err error) {
+	var lockedErr *usecase.LoginLockedError
+	if errors.As(err, &lockedErr) {
+		c.Header("Retry-After", strconv.Itoa(int(lockedErr.RetryAfter.Seconds())+1))
+		utils.SendErrorResponse(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if errors.Is(err, usecase.ErrAccountSuspended) {
+		utils.SendErrorResponse(c, http.StatusForbidden, err.Error())
+		return
+	}
+	if errors.Is(err, usecase.ErrOIDCEmailRegistered) {
+		utils.SendErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	utils.SendErrorResponse(c, http.StatusUnauthorized, err.Error())
+}
+
+// audit trail login, can filter by ?email=
+func (h *authHandler) GetLoginAttempts(c *gin.Context) {
+	pagination := utils.GetPaginationFromQuery(c)
+
+	result, err := h.authUsecase.GetLoginAttempts(c.Query("email"), pagination)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get login attempts", result)
+}
+
+func (h *authHandler) UnlockLogin(c *gin.Context) {
+	var input UnlockLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.authUsecase.UnlockLogin(input.Email, input.IPAddress); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success unlock login", nil)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -13,6 +14,48 @@ import (
 
 type CategoryInput struct {
 	NamaCategory string `json:"nama_category" binding:"required"`
+	IDParent     *uint  `json:"id_parent"` // null = root kategori
+	Slug         string `json:"slug"`      // optional, made from nama_category when empty
+	Urutan       int    `json:"urutan"`
+}
+
+// kode and tipe ignored on update
+type AtributInput struct {
+	Kode   string   `json:"kode"`
+	Nama   string   `json:"nama"`
+	Tipe   string   `json:"tipe"` // string, number or enum
+	Opsi   []string `json:"opsi"` // enum only
+	Satuan string   `json:"satuan"`
+	Wajib  bool     `json:"wajib"`
+	Urutan int      `json:"urutan"`
+}
+
+func (input AtributInput) toModel() model.CategoryAtribut {
+	return model.CategoryAtribut{
+		Kode:   input.Kode,
+		Nama:   input.Nama,
+		Tipe:   input.Tipe,
+		Opsi:   input.Opsi,
+		Satuan: input.Satuan,
+		Wajib:  input.Wajib,
+		Urutan: input.Urutan,
+	}
+}
+
+type MergeCategoryInput struct {
+	TargetID uint `json:"target_id" binding:"required"`
+}
+
+// 400 for input not valid, 409 when still used, other error use defaultCode
+func sendCategoryError(c *gin.Context, err error, defaultCode int) {
+	switch {
+	case errors.Is(err, usecase.ErrCategoryNotValid):
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrCategoryInUse):
+		utils.SendErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		utils.SendErrorResponse(c, defaultCode, err.Error())
+	}
 }
 
 type CategoryHandler interface {
@@ -21,6 +64,17 @@ type CategoryHandler interface {
 	GetCategoryByID(c *gin.Context)
 	UpdateCategory(c *gin.Context)
 	DeleteCategory(c *gin.Context)
+	MergeCategory(c *gin.Context)
+
+	// atribut schema
+	GetAtribut(c *gin.Context)
+	CreateAtribut(c *gin.Context)
+	UpdateAtribut(c *gin.Context)
+	DeleteAtribut(c *gin.Context)
+
+	// public
+	GetCategoryTree(c *gin.Context)
+	GetCategoryNode(c *gin.Context)
 }
 
 type categoryHandler struct {
@@ -40,11 +94,14 @@ func (h *categoryHandler) CreateCategory(c *gin.Context) {
 
 	category := model.Category{
 		NamaCategory: input.NamaCategory,
+		IDParent:     input.IDParent,
+		Slug:         input.Slug,
+		Urutan:       input.Urutan,
 	}
 
 	savedCategory, err := h.categoryUsecase.CreateCategory(category)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		sendCategoryError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -92,29 +149,172 @@ func (h *categoryHandler) UpdateCategory(c *gin.Context) {
 
 	inputCategory := model.Category{
 		NamaCategory: input.NamaCategory,
+		IDParent:     input.IDParent,
+		Slug:         input.Slug,
+		Urutan:       input.Urutan,
 	}
 
 	updatedCategory, err := h.categoryUsecase.UpdateCategory(uint(categoryID), inputCategory)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		sendCategoryError(c, err, http.StatusNotFound)
 		return
 	}
 
 	utils.SendSuccessResponse(c, "Success update kategori", updatedCategory)
 }
 
+// ?target_id= move produk and sub kategori to target before delete
 func (h *categoryHandler) DeleteCategory(c *gin.Context) {
 	categoryID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.SendErrorResponse(c, http.StatusBadRequest, "ID kategori not valid")
 		return
 	}
+	var targetID uint64
+	if c.Query("target_id") != "" {
+		targetID, err = strconv.ParseUint(c.Query("target_id"), 10, 32)
+		if err != nil || targetID == 0 {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "target_id not valid")
+			return
+		}
+	}
 
-	err = h.categoryUsecase.DeleteCategory(uint(categoryID))
+	err = h.categoryUsecase.DeleteCategory(uint(categoryID), uint(targetID))
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		sendCategoryError(c, err, http.StatusNotFound)
 		return
 	}
 
 	utils.SendSuccessResponse(c, "Success delete kategori", nil)
-}
\ No newline at end of file
+}
+
+func (h *categoryHandler) MergeCategory(c *gin.Context) {
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID kategori not valid")
+		return
+	}
+
+	var input MergeCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.categoryUsecase.MergeCategory(uint(categoryID), input.TargetID)
+	if err != nil {
+		sendCategoryError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success merge kategori", result)
+}
+
+func (h *categoryHandler) GetCategoryTree(c *gin.Context) {
+	tree, err := h.categoryUsecase.GetCategoryTree()
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	utils.SendSuccessResponse(c, "Success get kategori tree", tree)
+}
+
+// :id can be ID kategori or slug
+func (h *categoryHandler) GetCategoryNode(c *gin.Context) {
+	node, err := h.categoryUsecase.GetCategoryNode(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get Detail kategori", node)
+}
+
+// include atribut of parent kategori, buyer use kode for ?atribut[kode]= filter
+func (h *categoryHandler) GetAtribut(c *gin.Context) {
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID kategori not valid")
+		return
+	}
+
+	atribut, err := h.categoryUsecase.GetAtribut(uint(categoryID))
+	if err != nil {
+		sendCategoryError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get atribut kategori", atribut)
+}
+
+func (h *categoryHandler) CreateAtribut(c *gin.Context) {
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID kategori not valid")
+		return
+	}
+
+	var input AtributInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	atribut, err := h.categoryUsecase.CreateAtribut(uint(categoryID), input.toModel())
+	if err != nil {
+		sendCategoryError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendCreatedResponse(c, "Success create atribut kategori", atribut)
+}
+
+func (h *categoryHandler) UpdateAtribut(c *gin.Context) {
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID kategori not valid")
+		return
+	}
+	atributID, err := strconv.Atoi(c.Param("atributId"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID atribut not valid")
+		return
+	}
+
+	var input AtributInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	atribut, err := h.categoryUsecase.UpdateAtribut(uint(categoryID), uint(atributID), input.toModel())
+	if err != nil {
+		sendCategoryError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success update atribut kategori", atribut)
+}
+
+// nilai atribut in produk also deleted
+func (h *categoryHandler) DeleteAtribut(c *gin.Context) {
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID kategori not valid")
+		return
+	}
+	atributID, err := strconv.Atoi(c.Param("atributId"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID atribut not valid")
+		return
+	}
+
+	if err := h.categoryUsecase.DeleteAtribut(uint(categoryID), uint(atributID)); err != nil {
+		sendCategoryError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success delete atribut kategori", nil)
+}
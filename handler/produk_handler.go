@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
@@ -8,7 +9,9 @@ import (
 	"rakamin-evermos/repository"
 	"rakamin-evermos/usecase"
 	"rakamin-evermos/utils"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -22,6 +25,54 @@ type InputProduk struct {
 	Stok          int    `json:"stok" binding:"required"`
 	Deskripsi     string `json:"deskripsi" binding:"required"`
 	IDCategory    uint   `json:"id_category" binding:"required"`
+	Status        string `json:"status"` // draft / published (default), archived on update only
+
+	// nilai by kode atribut kategori, e.g. {"brand": "Asus", "ram": 16}
+	Atribut map[string]interface{} `json:"atribut"`
+}
+
+type ProdukStatusInput struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// max atribut filter in one request, keep query small
+const maxAtributFilter = 10
+
+// 400 for produk input not valid, other error same as toko access
+func sendProdukError(c *gin.Context, err error, defaultCode int) {
+	if errors.Is(err, usecase.ErrProdukNotValid) {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	sendTokoAccessError(c, err, defaultCode)
+}
+
+// ?atribut[kode]=nilai for exact match, ?atribut[kode]=min..max for number range (one side can be empty)
+func parseAtributFilter(c *gin.Context) []repository.AtributFilter {
+	query := c.QueryMap("atribut")
+	kodes := make([]string, 0, len(query))
+	for kode := range query {
+		kodes = append(kodes, kode)
+	}
+	sort.Strings(kodes)
+	if len(kodes) > maxAtributFilter {
+		kodes = kodes[:maxAtributFilter]
+	}
+
+	filters := make([]repository.AtributFilter, 0, len(kodes))
+	for _, kode := range kodes {
+		filter := repository.AtributFilter{Kode: kode, Nilai: query[kode]}
+		if from, to, isRange := strings.Cut(query[kode], ".."); isRange {
+			if min, err := strconv.ParseFloat(from, 64); err == nil {
+				filter.Min = &min
+			}
+			if max, err := strconv.ParseFloat(to, 64); err == nil {
+				filter.Max = &max
+			}
+		}
+		filters = append(filters, filter)
+	}
+	return filters
 }
 
 type ProdukHandler interface {
@@ -29,12 +80,16 @@ type ProdukHandler interface {
 	// Publik
 	GetAllProduk(c *gin.Context)
 	GetProdukByID(c *gin.Context)
+	GetSuggestions(c *gin.Context)
 
 	// Seller
 	CreateProduk(c *gin.Context)
 	GetMyProduk(c *gin.Context)
 	UpdateProduk(c *gin.Context)
+	SetProdukStatus(c *gin.Context)
 	DeleteProduk(c *gin.Context)
+	GetDeletedProduk(c *gin.Context)
+	RestoreProduk(c *gin.Context)
 	UploadFotoProduk(c *gin.Context)
 }
 
@@ -55,17 +110,60 @@ func parseFilterAndPagination(c *gin.Context) (utils.PaginationInput, repository
 	search := c.Query("search")
 	categoryID, _ := strconv.Atoi(c.Query("category_id"))
 
+	tokoID, _ := strconv.Atoi(c.Query("toko_id"))
+	inStock, _ := strconv.ParseBool(c.Query("in_stock"))
+
 	filter := repository.FilterInput{
 		Search:     search,
 		CategoryID: uint(categoryID),
+		Atribut:    parseAtributFilter(c),
+		MinHarga:   parseHargaQuery(c.Query("min_harga")),
+		MaxHarga:   parseHargaQuery(c.Query("max_harga")),
+		InStock:    inStock,
+		TokoID:     uint(tokoID),
+		Status:     c.Query("status"),
+		Sort:       parseSortQuery(c.Query("sort")),
 	}
 
 	return pagination, filter
 }
 
+// empty or not valid harga = no filter
+func parseHargaQuery(value string) *int64 {
+	harga, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || harga < 0 {
+		return nil
+	}
+	return &harga
+}
+
+// unknown sort = default order
+func parseSortQuery(value string) string {
+	switch value {
+	case repository.SortNewest, repository.SortPriceAsc, repository.SortPriceDesc, repository.SortBestSelling, repository.SortRating:
+		return value
+	}
+	return ""
+}
+
+// ?cursor= (empty for first page) switch to cursor pagination, else page & limit
 func (h *produkHandler) GetAllProduk(c *gin.Context) {
 	pagination, filter := parseFilterAndPagination(c)
 
+	if cursor, ok := utils.GetCursorFromQuery(c); ok {
+		result, err := h.produkUsecase.GetAllProdukCursor(cursor, filter)
+		if err != nil {
+			if errors.Is(err, utils.ErrCursorNotValid) {
+				utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.SendSuccessResponse(c, "Success get all produk", result)
+		return
+	}
+
 	result, err := h.produkUsecase.GetAllProduk(pagination, filter)
 	if err != nil {
 		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
@@ -91,10 +189,27 @@ func (h *produkHandler) GetProdukByID(c *gin.Context) {
 	utils.SendSuccessResponse(c, "Success get Detail produk", produk)
 }
 
+// ?q= typed text, ?limit= max 20
+func (h *produkHandler) GetSuggestions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	suggestions, err := h.produkUsecase.GetSuggestions(c.Query("q"), limit)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get suggestion", suggestions)
+}
+
 // seller only
 
 func (h *produkHandler) CreateProduk(c *gin.Context) {
 	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
 
 	var input InputProduk
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -110,11 +225,12 @@ func (h *produkHandler) CreateProduk(c *gin.Context) {
 		Stok:          input.Stok,
 		Deskripsi:     input.Deskripsi,
 		IDCategory:    input.IDCategory,
+		Status:        input.Status,
 	}
 
-	savedProduk, err := h.produkUsecase.CreateProduk(userID.(uint), produk)
+	savedProduk, err := h.produkUsecase.CreateProduk(userID.(uint), tokoID, produk, input.Atribut)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		sendProdukError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -123,12 +239,30 @@ func (h *produkHandler) CreateProduk(c *gin.Context) {
 
 func (h *produkHandler) GetMyProduk(c *gin.Context) {
 	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
 
 	pagination, filter := parseFilterAndPagination(c)
 
-	result, err := h.produkUsecase.GetMyProduk(userID.(uint), pagination, filter)
+	if cursor, ok := utils.GetCursorFromQuery(c); ok {
+		result, err := h.produkUsecase.GetMyProdukCursor(userID.(uint), tokoID, cursor, filter)
+		if err != nil {
+			if errors.Is(err, utils.ErrCursorNotValid) {
+				utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			sendTokoAccessError(c, err, http.StatusInternalServerError)
+			return
+		}
+		utils.SendSuccessResponse(c, "Success get my produk", result)
+		return
+	}
+
+	result, err := h.produkUsecase.GetMyProduk(userID.(uint), tokoID, pagination, filter)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -137,6 +271,10 @@ func (h *produkHandler) GetMyProduk(c *gin.Context) {
 
 func (h *produkHandler) UpdateProduk(c *gin.Context) {
 	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
 	produkID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.SendErrorResponse(c, http.StatusBadRequest, "ID produk not valid")
@@ -157,36 +295,111 @@ func (h *produkHandler) UpdateProduk(c *gin.Context) {
 		Stok:          input.Stok,
 		Deskripsi:     input.Deskripsi,
 		IDCategory:    input.IDCategory,
+		Status:        input.Status,
 	}
 
-	updatedProduk, err := h.produkUsecase.UpdateProduk(userID.(uint), uint(produkID), produk)
+	updatedProduk, err := h.produkUsecase.UpdateProduk(userID.(uint), tokoID, uint(produkID), produk, input.Atribut)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		sendProdukError(c, err, http.StatusNotFound)
 		return
 	}
 
 	utils.SendSuccessResponse(c, "Success update produk", updatedProduk)
 }
 
+// draft, published or archived
+func (h *produkHandler) SetProdukStatus(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+	produkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID produk not valid")
+		return
+	}
+
+	var input ProdukStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updatedProduk, err := h.produkUsecase.SetProdukStatus(userID.(uint), tokoID, uint(produkID), input.Status)
+	if err != nil {
+		sendProdukError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success update status produk", updatedProduk)
+}
+
 func (h *produkHandler) DeleteProduk(c *gin.Context) {
 	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
 	produkID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.SendErrorResponse(c, http.StatusBadRequest, "ID produk not valid")
 		return
 	}
 
-	err = h.produkUsecase.DeleteProduk(userID.(uint), uint(produkID))
+	err = h.produkUsecase.DeleteProduk(userID.(uint), tokoID, uint(produkID))
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		sendTokoAccessError(c, err, http.StatusNotFound)
 		return
 	}
 
 	utils.SendSuccessResponse(c, "Success delete produk", nil)
 }
 
+// trash, produk can be restored until purged
+func (h *produkHandler) GetDeletedProduk(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.produkUsecase.GetDeletedProduk(userID.(uint), tokoID, utils.GetPaginationFromQuery(c))
+	if err != nil {
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get deleted produk", result)
+}
+
+func (h *produkHandler) RestoreProduk(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+	produkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID produk not valid")
+		return
+	}
+
+	restoredProduk, err := h.produkUsecase.RestoreProduk(userID.(uint), tokoID, uint(produkID))
+	if err != nil {
+		sendProdukError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success restore produk", restoredProduk)
+}
+
 func (h *produkHandler) UploadFotoProduk(c *gin.Context) {
 	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
 	produkID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.SendErrorResponse(c, http.StatusBadRequest, "ID produk not valid")
@@ -209,9 +422,9 @@ func (h *produkHandler) UploadFotoProduk(c *gin.Context) {
 		return
 	}
 
-	savedFoto, err := h.produkUsecase.UploadFotoProduk(userID.(uint), uint(produkID), filePath)
+	savedFoto, err := h.produkUsecase.UploadFotoProduk(userID.(uint), tokoID, uint(produkID), filePath)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var spreadsheetContentType = map[string]string{
+	utils.SpreadsheetCSV:  "text/csv",
+	utils.SpreadsheetXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type ProdukImportHandler interface {
+	ImportProduk(c *gin.Context)
+	GetImport(c *gin.Context)
+	ExportProduk(c *gin.Context)
+}
+
+type produkImportHandler struct {
+	produkImportUsecase usecase.ProdukImportUsecase
+}
+
+func NewProdukImportHandler(produkImportUsecase usecase.ProdukImportUsecase) ProdukImportHandler {
+	return &produkImportHandler{produkImportUsecase}
+}
+
+// file processed in background, poll GET /my-produk/import/:id for result
+func (h *produkImportHandler) ImportProduk(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "File upload not found (key must be 'file')")
+		return
+	}
+	format := utils.SpreadsheetFormatFromName(file.Filename)
+	if format == "" {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "File must be .csv or .xlsx")
+		return
+	}
+	if file.Size > usecase.MaxImportFileSize {
+		utils.SendErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("File max %d MB", usecase.MaxImportFileSize>>20))
+		return
+	}
+
+	// create unique file uploads/import-[userID]-[uuid].[ext]
+	filePath := fmt.Sprintf("uploads/import-%d-%s.%s", userID.(uint), uuid.New().String(), format)
+	if err := c.SaveUploadedFile(file, filePath); err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to save file")
+		return
+	}
+
+	job, err := h.produkImportUsecase.ImportProduk(userID.(uint), tokoID, format, file.Filename, filePath)
+	if err != nil {
+		sendProdukError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendCreatedResponse(c, "Success upload import produk, file is processed in background", job)
+}
+
+func (h *produkImportHandler) GetImport(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+	importID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID import not valid")
+		return
+	}
+
+	job, err := h.produkImportUsecase.GetImport(userID.(uint), tokoID, uint(importID))
+	if err != nil {
+		sendTokoAccessError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get import produk", job)
+}
+
+// ?format=csv (default) or xlsx, same column as import so file can be edited and uploaded again
+func (h *produkImportHandler) ExportProduk(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", utils.SpreadsheetCSV)
+	contentType, known := spreadsheetContentType[format]
+	if !known {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "format must be csv or xlsx")
+		return
+	}
+
+	response := &exportResponseWriter{c: c, format: format, contentType: contentType}
+	writer, err := utils.NewSpreadsheetWriter(response, format)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer writer.Close()
+
+	err = h.produkImportUsecase.ExportProduk(userID.(uint), tokoID, writer)
+	if err == nil {
+		return
+	}
+	// header and part of file already sent, can't change to json error anymore
+	if response.started {
+		fmt.Printf("failed export produk of toko %d: %v\n", tokoID, err)
+		c.Abort()
+		return
+	}
+	sendTokoAccessError(c, err, http.StatusInternalServerError)
+}
+
+// send file header on first byte, so error before that still sent as json
+type exportResponseWriter struct {
+	c           *gin.Context
+	format      string
+	contentType string
+	started     bool
+}
+
+func (w *exportResponseWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="produk.%s"`, w.format))
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
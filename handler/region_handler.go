@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RegionHandler interface {
+	GetProvinsi(c *gin.Context)
+	GetKota(c *gin.Context)
+	GetKecamatan(c *gin.Context)
+	GetKelurahan(c *gin.Context)
+	GetKelurahanByKodePos(c *gin.Context)
+}
+
+type regionHandler struct {
+	regionUsecase usecase.RegionUsecase
+}
+
+func NewRegionHandler(regionUsecase usecase.RegionUsecase) RegionHandler {
+	return &regionHandler{regionUsecase}
+}
+
+// reference data rarely change, client can cache it
+func setRegionCache(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=86400")
+}
+
+// every list accept ?search= on nama
+func (h *regionHandler) GetProvinsi(c *gin.Context) {
+	provinsi, err := h.regionUsecase.GetProvinsi(c.Query("search"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setRegionCache(c)
+	utils.SendSuccessResponse(c, "Success get provinsi", provinsi)
+}
+
+func (h *regionHandler) GetKota(c *gin.Context) {
+	provinsiID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID provinsi not valid")
+		return
+	}
+
+	kota, err := h.regionUsecase.GetKota(uint(provinsiID), c.Query("search"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	setRegionCache(c)
+	utils.SendSuccessResponse(c, "Success get kota", kota)
+}
+
+func (h *regionHandler) GetKecamatan(c *gin.Context) {
+	kotaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID kota not valid")
+		return
+	}
+
+	kecamatan, err := h.regionUsecase.GetKecamatan(uint(kotaID), c.Query("search"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	setRegionCache(c)
+	utils.SendSuccessResponse(c, "Success get kecamatan", kecamatan)
+}
+
+func (h *regionHandler) GetKelurahan(c *gin.Context) {
+	kecamatanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID kecamatan not valid")
+		return
+	}
+
+	kelurahan, err := h.regionUsecase.GetKelurahan(uint(kecamatanID), c.Query("search"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	setRegionCache(c)
+	utils.SendSuccessResponse(c, "Success get kelurahan", kelurahan)
+}
+
+func (h *regionHandler) GetKelurahanByKodePos(c *gin.Context) {
+	kelurahan, err := h.regionUsecase.GetKelurahanByKodePos(c.Param("kode"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	setRegionCache(c)
+	utils.SendSuccessResponse(c, "Success get kelurahan", kelurahan)
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleInput struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateRoleInput struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type AssignRolesInput struct {
+	Roles []string `json:"roles"`
+}
+
+type RoleHandler interface {
+	GetRoles(c *gin.Context)
+	GetPermissions(c *gin.Context)
+	CreateRole(c *gin.Context)
+	UpdateRole(c *gin.Context)
+	GetUserRoles(c *gin.Context)
+	AssignUserRoles(c *gin.Context)
+}
+
+type roleHandler struct {
+	roleUsecase usecase.RoleUsecase
+}
+
+func NewRoleHandler(roleUsecase usecase.RoleUsecase) RoleHandler {
+	return &roleHandler{roleUsecase}
+}
+
+func (h *roleHandler) GetRoles(c *gin.Context) {
+	roles, err := h.roleUsecase.GetRoles()
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get all role", roles)
+}
+
+func (h *roleHandler) GetPermissions(c *gin.Context) {
+	permissions, err := h.roleUsecase.GetPermissions()
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get all permission", permissions)
+}
+
+func (h *roleHandler) CreateRole(c *gin.Context) {
+	var input RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role := model.Role{
+		Name:        input.Name,
+		Description: input.Description,
+	}
+
+	savedRole, err := h.roleUsecase.CreateRole(role, input.Permissions)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendCreatedResponse(c, "Success create role", savedRole)
+}
+
+func (h *roleHandler) UpdateRole(c *gin.Context) {
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID role not valid")
+		return
+	}
+
+	var input UpdateRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role := model.Role{
+		Description: input.Description,
+	}
+
+	updatedRole, err := h.roleUsecase.UpdateRole(uint(roleID), role, input.Permissions)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success update role", updatedRole)
+}
+
+func (h *roleHandler) GetUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID user not valid")
+		return
+	}
+
+	roles, err := h.roleUsecase.GetUserRoles(uint(userID))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get user roles", roles)
+}
+
+// replace all role of user with roles in body
+func (h *roleHandler) AssignUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID user not valid")
+		return
+	}
+
+	var input AssignRolesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	actorID, _ := c.Get("currentUserID")
+	roles, err := h.roleUsecase.AssignUserRoles(actorID.(uint), uint(userID), input.Roles, c.ClientIP())
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success assign user roles", roles)
+}
@@ -1,27 +1,67 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath" 
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
 	"rakamin-evermos/usecase"
 	"rakamin-evermos/utils"
 )
 
-// define data can change (only name toko so far)
+// define data can change, jam_operasional not sent = keep current jam
 type UpdateTokoInput struct {
-	NamaToko string `json:"nama_toko" binding:"required"`
+	NamaToko       string                `json:"nama_toko" binding:"required"`
+	Deskripsi      string                `json:"deskripsi"`
+	NoTelp         string                `json:"no_telp"`
+	EmailKontak    string                `json:"email_kontak" binding:"omitempty,email"`
+	DetailAlamat   string                `json:"detail_alamat"`
+	IDProvinsi     uint                  `json:"id_provinsi"`
+	IDKota         uint                  `json:"id_kota"`
+	IDKecamatan    uint                  `json:"id_kecamatan"`
+	IDKelurahan    uint64                `json:"id_kelurahan"`
+	KodePos        string                `json:"kode_pos"`
+	JamOperasional []JamOperasionalInput `json:"jam_operasional" binding:"omitempty,dive"`
+}
+
+type JamOperasionalInput struct {
+	Hari     int    `json:"hari" binding:"min=0,max=6"` // 0 = minggu
+	JamBuka  string `json:"jam_buka"`
+	JamTutup string `json:"jam_tutup"`
+	Tutup    bool   `json:"tutup"`
+}
+
+type LiburTokoInput struct {
+	IsLibur     bool       `json:"is_libur"`
+	LiburSampai *time.Time `json:"libur_sampai"` // optional, RFC3339
+	PesanLibur  string     `json:"pesan_libur"`
 }
 
 type TokoHandler interface {
 	GetMyToko(c *gin.Context)
 	UpdateMyToko(c *gin.Context)
 	UploadTokoPhoto(c *gin.Context)
+	SetLibur(c *gin.Context)
+
+	// verifikasi toko
+	UploadDokumen(c *gin.Context)
+	GetDokumen(c *gin.Context)
+	DeleteDokumen(c *gin.Context)
+	SubmitVerifikasi(c *gin.Context)
+
+	// public
+	GetTokoProfile(c *gin.Context)
+	GetTokoProduk(c *gin.Context)
 }
 
 type tokoHandler struct {
@@ -39,10 +79,14 @@ func (h *tokoHandler) GetMyToko(c *gin.Context) {
 		utils.SendErrorResponse(c, http.StatusUnauthorized, "fail to get user ID from token")
 		return
 	}
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
 
-	toko, err := h.tokoUsecase.GetMyToko(userID.(uint))
+	toko, err := h.tokoUsecase.GetMyToko(userID.(uint), tokoID)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		sendTokoAccessError(c, err, http.StatusNotFound)
 		return
 	}
 
@@ -55,6 +99,10 @@ func (h *tokoHandler) UpdateMyToko(c *gin.Context) {
 		utils.SendErrorResponse(c, http.StatusUnauthorized, "failed to get user ID from token")
 		return
 	}
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
 
 	var input UpdateTokoInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -63,12 +111,40 @@ func (h *tokoHandler) UpdateMyToko(c *gin.Context) {
 	}
 
 	updatedToko := model.Toko{
-		NamaToko: input.NamaToko,
+		NamaToko:     input.NamaToko,
+		Deskripsi:    input.Deskripsi,
+		NoTelp:       input.NoTelp,
+		EmailKontak:  input.EmailKontak,
+		DetailAlamat: input.DetailAlamat,
+		IDProvinsi:   input.IDProvinsi,
+		IDKota:       input.IDKota,
+		IDKecamatan:  input.IDKecamatan,
+		IDKelurahan:  input.IDKelurahan,
+		KodePos:      input.KodePos,
+	}
+	if input.JamOperasional != nil {
+		updatedToko.JamOperasional = []model.JamOperasional{}
+		for _, jam := range input.JamOperasional {
+			updatedToko.JamOperasional = append(updatedToko.JamOperasional, model.JamOperasional{
+				Hari:     jam.Hari,
+				JamBuka:  jam.JamBuka,
+				JamTutup: jam.JamTutup,
+				Tutup:    jam.Tutup,
+			})
+		}
 	}
 
-	savedToko, err := h.tokoUsecase.UpdateMyToko(userID.(uint), updatedToko)
+	savedToko, err := h.tokoUsecase.UpdateMyToko(userID.(uint), tokoID, updatedToko)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, usecase.ErrTokoNotValid) || errors.Is(err, usecase.ErrRegionNotValid) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, repository.ErrTokoSlugTaken) {
+			utils.SendErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -81,6 +157,10 @@ func (h *tokoHandler) UploadTokoPhoto(c *gin.Context) {
 		utils.SendErrorResponse(c, http.StatusUnauthorized, "failed to get user ID from token")
 		return
 	}
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
 
 	file, err := c.FormFile("photo")
 	if err != nil {
@@ -102,12 +182,189 @@ func (h *tokoHandler) UploadTokoPhoto(c *gin.Context) {
 	}
 
 	// Call Usecase with the file Complete Folder LOCATION
-	updatedToko, err := h.tokoUsecase.UploadTokoPhoto(userID.(uint), filePath)
+	updatedToko, err := h.tokoUsecase.UploadTokoPhoto(userID.(uint), tokoID, filePath)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	utils.SendSuccessResponse(c, "Success Upload Photo toko", updatedToko)
 }
 
+
+func (h *tokoHandler) SetLibur(c *gin.Context) {
+	userID, exists := c.Get("currentUserID")
+	if !exists {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "failed to get user ID from token")
+		return
+	}
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+
+	var input LiburTokoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updatedToko, err := h.tokoUsecase.SetLibur(userID.(uint), tokoID, input.IsLibur, input.LiburSampai, input.PesanLibur)
+	if err != nil {
+		if errors.Is(err, usecase.ErrTokoNotValid) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success update mode libur toko", updatedToko)
+}
+
+// allowed extension of dokumen verifikasi
+var dokumenExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".pdf": true}
+
+// multipart: file (jpg, png or pdf) and jenis (ktp, selfie_ktp, npwp)
+func (h *tokoHandler) UploadDokumen(c *gin.Context) {
+	userID, exists := c.Get("currentUserID")
+	if !exists {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "failed to get user ID from token")
+		return
+	}
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "File upload not found (key must be 'file')")
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !dokumenExtensions[ext] {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "dokumen must be jpg, png or pdf")
+		return
+	}
+
+	// Format: uploads/kyc-[userID]-[uuid].[ext]
+	filePath := fmt.Sprintf("uploads/kyc-%d-%s%s", userID.(uint), uuid.New().String(), ext)
+	if err := c.SaveUploadedFile(file, filePath); err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to save file")
+		return
+	}
+
+	dokumen, err := h.tokoUsecase.UploadDokumen(userID.(uint), tokoID, c.PostForm("jenis"), filepath.Base(file.Filename), filePath)
+	if err != nil {
+		// dokumen is personal data, don't keep file that not saved
+		os.Remove(filePath)
+		if errors.Is(err, usecase.ErrTokoNotValid) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendCreatedResponse(c, "Success upload dokumen toko", dokumen)
+}
+
+func (h *tokoHandler) GetDokumen(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+
+	dokumen, err := h.tokoUsecase.GetDokumen(userID.(uint), tokoID)
+	if err != nil {
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get dokumen toko", dokumen)
+}
+
+func (h *tokoHandler) DeleteDokumen(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+	dokumenID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID dokumen not valid")
+		return
+	}
+
+	if err := h.tokoUsecase.DeleteDokumen(userID.(uint), tokoID, uint(dokumenID)); err != nil {
+		if errors.Is(err, usecase.ErrTokoNotValid) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendTokoAccessError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success delete dokumen toko", nil)
+}
+
+// send toko to admin review queue
+func (h *tokoHandler) SubmitVerifikasi(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+
+	toko, err := h.tokoUsecase.SubmitVerifikasi(userID.(uint), tokoID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrTokoNotValid) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success submit toko for review", toko)
+}
+
+// :id can be ID toko or slug
+func (h *tokoHandler) GetTokoProfile(c *gin.Context) {
+	profile, err := h.tokoUsecase.GetTokoProfile(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get Data toko", profile)
+}
+
+// same filter as GET /produk
+func (h *tokoHandler) GetTokoProduk(c *gin.Context) {
+	pagination, filter := parseFilterAndPagination(c)
+
+	if cursor, ok := utils.GetCursorFromQuery(c); ok {
+		result, err := h.tokoUsecase.GetTokoProdukCursor(c.Param("id"), cursor, filter)
+		if err != nil {
+			if errors.Is(err, utils.ErrCursorNotValid) {
+				utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.SendSuccessResponse(c, "Success get produk toko", result)
+		return
+	}
+
+	result, err := h.tokoUsecase.GetTokoProduk(c.Param("id"), pagination, filter)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get produk toko", result)
+}
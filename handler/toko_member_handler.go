@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"rakamin-evermos/repository"
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InviteStaffInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"` // manager or packer
+}
+
+type UpdateStaffRoleInput struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type AcceptInvitationInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type TokoMemberHandler interface {
+	GetMyTokoList(c *gin.Context)
+	AcceptInvitation(c *gin.Context)
+
+	GetStaff(c *gin.Context)
+	UpdateStaffRole(c *gin.Context)
+	RemoveStaff(c *gin.Context)
+	InviteStaff(c *gin.Context)
+	GetInvitations(c *gin.Context)
+	RevokeInvitation(c *gin.Context)
+}
+
+type tokoMemberHandler struct {
+	tokoMemberUsecase usecase.TokoMemberUsecase
+}
+
+func NewTokoMemberHandler(tokoMemberUsecase usecase.TokoMemberUsecase) TokoMemberHandler {
+	return &tokoMemberHandler{tokoMemberUsecase}
+}
+
+// acting toko from X-Toko-ID header, empty = default toko of user
+func getActingTokoID(c *gin.Context) (uint, bool) {
+	header := c.GetHeader("X-Toko-ID")
+	if header == "" {
+		return 0, true
+	}
+	tokoID, err := strconv.ParseUint(header, 10, 32)
+	if err != nil || tokoID == 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "X-Toko-ID not valid")
+		return 0, false
+	}
+	return uint(tokoID), true
+}
+
+// 403 when not staff or role not allowed, 400 when acting toko must be chosen
+func sendTokoAccessError(c *gin.Context, err error, defaultCode int) {
+	switch {
+	case errors.Is(err, usecase.ErrTokoForbidden), errors.Is(err, usecase.ErrTokoNotActive):
+		utils.SendErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, usecase.ErrActingTokoRequired):
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendErrorResponse(c, defaultCode, err.Error())
+	}
+}
+
+func (h *tokoMemberHandler) GetMyTokoList(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	tokos, err := h.tokoMemberUsecase.GetMyTokoList(userID.(uint))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get my toko", tokos)
+}
+
+func (h *tokoMemberHandler) AcceptInvitation(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	var input AcceptInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	toko, err := h.tokoMemberUsecase.AcceptInvitation(userID.(uint), input.Token)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success join toko", toko)
+}
+
+func (h *tokoMemberHandler) GetStaff(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+
+	staff, err := h.tokoMemberUsecase.GetStaff(userID.(uint), tokoID)
+	if err != nil {
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get staff toko", staff)
+}
+
+func (h *tokoMemberHandler) UpdateStaffRole(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+	staffUserID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID user not valid")
+		return
+	}
+
+	var input UpdateStaffRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	staff, err := h.tokoMemberUsecase.UpdateStaffRole(userID.(uint), tokoID, uint(staffUserID), input.Role)
+	if err != nil {
+		sendTokoAccessError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success update role staff", staff)
+}
+
+func (h *tokoMemberHandler) RemoveStaff(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+	staffUserID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID user not valid")
+		return
+	}
+
+	if err := h.tokoMemberUsecase.RemoveStaff(userID.(uint), tokoID, uint(staffUserID)); err != nil {
+		sendTokoAccessError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success remove staff", nil)
+}
+
+// token in response only shown once, send it to invited staff
+func (h *tokoMemberHandler) InviteStaff(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+
+	var input InviteStaffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invitation, err := h.tokoMemberUsecase.InviteStaff(userID.(uint), tokoID, input.Email, input.Role)
+	if err != nil {
+		sendTokoAccessError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.SendCreatedResponse(c, "Success invite staff", invitation)
+}
+
+func (h *tokoMemberHandler) GetInvitations(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.tokoMemberUsecase.GetInvitations(userID.(uint), tokoID)
+	if err != nil {
+		sendTokoAccessError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get invitation", invitations)
+}
+
+func (h *tokoMemberHandler) RevokeInvitation(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	tokoID, ok := getActingTokoID(c)
+	if !ok {
+		return
+	}
+	invitationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID invitation not valid")
+		return
+	}
+
+	err = h.tokoMemberUsecase.RevokeInvitation(userID.(uint), tokoID, uint(invitationID))
+	if err != nil {
+		if errors.Is(err, repository.ErrInvitationNotPending) {
+			utils.SendErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		sendTokoAccessError(c, err, http.StatusNotFound)
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success revoke invitation", nil)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"rakamin-evermos/usecase"
 	"rakamin-evermos/utils"
@@ -10,7 +11,7 @@ import (
 )
 
 type TransaksiInput struct {
-	AlamatPengirimanID uint                   `json:"alamat_pengiriman_id" binding:"required"`
+	AlamatPengirimanID uint                   `json:"alamat_pengiriman_id"` // optional, default alamat used when empty
 	MethodBayar        string                 `json:"method_bayar" binding:"required"`
 	Items              []usecase.CartItemInput `json:"items" binding:"required,dive"` // dive for vlidate nested array
 }
@@ -64,6 +65,21 @@ func (h *transaksiHandler) CreateTransaksi(c *gin.Context) {
 func (h *transaksiHandler) GetMyTransaksi(c *gin.Context) {
 	userID, _ := c.Get("currentUserID")
 
+	// ?cursor= (empty for first page) return page of newest transaksi, else all
+	if cursor, ok := utils.GetCursorFromQuery(c); ok {
+		result, err := h.transaksiUsecase.GetMyTransaksiCursor(userID.(uint), cursor)
+		if err != nil {
+			if errors.Is(err, utils.ErrCursorNotValid) {
+				utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.SendSuccessResponse(c, "Success get my transaksi", result)
+		return
+	}
+
 	trxs, err := h.transaksiUsecase.GetMyTransaksi(userID.(uint))
 	if err != nil {
 		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorInput struct {
+	KataSandi string `json:"kata_sandi" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
+
+type TwoFactorHandler interface {
+	Enroll(c *gin.Context)
+	Confirm(c *gin.Context)
+	Disable(c *gin.Context)
+	RegenerateRecoveryCodes(c *gin.Context)
+}
+
+type twoFactorHandler struct {
+	twoFactorUsecase usecase.TwoFactorUsecase
+}
+
+func NewTwoFactorHandler(twoFactorUsecase usecase.TwoFactorUsecase) TwoFactorHandler {
+	return &twoFactorHandler{twoFactorUsecase}
+}
+
+// return secret and otpauth uri for authenticator app
+func (h *twoFactorHandler) Enroll(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	enrollment, err := h.twoFactorUsecase.Enroll(userID.(uint))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Scan the provisioning uri then confirm with the code", enrollment)
+}
+
+func (h *twoFactorHandler) Confirm(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	recoveryCodes, err := h.twoFactorUsecase.Confirm(userID.(uint), input.Code)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// recovery codes only shown once
+	utils.SendSuccessResponse(c, "Success enable 2FA", gin.H{"recovery_codes": recoveryCodes})
+}
+
+func (h *twoFactorHandler) Disable(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	var input DisableTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.twoFactorUsecase.Disable(userID.(uint), input.KataSandi, input.Code); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success disable 2FA", nil)
+}
+
+func (h *twoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	recoveryCodes, err := h.twoFactorUsecase.RegenerateRecoveryCodes(userID.(uint), input.Code)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success regenerate recovery codes", gin.H{"recovery_codes": recoveryCodes})
+}
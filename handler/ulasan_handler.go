@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UlasanInput struct {
+	Rating   int    `json:"rating" binding:"required"`
+	Komentar string `json:"komentar"`
+}
+
+type UlasanHandler interface {
+	CreateUlasan(c *gin.Context)
+	GetUlasan(c *gin.Context)
+}
+
+type ulasanHandler struct {
+	ulasanUsecase usecase.UlasanUsecase
+}
+
+func NewUlasanHandler(ulasanUsecase usecase.UlasanUsecase) UlasanHandler {
+	return &ulasanHandler{ulasanUsecase}
+}
+
+func (h *ulasanHandler) CreateUlasan(c *gin.Context) {
+	userID, _ := c.Get("currentUserID")
+	produkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID produk not valid")
+		return
+	}
+
+	var input UlasanInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ulasan, err := h.ulasanUsecase.CreateUlasan(userID.(uint), uint(produkID), input.Rating, input.Komentar)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrUlasanNotValid):
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, usecase.ErrUlasanNotBought):
+			utils.SendErrorResponse(c, http.StatusForbidden, err.Error())
+		default:
+			utils.SendErrorResponse(c, http.StatusNotFound, err.Error())
+		}
+		return
+	}
+
+	utils.SendCreatedResponse(c, "Success save ulasan", ulasan)
+}
+
+// newest first, paginated with ?page= and ?limit=
+func (h *ulasanHandler) GetUlasan(c *gin.Context) {
+	produkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "ID produk not valid")
+		return
+	}
+
+	result, err := h.ulasanUsecase.GetUlasan(uint(produkID), utils.GetPaginationFromQuery(c))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessResponse(c, "Success get ulasan produk", result)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -113,6 +114,10 @@ func (h *userHandler) UpdateProfile(c *gin.Context) {
 
 	savedUser, err := h.userUsecase.UpdateProfile(userID.(uint), updatedUser)
 	if err != nil {
+		if errors.Is(err, usecase.ErrRegionNotValid) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
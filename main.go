@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -10,9 +11,11 @@ import (
 	"rakamin-evermos/config"
 	"rakamin-evermos/model"
 	"rakamin-evermos/handler"
+	"rakamin-evermos/middleware"
 	"rakamin-evermos/repository"
 	"rakamin-evermos/router"
 	"rakamin-evermos/usecase"
+	"rakamin-evermos/utils"
 )
 
 var (
@@ -31,17 +34,48 @@ func main() {
 		&model.LogProduk{},
 		&model.Trx{},
 		&model.DetailTrx{},
+		&model.LoginAttempt{},
+		&model.LoginThrottle{},
+		&model.UserTwoFactor{},
+		&model.RecoveryCode{},
+		&model.Role{},
+		&model.Permission{},
+		&model.APIKey{},
+		&model.UserIdentity{},
+		&model.OIDCLoginState{},
+		&model.AuditLog{},
+		&model.LogAlamat{},
+		&model.JamOperasional{},
+		&model.TokoMember{},
+		&model.TokoInvitation{},
+		&model.TokoDokumen{},
+		&model.CategoryAtribut{},
+		&model.ProdukAtribut{},
+		&model.Provinsi{},
+		&model.Kota{},
+		&model.Kecamatan{},
+		&model.Kelurahan{},
+		&model.ProdukImport{},
+		&model.UlasanProduk{},
 	)
 	if err != nil {
 		log.Fatal("failed migrasi database:", err)
 	}
 	log.Println("Migrasi Database finished.")
 
+	// load jwt signing & verify keys, fail fast if config wrong
+	jwtKeySet, err := utils.LoadJWTKeySetFromEnv()
+	if err != nil {
+		log.Fatal("failed load jwt keys:", err)
+	}
+	utils.SetJWTKeySet(jwtKeySet)
+
 	// gin router
 	r := gin.Default()
 
 	userRepo := repository.NewUserRepository(db)
 	tokoRepo := repository.NewTokoRepository(db)
+	tokoMemberRepo := repository.NewTokoMemberRepository(db)
 	addressRepo := repository.NewAddressRepository(db)
 	categoryRepo := repository.NewCategoryRepository(db)
 	produkRepo := repository.NewProdukRepository(db)
@@ -49,19 +83,47 @@ func main() {
 	transaksiRepo := repository.NewTransaksiRepository(db)
 	detailTrxRepo := repository.NewDetailTrxRepository(db)
 	logProdukRepo := repository.NewLogProdukRepository(db)
+	logAlamatRepo := repository.NewLogAlamatRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	oidcRepo := repository.NewOIDCRepository(db)
+	auditLogRepo := repository.NewAuditLogRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	regionRepo := repository.NewRegionRepository(db)
+	produkImportRepo := repository.NewProdukImportRepository(db)
+	ulasanRepo := repository.NewUlasanRepository(db)
+	searchIndex := repository.NewMySQLSearchIndex(db)
+	suggestIndex := repository.NewMemorySuggestIndex()
+	produkIndexer := usecase.NewProdukIndexer(produkRepo, tokoRepo, searchIndex, suggestIndex)
 
-	authUsecase := usecase.NewAuthUsecase(userRepo, tokoRepo)
-	userUsecase := usecase.NewUserUsecase(userRepo)
-	addressUsecase := usecase.NewAddressUsecase(addressRepo)
-	categoryUsecase := usecase.NewCategoryUsecase(categoryRepo)
-	tokoUsecase := usecase.NewTokoUsecase(tokoRepo)
-	produkUsecase := usecase.NewProdukUsecase(produkRepo, fotoProdukRepo, tokoRepo)
+	loginGuard := usecase.NewLoginGuard(loginAttemptRepo, usecase.DefaultLoginGuardConfig())
+	oidcProviders := utils.LoadOIDCProvidersFromEnv()
+	authUsecase := usecase.NewAuthUsecase(userRepo, tokoRepo, loginAttemptRepo, twoFactorRepo, loginGuard, oidcRepo, oidcProviders)
+	twoFactorUsecase := usecase.NewTwoFactorUsecase(userRepo, twoFactorRepo)
+	roleUsecase := usecase.NewRoleUsecase(roleRepo, userRepo, auditLogRepo)
+	apiKeyUsecase := usecase.NewAPIKeyUsecase(apiKeyRepo)
+	adminUserUsecase := usecase.NewAdminUserUsecase(userRepo, roleRepo, auditLogRepo)
+	regionUsecase := usecase.NewRegionUsecase(regionRepo)
+	userUsecase := usecase.NewUserUsecase(userRepo, regionUsecase)
+	accountUsecase := usecase.NewAccountUsecase(accountRepo, userRepo, auditLogRepo, produkIndexer, usecase.AccountDeletionGraceFromEnv())
+	addressUsecase := usecase.NewAddressUsecase(addressRepo, regionUsecase)
+	categoryUsecase := usecase.NewCategoryUsecase(categoryRepo, produkIndexer)
+	tokoMemberUsecase := usecase.NewTokoMemberUsecase(tokoMemberRepo, userRepo)
+	tokoUsecase := usecase.NewTokoUsecase(tokoRepo, produkRepo, regionUsecase, tokoMemberUsecase, produkIndexer, ulasanRepo)
+	adminTokoUsecase := usecase.NewAdminTokoUsecase(tokoRepo, auditLogRepo, produkIndexer)
+	produkUsecase := usecase.NewProdukUsecase(produkRepo, fotoProdukRepo, tokoMemberUsecase, categoryUsecase, searchIndex, suggestIndex, produkIndexer, usecase.ProdukPurgeGraceFromEnv())
+	produkImportUsecase := usecase.NewProdukImportUsecase(produkImportRepo, produkRepo, produkUsecase, tokoMemberUsecase)
+	ulasanUsecase := usecase.NewUlasanUsecase(ulasanRepo, produkRepo)
 	transaksiUsecase := usecase.NewTransaksiUsecase(
 		db,
 		transaksiRepo,
 		detailTrxRepo,
 		logProdukRepo,
+		logAlamatRepo,
 		produkRepo,
+		tokoRepo,
 		addressRepo,
 	)
 
@@ -70,8 +132,61 @@ func main() {
 	addressHandler := handler.NewAddressHandler(addressUsecase)
 	categoryHandler := handler.NewCategoryHandler(categoryUsecase)
 	tokoHandler := handler.NewTokoHandler(tokoUsecase)
+	tokoMemberHandler := handler.NewTokoMemberHandler(tokoMemberUsecase)
 	produkHandler := handler.NewProdukHandler(produkUsecase)
+	produkImportHandler := handler.NewProdukImportHandler(produkImportUsecase)
 	transaksiHandler := handler.NewTransaksiHandler(transaksiUsecase)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorUsecase)
+	roleHandler := handler.NewRoleHandler(roleUsecase)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyUsecase)
+	adminUserHandler := handler.NewAdminUserHandler(adminUserUsecase)
+	accountHandler := handler.NewAccountHandler(accountUsecase)
+	regionHandler := handler.NewRegionHandler(regionUsecase)
+	adminTokoHandler := handler.NewAdminTokoHandler(adminTokoUsecase)
+	ulasanHandler := handler.NewUlasanHandler(ulasanUsecase)
+
+	// default role & permission, also give admin role to old is_admin user
+	if err := roleUsecase.SeedDefaultRoles(); err != nil {
+		log.Fatal("failed seed roles:", err)
+	}
+	permissionMiddleware := middleware.NewPermissionMiddleware(roleUsecase)
+
+	// region reference data, full dataset required so every real address can be validated
+	regionDataDir := os.Getenv("REGION_DATA_DIR")
+	if regionDataDir == "" {
+		log.Fatal("REGION_DATA_DIR is required, set it to folder with full region dataset")
+	}
+	if err := regionUsecase.SeedRegions(os.DirFS(regionDataDir)); err != nil {
+		log.Fatal("failed seed region:", err)
+	}
+
+	if err := addressRepo.AssignMissingDefaults(); err != nil {
+		log.Fatal("failed set default alamat:", err)
+	}
+	if _, err := logAlamatRepo.BackfillMissing(); err != nil {
+		log.Fatal("failed backfill alamat pengiriman:", err)
+	}
+	if _, err := produkRepo.BackfillHargaAngka(); err != nil {
+		log.Fatal("failed backfill harga produk:", err)
+	}
+	if err := categoryUsecase.BackfillSlugs(); err != nil {
+		log.Fatal("failed backfill slug kategori:", err)
+	}
+	if err := tokoUsecase.BackfillSlugs(); err != nil {
+		log.Fatal("failed backfill slug toko:", err)
+	}
+	if err := tokoMemberRepo.EnsureOwnerMemberships(); err != nil {
+		log.Fatal("failed create owner toko member:", err)
+	}
+	if err := tokoUsecase.ActivateLegacyToko(); err != nil {
+		log.Fatal("failed activate legacy toko:", err)
+	}
+	if err := produkIndexer.BuildSuggestIndex(); err != nil {
+		log.Fatal("failed build suggestion index:", err)
+	}
+	if err := produkImportUsecase.FailInterruptedImports(); err != nil {
+		log.Fatal("failed close interrupted import:", err)
+	}
 
 	router.SetupRouter(
 		r,
@@ -81,9 +196,38 @@ func main() {
 		categoryHandler,
 		tokoHandler,
 		produkHandler,
+		produkImportHandler,
 		transaksiHandler,
+		twoFactorHandler,
+		roleHandler,
+		apiKeyHandler,
+		adminUserHandler,
+		accountHandler,
+		regionHandler,
+		tokoMemberHandler,
+		adminTokoHandler,
+		ulasanHandler,
+		permissionMiddleware,
+		userUsecase,
+		apiKeyUsecase,
 )
 
+	// anonymize account after deletion grace period
+	stopAccountPurge := usecase.StartAccountPurgeJob(accountUsecase, time.Hour)
+	defer stopAccountPurge()
+
+	// remove foto of produk in trash after purge grace period
+	stopProdukPurge := usecase.StartProdukPurgeJob(produkUsecase, time.Hour)
+	defer stopProdukPurge()
+
+	// put back produk of toko whose libur ended in search and suggest index
+	stopLiburIndex := usecase.StartLiburIndexJob(produkIndexer, time.Minute)
+	defer stopLiburIndex()
+
+	// bulk import produk uploaded by seller
+	stopProdukImport := usecase.StartProdukImportWorker(produkImportUsecase, 5*time.Second)
+	defer stopProdukImport()
+
 	port := os.Getenv("PORT")
 	log.Printf("Server running in http://localhost:%s\n", port)
 	if err := r.Run(":" + port); err != nil {
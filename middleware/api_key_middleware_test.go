@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeAPIKeyAuthenticator struct {
+	keys map[string][]string
+}
+
+func (f *fakeAPIKeyAuthenticator) AuthenticateAPIKey(rawKey string) (uint, []string, error) {
+	scopes, ok := f.keys[rawKey]
+	if !ok {
+		return 0, nil, errors.New("api key not valid")
+	}
+	return 9, scopes, nil
+}
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	setupTestKeySet(t)
+	apiKeys := &fakeAPIKeyAuthenticator{keys: map[string][]string{
+		"rke_readonly_secret": {"produk:read"},
+	}}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("currentUserID")}) }
+	r.GET("/produk", RequireScope("produk:read"), AuthMiddleware(nil, apiKeys), ok)
+	r.POST("/produk", RequireScope("produk:write"), AuthMiddleware(nil, apiKeys), ok)
+	r.GET("/profile", AuthMiddleware(nil, nil), ok)
+	r.GET("/orders", AuthMiddleware(nil, apiKeys), ok) // api key allowed but scope forgotten
+	r.GET("/stok", AuthMiddleware(nil, apiKeys), RequireScope("produk:read"), ok) // scope placed after auth
+
+	request := func(method, path string, headers map[string]string) int {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{"apikey scheme", http.MethodGet, "/produk", map[string]string{"Authorization": "ApiKey rke_readonly_secret"}, http.StatusOK},
+		{"x-api-key header", http.MethodGet, "/produk", map[string]string{"X-API-Key": "rke_readonly_secret"}, http.StatusOK},
+		{"bearer api key", http.MethodGet, "/produk", map[string]string{"Authorization": "Bearer rke_readonly_secret"}, http.StatusOK},
+		{"missing scope", http.MethodPost, "/produk", map[string]string{"X-API-Key": "rke_readonly_secret"}, http.StatusForbidden},
+		{"unknown key", http.MethodGet, "/produk", map[string]string{"X-API-Key": "rke_unknown_secret"}, http.StatusUnauthorized},
+		{"key on jwt only route", http.MethodGet, "/profile", map[string]string{"X-API-Key": "rke_readonly_secret"}, http.StatusUnauthorized},
+		{"bearer key on jwt only route", http.MethodGet, "/profile", map[string]string{"Authorization": "Bearer rke_readonly_secret"}, http.StatusUnauthorized},
+		{"key on route without scope", http.MethodGet, "/orders", map[string]string{"X-API-Key": "rke_readonly_secret"}, http.StatusForbidden},
+		{"key on route with scope after auth", http.MethodGet, "/stok", map[string]string{"X-API-Key": "rke_readonly_secret"}, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		if got := request(tc.method, tc.path, tc.headers); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, got)
+		}
+	}
+
+	// jwt session not limited by scope
+	token, _ := utils.GenerateToken(1, false)
+	if got := request(http.MethodPost, "/produk", map[string]string{"Authorization": "Bearer " + token}); got != http.StatusOK {
+		t.Errorf("jwt on scoped route: expected 200, got %d", got)
+	}
+	if got := request(http.MethodGet, "/orders", map[string]string{"Authorization": "Bearer " + token}); got != http.StatusOK {
+		t.Errorf("jwt on route without scope: expected 200, got %d", got)
+	}
+}
@@ -5,66 +5,147 @@ import (
 	"net/http"
 	"strings"
 
-	"rakamin-evermos/utils" 
+	"rakamin-evermos/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AccountChecker return error if user cant use the api anymore, eg suspended (implemented by usecase.UserUsecase)
+type AccountChecker interface {
+	CheckAccountActive(userID uint) error
+}
+
+// APIKeyAuthenticator check raw api key, return owner and scopes (implemented by usecase.APIKeyUsecase)
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(rawKey string) (uint, []string, error)
+}
+
+// get scheme and credential from "Authorization: <scheme> <credential>"
+func parseAuthorizationHeader(authHeader string) (string, string, error) {
+	parts := strings.Fields(authHeader)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("Authorization header format must be 'Bearer <token>'")
+	}
+	return strings.ToLower(parts[0]), parts[1], nil
+}
+
+// AuthMiddleware accept Bearer JWT. if apiKeys not nil, api key is also accepted from
+// "Authorization: ApiKey <key>", "X-API-Key: <key>" or "Bearer rke_..."
+// api key rejected on route without RequireScope before it, so new route is closed for api key by default
+// if accounts not nil, suspended account is rejected even with valid token
+func AuthMiddleware(accounts AccountChecker, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// get token from header Authorization
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		headerAPIKey := c.GetHeader("X-API-Key")
+		if authHeader == "" && headerAPIKey == "" {
 			utils.SendErrorResponse(c, http.StatusUnauthorized, "Token authentication not provided")
-			c.Abort() 
+			c.Abort()
 			return
 		}
 
-		// format Bearer tokenString
-		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-		if tokenString == "" {
-			utils.SendErrorResponse(c, http.StatusUnauthorized, "Token authentication not valid")
-			c.Abort()
-			return
+		scheme, credential := "apikey", headerAPIKey
+		if authHeader != "" {
+			var err error
+			scheme, credential, err = parseAuthorizationHeader(authHeader)
+			if err != nil {
+				utils.SendErrorResponse(c, http.StatusUnauthorized, err.Error())
+				c.Abort()
+				return
+			}
 		}
 
-		token, err := utils.ValidateToken(tokenString)
-		if err != nil {
-			utils.SendErrorResponse(c, http.StatusUnauthorized, fmt.Sprintf("Token not valid: %s", err.Error()))
+		isAPIKey := scheme == "apikey" || (scheme == "bearer" && utils.LooksLikeAPIKey(credential))
+		authenticated := false
+		switch {
+		case isAPIKey && apiKeys != nil:
+			authenticated = authenticateAPIKey(c, apiKeys, credential)
+		case isAPIKey:
+			utils.SendErrorResponse(c, http.StatusUnauthorized, "API key not accepted for this endpoint")
+		case scheme == "bearer":
+			authenticated = authenticateJWT(c, credential)
+		default:
+			utils.SendErrorResponse(c, http.StatusUnauthorized, "Authorization header format must be 'Bearer <token>'")
+		}
+		if !authenticated {
 			c.Abort()
 			return
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
-			utils.SendErrorResponse(c, http.StatusUnauthorized, "Token authentication not valid")
+		if c.GetString("currentAuthMethod") == "api_key" && !checkAPIKeyScope(c) {
 			c.Abort()
 			return
 		}
 
-		// Set user information to context gin
-		userID := uint(claims["user_id"].(float64)) // JWT number is float64
-		isAdmin := claims["is_admin"].(bool)
-
-		c.Set("currentUserID", userID)
-		c.Set("currentUserIsAdmin", isAdmin)
+		if accounts != nil {
+			if err := accounts.CheckAccountActive(c.GetUint("currentUserID")); err != nil {
+				utils.SendErrorResponse(c, http.StatusForbidden, err.Error())
+				c.Abort()
+				return
+			}
+		}
 
 		c.Next()
 	}
 }
 
-// AdminOnlyMiddleware verifies if user is admin
-func AdminOnlyMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Ensure AuthMiddleware has run before to set currentUserIsAdmin
-		isAdmin, exists := c.Get("currentUserIsAdmin")
-		if !exists || !isAdmin.(bool) {
-			utils.SendErrorResponse(c, http.StatusForbidden, "Access denied: Only Admins are allowed")
-			c.Abort()
-			return
-		}
+func authenticateJWT(c *gin.Context, tokenString string) bool {
+	// typed claims, malformed claim value return error instead of panic
+	claims, err := utils.ParseAccessToken(tokenString)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, fmt.Sprintf("Token not valid: %s", err.Error()))
+		return false
+	}
+
+	// Set user information to context gin
+	c.Set("currentUserID", claims.UserID)
+	c.Set("currentUserIsAdmin", claims.IsAdmin)
+	c.Set("currentTokenID", claims.ID)
+	c.Set("currentTokenIssuedAt", claims.IssuedAt.Time) // for action that need recent login
+	c.Set("currentAuthMethod", "jwt")
+	return true
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, rawKey string) bool {
+	userID, scopes, err := apiKeys.AuthenticateAPIKey(rawKey)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return false
+	}
+
+	scopeSet := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		scopeSet[scope] = true
+	}
 
+	// api key never get admin right
+	c.Set("currentUserID", userID)
+	c.Set("currentUserIsAdmin", false)
+	c.Set("currentAuthMethod", "api_key")
+	c.Set("currentAPIKeyScopes", scopeSet)
+	return true
+}
+
+// scope set by RequireScope earlier in handler chain, false when 403 already sent
+func checkAPIKeyScope(c *gin.Context) bool {
+	scope := c.GetString("required_scope")
+	if scope == "" {
+		utils.SendErrorResponse(c, http.StatusForbidden, "API key not accepted for this endpoint, route has no scope")
+		return false
+	}
+	scopes, _ := c.Get("currentAPIKeyScopes")
+	scopeSet, _ := scopes.(map[string]bool)
+	if !scopeSet[scope] {
+		utils.SendErrorResponse(c, http.StatusForbidden, "API key missing scope "+scope)
+		return false
+	}
+	return true
+}
+
+// RequireScope mark scope needed by api key on the route, must be placed before AuthMiddleware.
+// AuthMiddleware reject api key when route has no scope mark, JWT session has full access
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("required_scope", scope)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
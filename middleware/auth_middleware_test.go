@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testSecret = "test-secret"
+
+func setupTestKeySet(t *testing.T) *utils.JWTKeySet {
+	t.Helper()
+	keySet := &utils.JWTKeySet{
+		Keys:       map[string]*utils.JWTKey{},
+		HMACSecret: []byte(testSecret),
+		Issuer:     "test-issuer",
+		Audience:   "test-audience",
+	}
+	utils.SetJWTKeySet(keySet)
+	t.Cleanup(func() { utils.SetJWTKeySet(nil) })
+	return keySet
+}
+
+func setupTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(nil, nil), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":  c.GetUint("currentUserID"),
+			"is_admin": c.GetBool("currentUserIsAdmin"),
+		})
+	})
+	return r
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"user_id":  7,
+		"is_admin": false,
+		"iss":      "test-issuer",
+		"aud":      "test-audience",
+		"sub":      "7",
+		"jti":      "token-id",
+		"iat":      now.Unix(),
+		"nbf":      now.Unix(),
+		"exp":      now.Add(time.Hour).Unix(),
+	}
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func doRequest(r *gin.Engine, path, authHeader string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddlewareAcceptsValidToken(t *testing.T) {
+	setupTestKeySet(t)
+	r := setupTestRouter()
+
+	token, err := utils.GenerateToken(7, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, header := range []string{"Bearer " + token, "bearer " + token, "  Bearer   " + token + " "} {
+		w := doRequest(r, "/me", header)
+		if w.Code != http.StatusOK {
+			t.Fatalf("header %q: expected 200, got %d: %s", header, w.Code, w.Body.String())
+		}
+		if w.Body.String() != `{"is_admin":false,"user_id":7}` {
+			t.Fatalf("unexpected body %s", w.Body.String())
+		}
+	}
+}
+
+func TestAuthMiddlewareRejectsBadHeader(t *testing.T) {
+	setupTestKeySet(t)
+	r := setupTestRouter()
+	token := signHS256(t, validClaims())
+
+	cases := map[string]string{
+		"missing header":    "",
+		"no scheme":         token,
+		"basic scheme":      "Basic " + token,
+		"scheme only":       "Bearer",
+		"scheme with space": "Bearer ",
+		"extra part":        "Bearer " + token + " extra",
+		"garbage token":     "Bearer not.a.jwt",
+	}
+	for name, header := range cases {
+		w := doRequest(r, "/me", header)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestAuthMiddlewareRejectsInvalidClaims(t *testing.T) {
+	setupTestKeySet(t)
+	r := setupTestRouter()
+	now := time.Now()
+
+	cases := map[string]func(jwt.MapClaims){
+		"expired":              func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() },
+		"missing exp":          func(c jwt.MapClaims) { delete(c, "exp") },
+		"missing iat":          func(c jwt.MapClaims) { delete(c, "iat") },
+		"issued in future":     func(c jwt.MapClaims) { c["iat"] = now.Add(time.Hour).Unix() },
+		"not valid yet":        func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Hour).Unix() },
+		"wrong audience":       func(c jwt.MapClaims) { c["aud"] = "other-service" },
+		"missing audience":     func(c jwt.MapClaims) { delete(c, "aud") },
+		"wrong issuer":         func(c jwt.MapClaims) { c["iss"] = "evil" },
+		"missing jti":          func(c jwt.MapClaims) { delete(c, "jti") },
+		"missing sub":          func(c jwt.MapClaims) { delete(c, "sub") },
+		"non numeric sub":      func(c jwt.MapClaims) { c["sub"] = "abc" },
+		"sub user_id mismatch": func(c jwt.MapClaims) { c["sub"] = "8" },
+		"is_admin as string":   func(c jwt.MapClaims) { c["is_admin"] = "true" },
+		"user_id as string":    func(c jwt.MapClaims) { c["user_id"] = "7" },
+		"challenge token":      func(c jwt.MapClaims) { c["purpose"] = utils.ChallengeTokenPurpose },
+	}
+	for name, mutate := range cases {
+		claims := validClaims()
+		mutate(claims)
+
+		// must not panic, always 401
+		w := doRequest(r, "/me", "Bearer "+signHS256(t, claims))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+
+	// audience as array is valid
+	claims := validClaims()
+	claims["aud"] = []string{"other-service", "test-audience"}
+	if w := doRequest(r, "/me", "Bearer "+signHS256(t, claims)); w.Code != http.StatusOK {
+		t.Errorf("audience array: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthMiddlewareRejectsWrongSignature(t *testing.T) {
+	keySet := setupTestKeySet(t)
+	r := setupTestRouter()
+
+	wrongSecret, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("other-secret"))
+	if w := doRequest(r, "/me", "Bearer "+wrongSecret); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: expected 401, got %d", w.Code)
+	}
+
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if w := doRequest(r, "/me", "Bearer "+unsigned); w.Code != http.StatusUnauthorized {
+		t.Errorf("alg none: expected 401, got %d", w.Code)
+	}
+
+	// ed25519 key registered, token signed by unknown kid or HS256 with kid must fail
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	keySet.Keys["k1"] = &utils.JWTKey{Kid: "k1", Method: jwt.SigningMethodEdDSA, PublicKey: pub}
+
+	good := jwt.NewWithClaims(jwt.SigningMethodEdDSA, validClaims())
+	good.Header["kid"] = "k1"
+	goodToken, _ := good.SignedString(priv)
+	if w := doRequest(r, "/me", "Bearer "+goodToken); w.Code != http.StatusOK {
+		t.Errorf("eddsa with kid: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	unknownKid := jwt.NewWithClaims(jwt.SigningMethodEdDSA, validClaims())
+	unknownKid.Header["kid"] = "k2"
+	unknownToken, _ := unknownKid.SignedString(priv)
+	if w := doRequest(r, "/me", "Bearer "+unknownToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown kid: expected 401, got %d", w.Code)
+	}
+
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	confused.Header["kid"] = "k1"
+	confusedToken, _ := confused.SignedString([]byte(pub))
+	if w := doRequest(r, "/me", "Bearer "+confusedToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("alg confusion: expected 401, got %d", w.Code)
+	}
+}
+
+type fakeAccountChecker struct {
+	suspended map[uint]bool
+}
+
+func (f fakeAccountChecker) CheckAccountActive(userID uint) error {
+	if f.suspended[userID] {
+		return errors.New("account suspended")
+	}
+	return nil
+}
+
+func TestAuthMiddlewareRejectsSuspendedAccount(t *testing.T) {
+	setupTestKeySet(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	accounts := fakeAccountChecker{suspended: map[uint]bool{7: true}}
+	r.GET("/me", AuthMiddleware(accounts, nil), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	suspendedToken, _ := utils.GenerateToken(7, false)
+	if w := doRequest(r, "/me", "Bearer "+suspendedToken); w.Code != http.StatusForbidden {
+		t.Errorf("suspended: expected 403, got %d", w.Code)
+	}
+
+	activeToken, _ := utils.GenerateToken(8, false)
+	if w := doRequest(r, "/me", "Bearer "+activeToken); w.Code != http.StatusOK {
+		t.Errorf("active: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
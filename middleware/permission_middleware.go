@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionLoader return permission code of user (implemented by usecase.RoleUsecase)
+type PermissionLoader interface {
+	GetUserPermissions(userID uint) ([]string, error)
+}
+
+type PermissionMiddleware struct {
+	loader PermissionLoader
+}
+
+func NewPermissionMiddleware(loader PermissionLoader) *PermissionMiddleware {
+	return &PermissionMiddleware{loader}
+}
+
+// RequirePermission check permission from db every request, so role change apply without re-login
+// must run after AuthMiddleware
+func (m *PermissionMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, err := m.currentPermissions(c)
+		if err != nil {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+			c.Abort()
+			return
+		}
+
+		if !permissions[permission] {
+			utils.SendErrorResponse(c, http.StatusForbidden, "Access denied: missing permission "+permission)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// loaded once per request, saved in context for next check
+func (m *PermissionMiddleware) currentPermissions(c *gin.Context) (map[string]bool, error) {
+	if cached, exists := c.Get("currentUserPermissions"); exists {
+		if permissions, ok := cached.(map[string]bool); ok {
+			return permissions, nil
+		}
+	}
+
+	codes, err := m.loader.GetUserPermissions(c.GetUint("currentUserID"))
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		permissions[code] = true
+	}
+	c.Set("currentUserPermissions", permissions)
+	return permissions, nil
+}
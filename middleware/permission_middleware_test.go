@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"rakamin-evermos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakePermissionLoader struct {
+	permissions map[uint][]string
+	calls       int
+	err         error
+}
+
+func (f *fakePermissionLoader) GetUserPermissions(userID uint) ([]string, error) {
+	f.calls++
+	return f.permissions[userID], f.err
+}
+
+func TestRequirePermission(t *testing.T) {
+	setupTestKeySet(t)
+	loader := &fakePermissionLoader{permissions: map[uint][]string{
+		1: {"category:read", "category:write"},
+		2: {"category:read"},
+	}}
+	permission := NewPermissionMiddleware(loader)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/categories", AuthMiddleware(nil, nil), permission.RequirePermission("category:read"), permission.RequirePermission("category:write"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		userID uint
+		want   int
+	}{
+		{1, http.StatusOK},
+		{2, http.StatusForbidden},
+		{3, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		loader.calls = 0
+		token, err := utils.GenerateToken(tc.userID, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := doRequest(r, "/categories", "Bearer "+token); w.Code != tc.want {
+			t.Errorf("user %d: expected %d, got %d", tc.userID, tc.want, w.Code)
+		}
+		if loader.calls != 1 {
+			t.Errorf("user %d: permissions should be loaded once per request, loaded %d times", tc.userID, loader.calls)
+		}
+	}
+
+	loader.err = errors.New("db down")
+	token, _ := utils.GenerateToken(1, false)
+	if w := doRequest(r, "/categories", "Bearer "+token); w.Code != http.StatusInternalServerError {
+		t.Errorf("loader error: expected 500, got %d", w.Code)
+	}
+}
@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Alamat struct {
 	ID            uint   `gorm:"primaryKey;autoIncrement;column:id"`
@@ -9,8 +13,21 @@ type Alamat struct {
 	NamaPenerima  string `gorm:"size:255"`
 	NoTelp        string `gorm:"size:255"`
 	DetailAlamat  string `gorm:"size:255"`
+	IDProvinsi    uint   `gorm:"column:id_provinsi"`
+	IDKota        uint   `gorm:"column:id_kota"`
+	IDKecamatan   uint   `gorm:"column:id_kecamatan"`
+	IDKelurahan   uint64 `gorm:"column:id_kelurahan"`
+	KodePos       string `gorm:"size:5"`
+	Jalan         string `gorm:"size:255"`
+	Catatan       string `gorm:"size:255"`
+	Latitude      *float64
+	Longitude     *float64
+	IsDefault     bool   `gorm:"column:is_default;index"`
 	CreatedAtDate time.Time `gorm:"column:created_at_date"`
 	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+
+	// set when alamat deleted but still referenced by trx
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 func (Alamat) TableName() string {
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// scope that can be given to api key, format <resource>:<action>
+const (
+	ScopeProdukRead     = "produk:read"
+	ScopeProdukWrite    = "produk:write"
+	ScopeTransaksiRead  = "transaksi:read"
+	ScopeTransaksiWrite = "transaksi:write"
+)
+
+var APIKeyScopes = []string{ScopeProdukRead, ScopeProdukWrite, ScopeTransaksiRead, ScopeTransaksiWrite}
+
+// APIKey for server to server integration, only sha256 hash of key stored
+type APIKey struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	IDUser        uint       `gorm:"column:id_user;index"`
+	Name          string     `gorm:"size:255"`
+	Prefix        string     `gorm:"size:32;index"` // shown in list so user know which key
+	KeyHash       string     `gorm:"size:64;unique" json:"-"`
+	Scopes        string     `gorm:"size:255"` // comma separated
+	LastUsedAt    *time.Time `gorm:"column:last_used_at"`
+	ExpiresAt     *time.Time `gorm:"column:expires_at"`
+	RevokedAt     *time.Time `gorm:"column:revoked_at"`
+	CreatedAtDate time.Time  `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time  `gorm:"column:updated_at_date"`
+}
+
+func (APIKey) TableName() string {
+	return "api_key"
+}
+
+func (k APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
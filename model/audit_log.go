@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// admin action name
+const (
+	AuditUserSuspend   = "user.suspend"
+	AuditUserUnsuspend = "user.unsuspend"
+	AuditUserRoles     = "user.roles"
+
+	AuditTokoApprove   = "toko.approve"
+	AuditTokoReject    = "toko.reject"
+	AuditTokoSuspend   = "toko.suspend"
+	AuditTokoUnsuspend = "toko.unsuspend"
+
+	AuditAccountDeletionRequest = "account.deletion_request"
+	AuditAccountDeletionCancel  = "account.deletion_cancel"
+	AuditAccountAnonymize       = "account.anonymize" // done by system, actor 0
+)
+
+// who did what to which data, written for every admin action
+type AuditLog struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	IDActor       uint      `gorm:"column:id_actor;index"`
+	Action        string    `gorm:"size:100;index"`
+	TargetType    string    `gorm:"size:50;index:idx_audit_target"`
+	TargetID      uint      `gorm:"column:target_id;index:idx_audit_target"`
+	Detail        string    `gorm:"type:text"`
+	IPAddress     string    `gorm:"size:45"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
@@ -3,15 +3,56 @@ package model
 import "time"
 
 type Category struct {
-	ID            uint   `gorm:"primaryKey;autoIncrement;column:id"`
-	NamaCategory  string `gorm:"size:255"`
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	IDParent      *uint     `gorm:"column:id_parent;index"` // nil = root category
+	NamaCategory  string    `gorm:"size:255;index:idx_category_fulltext,class:FULLTEXT"`
+	Slug          string    `gorm:"size:255;index"`
+	Urutan        int       `gorm:"column:urutan"` // order between sibling, small first
 	CreatedAtDate time.Time `gorm:"column:created_at_date"`
 	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
 
 	// Relasi ke produk
-	Produk      []Produk `gorm:"foreignKey:IDCategory"`
+	Produk []Produk `gorm:"foreignKey:IDCategory"`
 }
 
 func (Category) TableName() string {
 	return "category"
-}
\ No newline at end of file
+}
+
+// rootID and every category below it, rootID always first
+func CategoryWithDescendants(categories []Category, rootID uint) []uint {
+	children := map[uint][]uint{}
+	for _, category := range categories {
+		if category.IDParent != nil {
+			children[*category.IDParent] = append(children[*category.IDParent], category.ID)
+		}
+	}
+
+	ids := []uint{rootID}
+	visited := map[uint]bool{rootID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, childID := range children[ids[i]] {
+			if !visited[childID] {
+				visited[childID] = true
+				ids = append(ids, childID)
+			}
+		}
+	}
+	return ids
+}
+
+// parent chain of categoryID from root, categoryID itself last
+func CategoryWithAncestors(categories []Category, categoryID uint) []uint {
+	parents := make(map[uint]*uint, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.IDParent
+	}
+
+	ids := []uint{categoryID}
+	visited := map[uint]bool{categoryID: true}
+	for parentID := parents[categoryID]; parentID != nil && !visited[*parentID]; parentID = parents[*parentID] {
+		visited[*parentID] = true
+		ids = append([]uint{*parentID}, ids...)
+	}
+	return ids
+}
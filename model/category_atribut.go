@@ -0,0 +1,84 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// tipe nilai atribut produk
+const (
+	AtributTipeString = "string"
+	AtributTipeNumber = "number"
+	AtributTipeEnum   = "enum"
+)
+
+// StringList saved as json array in text column
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("StringList: unsupported type")
+	}
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, l)
+}
+
+// CategoryAtribut is schema of spec produk in one kategori, also used by
+// produk in sub kategori. Kode unique inside kategori and its parent/sub kategori
+type CategoryAtribut struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	IDCategory    uint       `gorm:"column:id_category;uniqueIndex:idx_category_atribut_kode"`
+	Kode          string     `gorm:"size:100;uniqueIndex:idx_category_atribut_kode"` // key in input and filter, e.g. "ram"
+	Nama          string     `gorm:"size:255"`
+	Tipe          string     `gorm:"size:20"`
+	Opsi          StringList `gorm:"type:text"` // only for enum
+	Satuan        string     `gorm:"size:50"`   // e.g. "GB", only for display
+	Wajib         bool
+	Urutan        int       `gorm:"column:urutan"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+}
+
+func (CategoryAtribut) TableName() string {
+	return "category_atribut"
+}
+
+// ProdukAtribut is value of one atribut for one produk.
+// NilaiAngka filled for number atribut so it can be filtered by range
+type ProdukAtribut struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	IDProduk      uint      `gorm:"column:id_produk;uniqueIndex:idx_produk_atribut"`
+	IDAtribut     uint      `gorm:"column:id_atribut;uniqueIndex:idx_produk_atribut;index:idx_produk_atribut_nilai"`
+	Nilai         string    `gorm:"size:255;index:idx_produk_atribut_nilai"`
+	NilaiAngka    *float64  `gorm:"column:nilai_angka"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+
+	Atribut *CategoryAtribut `gorm:"foreignKey:IDAtribut"`
+}
+
+func (ProdukAtribut) TableName() string {
+	return "produk_atribut"
+}
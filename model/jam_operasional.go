@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// JamOperasional is opening hour of toko for one day in week
+type JamOperasional struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	IDToko        uint      `gorm:"column:id_toko;uniqueIndex:idx_jam_operasional_toko_hari"`
+	Hari          int       `gorm:"uniqueIndex:idx_jam_operasional_toko_hari"` // 0 = minggu ... 6 = sabtu, same as time.Weekday
+	JamBuka       string    `gorm:"size:5"`                                    // "08:00"
+	JamTutup      string    `gorm:"size:5"`                                    // "17:00"
+	Tutup         bool      // closed whole day
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+}
+
+func (JamOperasional) TableName() string {
+	return "jam_operasional_toko"
+}
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// LogAlamat is copy of alamat pengiriman at checkout time,
+// trx keep showing this even when user edit or delete the alamat
+type LogAlamat struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement;column:id"`
+	IDTrx         uint   `gorm:"column:id_trx;uniqueIndex"`
+	IDAlamat      uint   `gorm:"column:id_alamat"`
+	IDUser        uint   `gorm:"column:id_user;index"`
+	JudulAlamat   string `gorm:"size:255"`
+	NamaPenerima  string `gorm:"size:255"`
+	NoTelp        string `gorm:"size:255"`
+	DetailAlamat  string `gorm:"size:255"`
+	IDProvinsi    uint   `gorm:"column:id_provinsi"`
+	IDKota        uint   `gorm:"column:id_kota"`
+	IDKecamatan   uint   `gorm:"column:id_kecamatan"`
+	IDKelurahan   uint64 `gorm:"column:id_kelurahan"`
+	KodePos       string `gorm:"size:5"`
+	Jalan         string `gorm:"size:255"`
+	Catatan       string `gorm:"size:255"`
+	Latitude      *float64
+	Longitude     *float64
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+}
+
+func (LogAlamat) TableName() string {
+	return "log_alamat"
+}
+
+func NewLogAlamat(alamat Alamat, now time.Time) LogAlamat {
+	return LogAlamat{
+		IDAlamat:      alamat.ID,
+		IDUser:        alamat.IDUser,
+		JudulAlamat:   alamat.JudulAlamat,
+		NamaPenerima:  alamat.NamaPenerima,
+		NoTelp:        alamat.NoTelp,
+		DetailAlamat:  alamat.DetailAlamat,
+		IDProvinsi:    alamat.IDProvinsi,
+		IDKota:        alamat.IDKota,
+		IDKecamatan:   alamat.IDKecamatan,
+		IDKelurahan:   alamat.IDKelurahan,
+		KodePos:       alamat.KodePos,
+		Jalan:         alamat.Jalan,
+		Catatan:       alamat.Catatan,
+		Latitude:      alamat.Latitude,
+		Longitude:     alamat.Longitude,
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// LoginAttempt is the audit trail of every login try (success or failed)
+type LoginAttempt struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement;column:id"`
+	IDUser        *uint  `gorm:"column:id_user"` // nil when email not registered
+	Email         string `gorm:"size:255;index"`
+	IPAddress     string `gorm:"size:64;index"`
+	Success       bool
+	Reason        string    `gorm:"size:64"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+}
+
+func (LoginAttempt) TableName() string {
+	return "login_attempt"
+}
+
+// LoginThrottle keep failed counter per account / per IP
+// Key format: "account:<email>" or "ip:<address>"
+type LoginThrottle struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement;column:id"`
+	Key           string `gorm:"size:255;unique"`
+	FailedCount   int
+	LastFailedAt  time.Time  `gorm:"column:last_failed_at"`
+	LockedUntil   *time.Time `gorm:"column:locked_until"`
+	CreatedAtDate time.Time  `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time  `gorm:"column:updated_at_date"`
+}
+
+func (LoginThrottle) TableName() string {
+	return "login_throttle"
+}
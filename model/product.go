@@ -1,26 +1,53 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// draft and archived produk only seen by seller
+const (
+	ProdukStatusDraft     = "draft"
+	ProdukStatusPublished = "published"
+	ProdukStatusArchived  = "archived"
+)
 
 type Produk struct {
 	ID             uint   `gorm:"primaryKey;autoIncrement;column:id"`
 	IDToko         uint   `gorm:"column:id_toko"`
 	IDCategory     uint   `gorm:"column:id_category"`
-	NamaProduk     string `gorm:"size:255"`
+	NamaProduk     string `gorm:"size:255;index:idx_produk_nama_fulltext,class:FULLTEXT;index:idx_produk_fulltext,class:FULLTEXT,priority:1"`
 	Slug           string `gorm:"size:255"`
 	HargaReseller  string `gorm:"size:255"`
 	HargaKonsumen  string `gorm:"size:255"`
 	Stok           int
-	Deskripsi      string `gorm:"type:text"`
+	Deskripsi      string `gorm:"type:text;index:idx_produk_fulltext,class:FULLTEXT,priority:2"`
+	Status         string `gorm:"size:20;default:published;index"`
 	CreatedAtDate  time.Time `gorm:"column:created_at_date"`
 	UpdatedAtDate  time.Time `gorm:"column:updated_at_date"`
+	DeletedAt      gorm.DeletedAt `gorm:"index"` // in trash, restorable until purged
+
+	// number of HargaKonsumen, set by repository on save, used by harga filter and sort
+	HargaKonsumenAngka uint64 `gorm:"column:harga_konsumen_angka;index" json:"-"`
 
 	// Relasi nya ke foto produk, log produk, dan kategori
 	FotoProduk   []FotoProduk `gorm:"foreignKey:IDProduk"`
 	LogProduk    []LogProduk  `gorm:"foreignKey:IDProduk"`
 	Category     Category     `gorm:"foreignKey:IDCategory"`
+	Toko         *Toko        `gorm:"foreignKey:IDToko"`
+	Atribut      []ProdukAtribut `gorm:"foreignKey:IDProduk"`
 }
 
 func (Produk) TableName() string {
 	return "produk"
+}
+
+func (p Produk) IsPublished() bool {
+	return p.Status == ProdukStatusPublished && !p.DeletedAt.Valid
+}
+
+// shown in public list, search and suggestion: published, toko active and not in mode libur. Toko must be loaded
+func (p Produk) IsPublic(now time.Time) bool {
+	return p.IsPublished() && p.Toko != nil && p.Toko.IsActive() && !p.Toko.SedangLibur(now)
 }
\ No newline at end of file
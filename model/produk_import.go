@@ -0,0 +1,77 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// status of import produk job
+const (
+	ImportStatusPending    = "pending"
+	ImportStatusProcessing = "processing"
+	ImportStatusDone       = "done"   // finished, some row can still fail
+	ImportStatusFailed     = "failed" // file can't be read, no row imported
+)
+
+// Baris is row number in file, header is row 1
+type ImportRowError struct {
+	Baris int    `json:"baris"`
+	Pesan string `json:"pesan"`
+}
+
+// ImportRowErrors saved as json array in text column
+type ImportRowErrors []ImportRowError
+
+func (l ImportRowErrors) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+func (l *ImportRowErrors) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("ImportRowErrors: unsupported type")
+	}
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, l)
+}
+
+// ProdukImport is background job of bulk import from csv / xlsx, polled by seller
+type ProdukImport struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement;column:id"`
+	IDToko        uint   `gorm:"column:id_toko;index"`
+	IDUser        uint   `gorm:"column:id_user"` // uploader, row saved with its permission
+	Format        string `gorm:"size:10"`
+	NamaFile      string `gorm:"size:255"`
+	FilePath      string `gorm:"size:255" json:"-"` // removed after processed
+	Status        string `gorm:"size:20;index"`
+	Pesan         string `gorm:"size:255"` // reason of failed
+	TotalBaris    int
+	Berhasil      int
+	Gagal         int
+	Errors        ImportRowErrors `gorm:"type:text"`
+	StartedAt     *time.Time
+	FinishedAt    *time.Time
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+}
+
+func (ProdukImport) TableName() string {
+	return "produk_import"
+}
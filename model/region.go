@@ -0,0 +1,42 @@
+package model
+
+// region reference data, id is Kemendagri code (provinsi 31, kota 3171, kecamatan 317110, kelurahan 3171101001)
+type Provinsi struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement:false;column:id"`
+	Nama string `gorm:"size:255;index"`
+}
+
+func (Provinsi) TableName() string {
+	return "provinsi"
+}
+
+type Kota struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement:false;column:id"`
+	IDProvinsi uint   `gorm:"column:id_provinsi;index"`
+	Nama       string `gorm:"size:255"`
+}
+
+func (Kota) TableName() string {
+	return "kota"
+}
+
+type Kecamatan struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement:false;column:id"`
+	IDKota uint   `gorm:"column:id_kota;index"`
+	Nama   string `gorm:"size:255"`
+}
+
+func (Kecamatan) TableName() string {
+	return "kecamatan"
+}
+
+type Kelurahan struct {
+	ID          uint64 `gorm:"primaryKey;autoIncrement:false;column:id"`
+	IDKecamatan uint   `gorm:"column:id_kecamatan;index"`
+	Nama        string `gorm:"size:255"`
+	KodePos     string `gorm:"size:5;index"`
+}
+
+func (Kelurahan) TableName() string {
+	return "kelurahan"
+}
@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// permission code, format <resource>:<action>
+const (
+	PermissionCategoryRead  = "category:read"
+	PermissionCategoryWrite = "category:write"
+	PermissionUserRead      = "user:read"
+	PermissionUserWrite     = "user:write"
+	PermissionRoleManage    = "role:manage"
+	PermissionLoginAudit    = "login:audit"
+	PermissionLoginUnlock   = "login:unlock"
+	PermissionTrxRead       = "trx:read"
+	PermissionAuditRead     = "audit:read"
+	PermissionTokoReview    = "toko:review"
+)
+
+// default role name
+const (
+	RoleAdmin          = "admin"
+	RoleCatalogManager = "catalog-manager"
+	RoleSupport        = "support"
+	RoleFinance        = "finance"
+)
+
+type Role struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Name          string    `gorm:"size:100;unique"`
+	Description   string    `gorm:"size:255"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+
+	// Relasi ke permission
+	Permissions []Permission `gorm:"many2many:role_permission;joinForeignKey:id_role;joinReferences:id_permission"`
+}
+
+func (Role) TableName() string {
+	return "role"
+}
+
+type Permission struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Code          string    `gorm:"size:100;unique"`
+	Description   string    `gorm:"size:255"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+}
+
+func (Permission) TableName() string {
+	return "permission"
+}
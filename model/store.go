@@ -2,18 +2,71 @@ package model
 
 import "time"
 
+// status verifikasi toko, only active toko visible to buyer and can get order
+const (
+	TokoStatusDraft         = "draft"
+	TokoStatusPendingReview = "pending_review"
+	TokoStatusActive        = "active"
+	TokoStatusSuspended     = "suspended"
+)
+
 type Toko struct {
-	ID            uint   `gorm:"primaryKey;autoIncrement;column:id"`
-	IDUser        uint   `gorm:"column:id_user;unique"`
-	NamaToko      string `gorm:"size:255"`
-	UrlFoto       string `gorm:"size:255"`
+	ID        uint   `gorm:"primaryKey;autoIncrement;column:id"`
+	IDUser    uint   `gorm:"column:id_user;unique"`
+	NamaToko  string `gorm:"size:255;index:idx_toko_fulltext,class:FULLTEXT"`
+	Slug      *string `gorm:"size:255"` // NULL until set, unique index made by TokoRepository.EnsureSlugUniqueIndex after backfill
+	UrlFoto   string `gorm:"size:255"`
+	Deskripsi string `gorm:"type:text"`
+
+	// kontak toko
+	NoTelp      string `gorm:"size:255"`
+	EmailKontak string `gorm:"size:255"`
+
+	// alamat asal pengiriman / pickup
+	DetailAlamat string `gorm:"size:255"`
+	IDProvinsi   uint   `gorm:"column:id_provinsi"`
+	IDKota       uint   `gorm:"column:id_kota"`
+	IDKecamatan  uint   `gorm:"column:id_kecamatan"`
+	IDKelurahan  uint64 `gorm:"column:id_kelurahan"`
+	KodePos      string `gorm:"size:5"`
+
+	// mode libur, LiburSampai nil = until seller turn it off
+	IsLibur     bool       `gorm:"column:is_libur;index"`
+	LiburSampai *time.Time `gorm:"column:libur_sampai"`
+	PesanLibur  string     `gorm:"size:255"`
+
+	// verifikasi by admin, StatusReason filled when rejected or suspended
+	Status       string     `gorm:"size:20;index"`
+	StatusReason string     `gorm:"size:255"`
+	SubmittedAt  *time.Time `gorm:"column:submitted_at"`
+	ReviewedAt   *time.Time `gorm:"column:reviewed_at"`
+	ReviewedBy   *uint      `gorm:"column:reviewed_by"`
+
 	CreatedAtDate time.Time `gorm:"column:created_at_date"`
 	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
 
 	// Relasi ke produk
-	Produk      []Produk `gorm:"foreignKey:IDToko"`
+	Produk         []Produk         `gorm:"foreignKey:IDToko"`
+	JamOperasional []JamOperasional `gorm:"foreignKey:IDToko"`
+	Dokumen        []TokoDokumen    `gorm:"foreignKey:IDToko"`
 }
 
 func (Toko) TableName() string {
 	return "toko"
-}
\ No newline at end of file
+}
+
+func (t Toko) SedangLibur(now time.Time) bool {
+	return t.IsLibur && (t.LiburSampai == nil || now.Before(*t.LiburSampai))
+}
+
+// empty when toko has no slug yet
+func (t Toko) SlugValue() string {
+	if t.Slug == nil {
+		return ""
+	}
+	return *t.Slug
+}
+
+func (t Toko) IsActive() bool {
+	return t.Status == TokoStatusActive
+}
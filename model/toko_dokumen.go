@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// jenis dokumen verifikasi (KYC) toko
+const (
+	DokumenKTP       = "ktp"
+	DokumenSelfieKTP = "selfie_ktp"
+	DokumenNPWP      = "npwp"
+)
+
+var TokoDokumenJenis = []string{DokumenKTP, DokumenSelfieKTP, DokumenNPWP}
+
+// must be uploaded before toko can be submitted for review
+var RequiredTokoDokumen = []string{DokumenKTP, DokumenSelfieKTP}
+
+// TokoDokumen is private file, never served publicly, only to admin reviewer
+type TokoDokumen struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	IDToko        uint      `gorm:"column:id_toko;index"`
+	Jenis         string    `gorm:"size:50"`
+	Url           string    `gorm:"size:255" json:"-"`
+	NamaFile      string    `gorm:"size:255"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+}
+
+func (TokoDokumen) TableName() string {
+	return "toko_dokumen"
+}
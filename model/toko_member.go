@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+// role of user inside one toko
+const (
+	TokoRoleOwner   = "owner"
+	TokoRoleManager = "manager"
+	TokoRolePacker  = "packer"
+)
+
+// permission inside toko, checked when resolve acting toko
+const (
+	TokoPermTokoRead    = "toko:read"
+	TokoPermTokoWrite   = "toko:write"
+	TokoPermProdukRead  = "produk:read"
+	TokoPermProdukWrite = "produk:write"
+	TokoPermStaffManage = "staff:manage"
+	TokoPermVerifikasi  = "verifikasi:submit"
+)
+
+var TokoRolePermissions = map[string][]string{
+	TokoRoleOwner:   {TokoPermTokoRead, TokoPermTokoWrite, TokoPermProdukRead, TokoPermProdukWrite, TokoPermStaffManage, TokoPermVerifikasi},
+	TokoRoleManager: {TokoPermTokoRead, TokoPermTokoWrite, TokoPermProdukRead, TokoPermProdukWrite},
+	TokoRolePacker:  {TokoPermTokoRead, TokoPermProdukRead},
+}
+
+// TokoMember is staff of toko, owner of toko (Toko.IDUser) also has owner row
+type TokoMember struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	IDToko        uint      `gorm:"column:id_toko;uniqueIndex:idx_toko_member_toko_user"`
+	IDUser        uint      `gorm:"column:id_user;uniqueIndex:idx_toko_member_toko_user;index"`
+	Role          string    `gorm:"size:50"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+
+	User *User `gorm:"foreignKey:IDUser"`
+	Toko *Toko `gorm:"foreignKey:IDToko"`
+}
+
+func (TokoMember) TableName() string {
+	return "toko_member"
+}
+
+func (m TokoMember) HasPermission(permission string) bool {
+	for _, granted := range TokoRolePermissions[m.Role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// TokoInvitation is sent to email, accepted by user login with that email.
+// only sha256 hash of token stored, raw token shown once to inviter
+type TokoInvitation struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	IDToko        uint       `gorm:"column:id_toko;index"`
+	IDInviter     uint       `gorm:"column:id_inviter"`
+	Email         string     `gorm:"size:255;index"`
+	Role          string     `gorm:"size:50"`
+	TokenHash     string     `gorm:"size:64;unique" json:"-"`
+	ExpiresAt     time.Time  `gorm:"column:expires_at"`
+	AcceptedAt    *time.Time `gorm:"column:accepted_at"`
+	AcceptedBy    *uint      `gorm:"column:accepted_by"`
+	RevokedAt     *time.Time `gorm:"column:revoked_at"`
+	CreatedAtDate time.Time  `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time  `gorm:"column:updated_at_date"`
+}
+
+func (TokoInvitation) TableName() string {
+	return "toko_invitation"
+}
@@ -15,6 +15,9 @@ type Trx struct {
 	// Relasi yg ke detail transaksi, alamat
 	DetailTrx      []DetailTrx `gorm:"foreignKey:IDTrx"`
 	Alamat           Alamat      `gorm:"foreignKey:AlamatPengiriman"`
+
+	// alamat at checkout time, use this instead of Alamat for show the order
+	LogAlamat *LogAlamat `gorm:"foreignKey:IDTrx"`
 }
 
 func (Trx) TableName() string {
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// TOTP setting per user, Enabled false until user confirm first code
+type UserTwoFactor struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	IDUser        uint       `gorm:"column:id_user;unique"`
+	Secret        string     `gorm:"size:255" json:"-"`
+	Enabled       bool       `gorm:"default:false"`
+	LastUsedStep  int64      `gorm:"column:last_used_step" json:"-"` // block same code used twice
+	ConfirmedAt   *time.Time `gorm:"column:confirmed_at"`
+	CreatedAtDate time.Time  `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time  `gorm:"column:updated_at_date"`
+}
+
+func (UserTwoFactor) TableName() string {
+	return "user_two_factor"
+}
+
+// one time recovery code, only bcrypt hash stored
+type RecoveryCode struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	IDUser        uint       `gorm:"column:id_user;index"`
+	CodeHash      string     `gorm:"size:255" json:"-"`
+	UsedAt        *time.Time `gorm:"column:used_at"`
+	CreatedAtDate time.Time  `gorm:"column:created_at_date"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_code"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// UlasanProduk is rating of produk by user who bought it, one per user per produk (sent again = replaced)
+type UlasanProduk struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	IDUser        uint      `gorm:"column:id_user;uniqueIndex:idx_ulasan_user_produk"`
+	IDProduk      uint      `gorm:"column:id_produk;uniqueIndex:idx_ulasan_user_produk;index"`
+	Rating        int       // 1 - 5
+	Komentar      string    `gorm:"type:text"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+}
+
+func (UlasanProduk) TableName() string {
+	return "ulasan_produk"
+}
@@ -15,6 +15,10 @@ type User struct {
 	IDProvinsi   int       `gorm:"column:id_provinsi"`
 	IDKota       int       `gorm:"column:id_kota"`
 	IsAdmin      bool      `gorm:"default:false"`
+	SuspendedAt     *time.Time `gorm:"column:suspended_at"` // suspended user cant login or use token
+	SuspendedReason string     `gorm:"size:255"`
+	DeletionScheduledAt *time.Time `gorm:"column:deletion_scheduled_at;index"` // account anonymized after this time
+	AnonymizedAt        *time.Time `gorm:"column:anonymized_at"`
 	CreatedAtDate time.Time `gorm:"column:created_at_date"`
 	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
 
@@ -22,6 +26,9 @@ type User struct {
 	Toko    Toko    `gorm:"foreignKey:IDUser"` 
 	Alamat  []Alamat `gorm:"foreignKey:IDUser"`
 	Trx     []Trx    `gorm:"foreignKey:IDUser"`
+
+	// Relasi ke role (RBAC)
+	Roles []Role `gorm:"many2many:user_role;joinForeignKey:id_user;joinReferences:id_role" json:"Roles,omitempty"`
 }
 
 // nama tabel 
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// link between user and external OIDC account, one provider subject only belong to one user
+type UserIdentity struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	IDUser        uint      `gorm:"column:id_user;index"`
+	Provider      string    `gorm:"size:50;uniqueIndex:idx_provider_subject"`
+	Subject       string    `gorm:"size:255;uniqueIndex:idx_provider_subject"`
+	Email         string    `gorm:"size:255"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+	UpdatedAtDate time.Time `gorm:"column:updated_at_date"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identity"
+}
+
+// pending OIDC login, kept server side so PKCE verifier never leave the server
+type OIDCLoginState struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	State         string    `gorm:"size:100;unique"`
+	Provider      string    `gorm:"size:50"`
+	CodeVerifier  string    `gorm:"size:255" json:"-"`
+	Nonce         string    `gorm:"size:255" json:"-"`
+	ExpiresAt     time.Time `gorm:"column:expires_at;index"`
+	CreatedAtDate time.Time `gorm:"column:created_at_date"`
+}
+
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_state"
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+)
+
+// all personal data of one user, used for data export
+type AccountData struct {
+	User      model.User
+	Addresses []model.Alamat
+	Toko      *model.Toko
+	Produk    []model.Produk
+	Trx       []model.Trx
+}
+
+// query that touch many table for one account (export & anonymize)
+type AccountRepository interface {
+	FindAccountData(userID uint) (AccountData, error)
+	FindDueDeletions(now time.Time) ([]model.User, error)
+	Anonymize(userID uint, now time.Time) ([]string, error) // return uploaded file path that can be removed
+}
+
+type accountRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountRepository(db *gorm.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+func (r *accountRepository) FindAccountData(userID uint) (AccountData, error) {
+	var data AccountData
+	if err := r.db.Where("id = ?", userID).First(&data.User).Error; err != nil {
+		return data, err
+	}
+	if err := r.db.Where("id_user = ?", userID).Order("id").Find(&data.Addresses).Error; err != nil {
+		return data, err
+	}
+
+	var toko model.Toko
+	err := r.db.Where("id_user = ?", userID).First(&toko).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return data, err
+	}
+	if err == nil {
+		data.Toko = &toko
+		if err := r.db.Preload("FotoProduk").Preload("Category").Where("id_toko = ?", toko.ID).Order("id").Find(&data.Produk).Error; err != nil {
+			return data, err
+		}
+	}
+
+	err = r.db.Preload("DetailTrx").Preload("DetailTrx.LogProduk").Preload("LogAlamat").
+		Where("id_user = ?", userID).Order("id").Find(&data.Trx).Error
+	return data, err
+}
+
+func (r *accountRepository) FindDueDeletions(now time.Time) ([]model.User, error) {
+	var users []model.User
+	err := r.db.Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", now).Find(&users).Error
+	return users, err
+}
+
+// personal field replaced, trx, detail_trx and log_produk kept for accounting
+// row referenced by trx history (alamat, toko, produk in log_produk) is anonymized, not deleted
+func (r *accountRepository) Anonymize(userID uint, now time.Time) ([]string, error) {
+	var removedFiles []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+
+		anonymousEmail := fmt.Sprintf("deleted-%d@deleted.invalid", userID)
+		err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"nama":                  "Deleted User",
+			"kata_sandi":            "",
+			"no_telp":               fmt.Sprintf("deleted-%d", userID),
+			"tanggal_lahir":         nil,
+			"jenis_kelamin":         "",
+			"tentang":               "",
+			"pekerjaan":             "",
+			"email":                 anonymousEmail,
+			"id_provinsi":           0,
+			"id_kota":               0,
+			"is_admin":              false,
+			"suspended_at":          nil,
+			"suspended_reason":      "",
+			"deletion_scheduled_at": nil,
+			"anonymized_at":         now,
+			"updated_at_date":       now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// soft deleted alamat and alamat snapshot in trx also contain personal data
+		err = tx.Unscoped().Model(&model.Alamat{}).Where("id_user = ?", userID).Updates(map[string]interface{}{
+			"judul_alamat":    "Deleted",
+			"nama_penerima":   "Deleted User",
+			"no_telp":         "",
+			"detail_alamat":   "",
+			"jalan":           "",
+			"catatan":         "",
+			"latitude":        nil,
+			"longitude":       nil,
+			"updated_at_date": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&model.LogAlamat{}).Where("id_user = ?", userID).Updates(map[string]interface{}{
+			"judul_alamat":    "Deleted",
+			"nama_penerima":   "Deleted User",
+			"no_telp":         "",
+			"detail_alamat":   "",
+			"jalan":           "",
+			"catatan":         "",
+			"latitude":        nil,
+			"longitude":       nil,
+			"updated_at_date": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var toko model.Toko
+		err = tx.Where("id_user = ?", userID).First(&toko).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			if toko.UrlFoto != "" {
+				removedFiles = append(removedFiles, toko.UrlFoto)
+			}
+			err = tx.Model(&model.Toko{}).Where("id = ?", toko.ID).Updates(map[string]interface{}{
+				"nama_toko":       fmt.Sprintf("Toko #%d", toko.ID),
+				"slug":            nil, // new slug from anonymized name on next start, NULL not blocked by unique index
+				"url_foto":        "",
+				"deskripsi":       "",
+				"no_telp":         "",
+				"email_kontak":    "",
+				"detail_alamat":   "",
+				"pesan_libur":     "",
+				"status":          model.TokoStatusSuspended, // hidden from buyer
+				"status_reason":   "account deleted",
+				"updated_at_date": now,
+			}).Error
+			if err != nil {
+				return err
+			}
+
+			// produk in trash included, anonymized produk never come back from trash
+			produkIDs := tx.Unscoped().Model(&model.Produk{}).Select("id").Where("id_toko = ?", toko.ID)
+			var photoURLs []string
+			if err := tx.Model(&model.FotoProduk{}).Where("id_produk IN (?)", produkIDs).Pluck("url", &photoURLs).Error; err != nil {
+				return err
+			}
+			removedFiles = append(removedFiles, photoURLs...)
+			if err := tx.Where("id_produk IN (?)", produkIDs).Delete(&model.FotoProduk{}).Error; err != nil {
+				return err
+			}
+
+			// staff lose access to toko of deleted account
+			if err := tx.Where("id_toko = ?", toko.ID).Delete(&model.TokoMember{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id_toko = ?", toko.ID).Delete(&model.TokoInvitation{}).Error; err != nil {
+				return err
+			}
+
+			// produk in transaction history stay (log_produk reference it), just cant be bought again
+			// NULL in NOT IN subquery match no row
+			soldIDs := tx.Model(&model.LogProduk{}).Select("id_produk").Where("id_produk IS NOT NULL")
+			unsoldIDs := tx.Unscoped().Model(&model.Produk{}).Select("id").Where("id_toko = ? AND id NOT IN (?)", toko.ID, soldIDs)
+			if err := tx.Where("id_produk IN (?)", unsoldIDs).Delete(&model.ProdukAtribut{}).Error; err != nil {
+				return err
+			}
+			// hard delete, soft delete would leave them in trash of the anonymized toko
+			if err := tx.Unscoped().Where("id_toko = ? AND id NOT IN (?)", toko.ID, soldIDs).Delete(&model.Produk{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&model.Produk{}).Where("id_toko = ?", toko.ID).Updates(map[string]interface{}{"stok": 0, "updated_at_date": now}).Error; err != nil {
+				return err
+			}
+		}
+
+		cleanups := []interface{}{&model.RecoveryCode{}, &model.UserTwoFactor{}, &model.APIKey{}, &model.UserIdentity{}, &model.TokoMember{}}
+		for _, table := range cleanups {
+			if err := tx.Where("id_user = ?", userID).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM user_role WHERE id_user = ?", userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("email = ? AND accepted_at IS NULL", user.Email).Delete(&model.TokoInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("`key` = ?", "account:"+strings.ToLower(strings.TrimSpace(user.Email))).Delete(&model.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.LoginAttempt{}).Where("id_user = ? OR email = ?", userID, user.Email).
+			Updates(map[string]interface{}{"email": anonymousEmail, "ip_address": ""}).Error
+	})
+	return removedFiles, err
+}
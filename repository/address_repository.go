@@ -1,17 +1,23 @@
 package repository
 
 import (
+	"errors"
+
 	"rakamin-evermos/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AddressRepository interface {
 	Save(alamat model.Alamat) (model.Alamat, error)
 	FindAllByUserID(userID uint) ([]model.Alamat, error)
 	FindByIDAndUserID(addressID, userID uint) (model.Alamat, error)
+	FindDefaultByUserID(userID uint) (model.Alamat, error)
 	Update(alamat model.Alamat) (model.Alamat, error)
+	SetDefault(addressID, userID uint) error
 	Delete(alamat model.Alamat) error
+	AssignMissingDefaults() error
 }
 
 type addressRepository struct {
@@ -22,19 +28,44 @@ func NewAddressRepository(db *gorm.DB) AddressRepository {
 	return &addressRepository{db}
 }
 
+// lock user row so concurrent request for same user run one by one,
+// then at most one alamat keep is_default = true
+func lockAddressOwner(tx *gorm.DB, userID uint) error {
+	var user model.User
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", userID).First(&user).Error
+}
 
+// first alamat of user always become default
 func (r *addressRepository) Save(alamat model.Alamat) (model.Alamat, error) {
-	err := r.db.Create(&alamat).Error
-	if err != nil {
-		return alamat, err
-	}
-	return alamat, nil
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressOwner(tx, alamat.IDUser); err != nil {
+			return err
+		}
+
+		var defaultCount int64
+		err := tx.Model(&model.Alamat{}).Where("id_user = ? AND is_default = ?", alamat.IDUser, true).Count(&defaultCount).Error
+		if err != nil {
+			return err
+		}
+		if defaultCount == 0 {
+			alamat.IsDefault = true
+		}
+
+		if alamat.IsDefault {
+			err = tx.Model(&model.Alamat{}).Where("id_user = ? AND is_default = ?", alamat.IDUser, true).Update("is_default", false).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(&alamat).Error
+	})
+	return alamat, err
 }
 
-// get all alamat based on ID User
+// get all alamat based on ID User, default alamat first
 func (r *addressRepository) FindAllByUserID(userID uint) ([]model.Alamat, error) {
 	var alamats []model.Alamat
-	err := r.db.Where("id_user = ?", userID).Find(&alamats).Error
+	err := r.db.Where("id_user = ?", userID).Order("is_default DESC, id").Find(&alamats).Error
 	if err != nil {
 		return alamats, err
 	}
@@ -51,18 +82,85 @@ func (r *addressRepository) FindByIDAndUserID(addressID, userID uint) (model.Ala
 	return alamat, nil
 }
 
+func (r *addressRepository) FindDefaultByUserID(userID uint) (model.Alamat, error) {
+	var alamat model.Alamat
+	err := r.db.Where("id_user = ? AND is_default = ?", userID, true).First(&alamat).Error
+	return alamat, err
+}
+
+// is_default is changed only by Save, SetDefault and Delete
 func (r *addressRepository) Update(alamat model.Alamat) (model.Alamat, error) {
-	err := r.db.Save(&alamat).Error
+	err := r.db.Omit("is_default").Save(&alamat).Error
 	if err != nil {
 		return alamat, err
 	}
 	return alamat, nil
 }
 
+func (r *addressRepository) SetDefault(addressID, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressOwner(tx, userID); err != nil {
+			return err
+		}
+
+		var alamat model.Alamat
+		if err := tx.Where("id = ? AND id_user = ?", addressID, userID).First(&alamat).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&model.Alamat{}).Where("id_user = ? AND id <> ? AND is_default = ?", userID, addressID, true).Update("is_default", false).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&alamat).Update("is_default", true).Error
+	})
+}
+
+// alamat used by trx only soft deleted (trx.alamat_pengiriman keep valid),
+// when default alamat deleted, oldest other alamat become default
 func (r *addressRepository) Delete(alamat model.Alamat) error {
-	err := r.db.Delete(&alamat).Error
-	if err != nil {
-		return err
-	}
-	return nil
-}
\ No newline at end of file
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressOwner(tx, alamat.IDUser); err != nil {
+			return err
+		}
+
+		var trxCount int64
+		if err := tx.Model(&model.Trx{}).Where("alamat_pengiriman = ?", alamat.ID).Count(&trxCount).Error; err != nil {
+			return err
+		}
+		if trxCount > 0 {
+			if err := tx.Model(&alamat).Update("is_default", false).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&alamat).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Unscoped().Delete(&alamat).Error; err != nil {
+			return err
+		}
+
+		var defaultCount int64
+		err := tx.Model(&model.Alamat{}).Where("id_user = ? AND is_default = ?", alamat.IDUser, true).Count(&defaultCount).Error
+		if err != nil || defaultCount > 0 {
+			return err
+		}
+
+		var next model.Alamat
+		err = tx.Where("id_user = ?", alamat.IDUser).Order("id").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+}
+
+// alamat created before is_default exist: oldest alamat of each user become default
+func (r *addressRepository) AssignMissingDefaults() error {
+	return r.db.Exec(
+		"UPDATE alamat SET is_default = ? WHERE id IN (SELECT id FROM (SELECT MIN(id) AS id FROM alamat WHERE deleted_at IS NULL GROUP BY id_user HAVING SUM(is_default) = 0) AS first_alamat)",
+		true,
+	).Error
+}
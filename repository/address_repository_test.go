@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"testing"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/utils/testdb"
+
+	"gorm.io/gorm"
+)
+
+func defaultAlamatIDs(t *testing.T, db *gorm.DB, userID uint) []uint {
+	t.Helper()
+	var ids []uint
+	if err := db.Model(&model.Alamat{}).Where("id_user = ? AND is_default = ?", userID, true).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestAddressRepositoryMoveDefault(t *testing.T) {
+	db := testdb.Open(t, &model.User{}, &model.Alamat{}, &model.Trx{})
+	db.Create(&model.User{ID: 1, Email: "budi@example.com", NoTelp: "0811"})
+	repo := NewAddressRepository(db)
+
+	rumah, _ := repo.Save(model.Alamat{IDUser: 1, JudulAlamat: "Rumah"})
+	kantor, _ := repo.Save(model.Alamat{IDUser: 1, JudulAlamat: "Kantor"})
+	if !rumah.IsDefault || kantor.IsDefault {
+		t.Fatalf("expected first alamat default, got %v %v", rumah.IsDefault, kantor.IsDefault)
+	}
+
+	if err := repo.SetDefault(kantor.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ids := defaultAlamatIDs(t, db, 1); len(ids) != 1 || ids[0] != kantor.ID {
+		t.Fatalf("expected only kantor default, got %v", ids)
+	}
+
+	gudang, _ := repo.Save(model.Alamat{IDUser: 1, JudulAlamat: "Gudang", IsDefault: true})
+	if ids := defaultAlamatIDs(t, db, 1); len(ids) != 1 || ids[0] != gudang.ID {
+		t.Fatalf("expected only new default gudang, got %v", ids)
+	}
+}
+
+func TestAddressRepositoryDeleteDefault(t *testing.T) {
+	db := testdb.Open(t, &model.User{}, &model.Alamat{}, &model.Trx{})
+	db.Create(&model.User{ID: 1, Email: "budi@example.com", NoTelp: "0811"})
+	repo := NewAddressRepository(db)
+
+	rumah, _ := repo.Save(model.Alamat{IDUser: 1, JudulAlamat: "Rumah"})
+	kantor, _ := repo.Save(model.Alamat{IDUser: 1, JudulAlamat: "Kantor"})
+	repo.Save(model.Alamat{IDUser: 1, JudulAlamat: "Gudang"})
+
+	// oldest other alamat become default
+	if err := repo.Delete(rumah); err != nil {
+		t.Fatal(err)
+	}
+	if ids := defaultAlamatIDs(t, db, 1); len(ids) != 1 || ids[0] != kantor.ID {
+		t.Fatalf("expected kantor promoted to default, got %v", ids)
+	}
+}
+
+// alamat used by trx only soft deleted, never used alamat hard deleted
+func TestAddressRepositoryDeleteUsedByTrx(t *testing.T) {
+	db := testdb.Open(t, &model.User{}, &model.Alamat{}, &model.Trx{})
+	db.Create(&model.User{ID: 1, Email: "budi@example.com", NoTelp: "0811"})
+	repo := NewAddressRepository(db)
+
+	rumah, _ := repo.Save(model.Alamat{IDUser: 1, JudulAlamat: "Rumah"})
+	gudang, _ := repo.Save(model.Alamat{IDUser: 1, JudulAlamat: "Gudang"})
+	db.Create(&model.Trx{IDUser: 1, AlamatPengiriman: rumah.ID, KodeInvoice: "INV/1/a"})
+
+	if err := repo.Delete(rumah); err != nil {
+		t.Fatal(err)
+	}
+	var deleted model.Alamat
+	if err := db.Unscoped().First(&deleted, rumah.ID).Error; err != nil || !deleted.DeletedAt.Valid || deleted.IsDefault {
+		t.Fatalf("expected alamat used by trx soft deleted and not default, got %+v %v", deleted, err)
+	}
+
+	if err := repo.Delete(gudang); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Unscoped().Model(&model.Alamat{}).Where("id = ?", gudang.ID).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected unused alamat hard deleted, got %d row", count)
+	}
+}
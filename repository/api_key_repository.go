@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"time"
+
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Save(apiKey model.APIKey) (model.APIKey, error)
+	FindAllByUserID(userID uint) ([]model.APIKey, error)
+	FindByIDAndUserID(apiKeyID, userID uint) (model.APIKey, error)
+	FindByHash(keyHash string) (model.APIKey, error)
+	Update(apiKey model.APIKey) (model.APIKey, error)
+	UpdateLastUsed(apiKeyID uint, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db}
+}
+
+func (r *apiKeyRepository) Save(apiKey model.APIKey) (model.APIKey, error) {
+	err := r.db.Create(&apiKey).Error
+	return apiKey, err
+}
+
+func (r *apiKeyRepository) FindAllByUserID(userID uint) ([]model.APIKey, error) {
+	var apiKeys []model.APIKey
+	err := r.db.Where("id_user = ?", userID).Order("id DESC").Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+func (r *apiKeyRepository) FindByIDAndUserID(apiKeyID, userID uint) (model.APIKey, error) {
+	var apiKey model.APIKey
+	err := r.db.Where("id = ? AND id_user = ?", apiKeyID, userID).First(&apiKey).Error
+	return apiKey, err
+}
+
+func (r *apiKeyRepository) FindByHash(keyHash string) (model.APIKey, error) {
+	var apiKey model.APIKey
+	err := r.db.Where("key_hash = ?", keyHash).First(&apiKey).Error
+	return apiKey, err
+}
+
+func (r *apiKeyRepository) Update(apiKey model.APIKey) (model.APIKey, error) {
+	err := r.db.Save(&apiKey).Error
+	return apiKey, err
+}
+
+// only touch last_used_at, called on every authenticated request
+func (r *apiKeyRepository) UpdateLastUsed(apiKeyID uint, usedAt time.Time) error {
+	return r.db.Model(&model.APIKey{}).Where("id = ?", apiKeyID).Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"rakamin-evermos/model"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+// audit log parameter filter, zero value is ignored
+type AuditLogFilter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+}
+
+type AuditLogRepository interface {
+	Save(log model.AuditLog) (model.AuditLog, error)
+	FindAll(filter AuditLogFilter, pagination utils.PaginationInput) ([]model.AuditLog, int64, error)
+}
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db}
+}
+
+func (r *auditLogRepository) Save(log model.AuditLog) (model.AuditLog, error) {
+	err := r.db.Create(&log).Error
+	return log, err
+}
+
+// newest first
+func (r *auditLogRepository) FindAll(filter AuditLogFilter, pagination utils.PaginationInput) ([]model.AuditLog, int64, error) {
+	var logs []model.AuditLog
+	var totalData int64
+
+	query := r.db.Model(&model.AuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("id_actor = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+
+	err := query.Count(&totalData).Error
+	if err != nil {
+		return logs, totalData, err
+	}
+
+	err = query.Order("id DESC").Scopes(utils.Paginate(pagination.Page, pagination.Limit)).Find(&logs).Error
+	return logs, totalData, err
+}
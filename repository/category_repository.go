@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"time"
+
 	"rakamin-evermos/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CategoryRepository interface {
@@ -12,6 +15,28 @@ type CategoryRepository interface {
 	FindByID(categoryID uint) (model.Category, error)
 	Update(category model.Category) (model.Category, error)
 	Delete(category model.Category) error
+
+	FindBySlug(slug string) (model.Category, error)
+	CountChildren(categoryID uint) (int64, error)
+	SlugExists(slug string, exceptCategoryID uint) (bool, error)
+	FindAllWithoutSlug() ([]model.Category, error)
+
+	CountProduk(categoryID uint) (int64, error)
+	ReassignAndDelete(categoryID, targetID uint, now time.Time) (ReassignResult, error)
+
+	// atribut schema
+	FindAtributByCategoryIDs(categoryIDs []uint) ([]model.CategoryAtribut, error)
+	FindAtributByID(categoryID, atributID uint) (model.CategoryAtribut, error)
+	SaveAtribut(atribut model.CategoryAtribut) (model.CategoryAtribut, error)
+	UpdateAtribut(atribut model.CategoryAtribut) (model.CategoryAtribut, error)
+	DeleteAtribut(atribut model.CategoryAtribut) error
+	CountNilaiNotIn(atributID uint, nilai []string) (int64, error)
+}
+
+// row moved from deleted category to target
+type ReassignResult struct {
+	Produk      int64
+	SubCategory int64
 }
 
 type categoryRepository struct {
@@ -32,7 +57,7 @@ func (r *categoryRepository) Save(category model.Category) (model.Category, erro
 
 func (r *categoryRepository) FindAll() ([]model.Category, error) {
 	var categories []model.Category
-	err := r.db.Find(&categories).Error
+	err := r.db.Order("urutan, nama_category, id").Find(&categories).Error
 	if err != nil {
 		return categories, err
 	}
@@ -62,4 +87,118 @@ func (r *categoryRepository) Delete(category model.Category) error {
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *categoryRepository) FindBySlug(slug string) (model.Category, error) {
+	var category model.Category
+	err := r.db.Where("slug = ?", slug).First(&category).Error
+	return category, err
+}
+
+func (r *categoryRepository) CountChildren(categoryID uint) (int64, error) {
+	var total int64
+	err := r.db.Model(&model.Category{}).Where("id_parent = ?", categoryID).Count(&total).Error
+	return total, err
+}
+
+func (r *categoryRepository) SlugExists(slug string, exceptCategoryID uint) (bool, error) {
+	var total int64
+	err := r.db.Model(&model.Category{}).Where("slug = ? AND id <> ?", slug, exceptCategoryID).Count(&total).Error
+	return total > 0, err
+}
+
+// category created before slug exist
+func (r *categoryRepository) FindAllWithoutSlug() ([]model.Category, error) {
+	var categories []model.Category
+	err := r.db.Where("slug = ? OR slug IS NULL", "").Order("id").Find(&categories).Error
+	return categories, err
+}
+
+// produk in trash counted too, it can still be restored
+func (r *categoryRepository) CountProduk(categoryID uint) (int64, error) {
+	var total int64
+	err := r.db.Unscoped().Model(&model.Produk{}).Where("id_category = ?", categoryID).Count(&total).Error
+	return total, err
+}
+
+// produk and sub kategori moved to target then category deleted, all or nothing.
+// both row locked so target can't be deleted while produk moved into it
+func (r *categoryRepository) ReassignAndDelete(categoryID, targetID uint, now time.Time) (ReassignResult, error) {
+	var result ReassignResult
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var locked []model.Category
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", []uint{categoryID, targetID}).Find(&locked).Error; err != nil {
+			return err
+		}
+		if len(locked) != 2 {
+			return gorm.ErrRecordNotFound
+		}
+
+		moved := tx.Unscoped().Model(&model.Produk{}).Where("id_category = ?", categoryID).
+			Updates(map[string]interface{}{"id_category": targetID, "updated_at_date": now})
+		if moved.Error != nil {
+			return moved.Error
+		}
+		result.Produk = moved.RowsAffected
+
+		moved = tx.Model(&model.Category{}).Where("id_parent = ?", categoryID).
+			Updates(map[string]interface{}{"id_parent": targetID, "updated_at_date": now})
+		if moved.Error != nil {
+			return moved.Error
+		}
+		result.SubCategory = moved.RowsAffected
+
+		// atribut schema follow the produk, kode clash already checked by usecase
+		err := tx.Model(&model.CategoryAtribut{}).Where("id_category = ?", categoryID).
+			Updates(map[string]interface{}{"id_category": targetID, "updated_at_date": now}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("id = ?", categoryID).Delete(&model.Category{}).Error
+	})
+	return result, err
+}
+
+func (r *categoryRepository) FindAtributByCategoryIDs(categoryIDs []uint) ([]model.CategoryAtribut, error) {
+	var atribut []model.CategoryAtribut
+	err := r.db.Where("id_category IN ?", categoryIDs).Order("urutan, id").Find(&atribut).Error
+	return atribut, err
+}
+
+func (r *categoryRepository) FindAtributByID(categoryID, atributID uint) (model.CategoryAtribut, error) {
+	var atribut model.CategoryAtribut
+	err := r.db.Where("id = ? AND id_category = ?", atributID, categoryID).First(&atribut).Error
+	return atribut, err
+}
+
+func (r *categoryRepository) SaveAtribut(atribut model.CategoryAtribut) (model.CategoryAtribut, error) {
+	err := r.db.Create(&atribut).Error
+	return atribut, err
+}
+
+func (r *categoryRepository) UpdateAtribut(atribut model.CategoryAtribut) (model.CategoryAtribut, error) {
+	err := r.db.Save(&atribut).Error
+	return atribut, err
+}
+
+// nilai of produk deleted together with the schema
+func (r *categoryRepository) DeleteAtribut(atribut model.CategoryAtribut) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id_atribut = ?", atribut.ID).Delete(&model.ProdukAtribut{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&atribut).Error
+	})
+}
+
+// produk nilai that not one of nilai, used before enum opsi removed
+func (r *categoryRepository) CountNilaiNotIn(atributID uint, nilai []string) (int64, error) {
+	var total int64
+	query := r.db.Model(&model.ProdukAtribut{}).Where("id_atribut = ?", atributID)
+	if len(nilai) > 0 {
+		query = query.Where("nilai NOT IN ?", nilai)
+	}
+	err := query.Count(&total).Error
+	return total, err
+}
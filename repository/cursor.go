@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"fmt"
+
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// keyset order of list: Expr then id, both in same direction
+type cursorKey struct {
+	Scope string
+	Expr  string        // empty = order by id only
+	Vars  []interface{} // vars of Expr
+	Desc  bool
+}
+
+// row after position in key order, one more row than limit to know if there is next page
+func pageAfter(query *gorm.DB, key cursorKey, after *utils.CursorPosition, afterValue interface{}, limit int) *gorm.DB {
+	op, dir := ">", "ASC"
+	if key.Desc {
+		op, dir = "<", "DESC"
+	}
+
+	if after != nil {
+		if key.Expr == "" {
+			query = query.Where("id "+op+" ?", after.ID)
+		} else {
+			vars := append([]interface{}{}, key.Vars...)
+			vars = append(vars, afterValue)
+			vars = append(vars, key.Vars...)
+			vars = append(vars, afterValue, after.ID)
+			query = query.Where(clause.Expr{
+				SQL:                fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", key.Expr, op, key.Expr, op),
+				Vars:               vars,
+				WithoutParentheses: true,
+			})
+		}
+	}
+
+	if key.Expr == "" {
+		return query.Order("id " + dir).Limit(limit + 1)
+	}
+	// one expression, gorm drop order expression when merged with next Order
+	orderBy := clause.Expr{SQL: fmt.Sprintf("%s %s, id %s", key.Expr, dir, dir), Vars: key.Vars, WithoutParentheses: true}
+	return query.Order(clause.OrderBy{Expression: orderBy}).Limit(limit + 1)
+}
+
+// cursor made for other list or sort can't be used
+func checkCursorScope(key cursorKey, after *utils.CursorPosition) error {
+	if after != nil && after.Scope != key.Scope {
+		return fmt.Errorf("%w: cursor is for other list or sort", utils.ErrCursorNotValid)
+	}
+	return nil
+}
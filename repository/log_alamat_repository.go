@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+)
+
+type LogAlamatRepository interface {
+	Save(tx *gorm.DB, logAlamat model.LogAlamat) (model.LogAlamat, error)
+	BackfillMissing() (int64, error)
+}
+
+type logAlamatRepository struct {
+	db *gorm.DB
+}
+
+func NewLogAlamatRepository(db *gorm.DB) LogAlamatRepository {
+	return &logAlamatRepository{db}
+}
+
+func (r *logAlamatRepository) Save(tx *gorm.DB, logAlamat model.LogAlamat) (model.LogAlamat, error) {
+	// use tx from usecase not r db
+	err := tx.Create(&logAlamat).Error
+	return logAlamat, err
+}
+
+// trx created before snapshot exist copy current alamat (include soft deleted one)
+func (r *logAlamatRepository) BackfillMissing() (int64, error) {
+	result := r.db.Exec(`INSERT INTO log_alamat (id_trx, id_alamat, id_user, judul_alamat, nama_penerima, no_telp, detail_alamat,
+		id_provinsi, id_kota, id_kecamatan, id_kelurahan, kode_pos, jalan, catatan, latitude, longitude, created_at_date, updated_at_date)
+		SELECT trx.id, alamat.id, alamat.id_user, alamat.judul_alamat, alamat.nama_penerima, alamat.no_telp, alamat.detail_alamat,
+		alamat.id_provinsi, alamat.id_kota, alamat.id_kecamatan, alamat.id_kelurahan, alamat.kode_pos, alamat.jalan, alamat.catatan,
+		alamat.latitude, alamat.longitude, trx.created_at_date, trx.created_at_date
+		FROM trx JOIN alamat ON alamat.id = trx.alamat_pengiriman
+		WHERE NOT EXISTS (SELECT 1 FROM log_alamat WHERE log_alamat.id_trx = trx.id)`)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+// in memory version of LoginAttemptRepository, no db needed (used in test)
+type memoryLoginAttemptRepository struct {
+	mu        sync.Mutex
+	attempts  []model.LoginAttempt
+	throttles map[string]model.LoginThrottle
+	lastID    uint
+}
+
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{throttles: map[string]model.LoginThrottle{}}
+}
+
+func (r *memoryLoginAttemptRepository) SaveAttempt(attempt model.LoginAttempt) (model.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	attempt.ID = r.lastID
+	r.attempts = append(r.attempts, attempt)
+	return attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) FindAttempts(email string, pagination utils.PaginationInput) ([]model.LoginAttempt, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// newest first, same as db version
+	var filtered []model.LoginAttempt
+	for i := len(r.attempts) - 1; i >= 0; i-- {
+		if email == "" || r.attempts[i].Email == email {
+			filtered = append(filtered, r.attempts[i])
+		}
+	}
+
+	totalData := int64(len(filtered))
+	start := (pagination.Page - 1) * pagination.Limit
+	if start >= len(filtered) {
+		return []model.LoginAttempt{}, totalData, nil
+	}
+	end := start + pagination.Limit
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	return filtered[start:end], totalData, nil
+}
+
+func (r *memoryLoginAttemptRepository) FindThrottle(key string) (model.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	throttle, ok := r.throttles[key]
+	if !ok {
+		return throttle, gorm.ErrRecordNotFound
+	}
+	return throttle, nil
+}
+
+func (r *memoryLoginAttemptRepository) UpdateThrottle(key string, now time.Time, update func(throttle *model.LoginThrottle)) (model.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	throttle, ok := r.throttles[key]
+	if !ok {
+		r.lastID++
+		throttle = model.LoginThrottle{ID: r.lastID, Key: key, LastFailedAt: now, CreatedAtDate: now, UpdatedAtDate: now}
+	}
+	update(&throttle)
+	r.throttles[key] = throttle
+	return throttle, nil
+}
+
+func (r *memoryLoginAttemptRepository) DeleteThrottle(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.throttles, key)
+	return nil
+}
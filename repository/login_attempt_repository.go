@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"rakamin-evermos/model"
+	"rakamin-evermos/utils"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// storage for login audit trail and throttle counter
+// implemented with gorm (db) and in memory (for test)
+type LoginAttemptRepository interface {
+	SaveAttempt(attempt model.LoginAttempt) (model.LoginAttempt, error)
+	FindAttempts(email string, pagination utils.PaginationInput) ([]model.LoginAttempt, int64, error)
+
+	FindThrottle(key string) (model.LoginThrottle, error)
+	// update run while row locked, so parallel failed login never read same counter. row created when missing
+	UpdateThrottle(key string, now time.Time, update func(throttle *model.LoginThrottle)) (model.LoginThrottle, error)
+	DeleteThrottle(key string) error
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db}
+}
+
+func (r *loginAttemptRepository) SaveAttempt(attempt model.LoginAttempt) (model.LoginAttempt, error) {
+	err := r.db.Create(&attempt).Error
+	return attempt, err
+}
+
+// newest attempt first, filter by email if not empty
+func (r *loginAttemptRepository) FindAttempts(email string, pagination utils.PaginationInput) ([]model.LoginAttempt, int64, error) {
+	var attempts []model.LoginAttempt
+	var totalData int64
+
+	query := r.db.Model(&model.LoginAttempt{})
+	if email != "" {
+		query = query.Where("email = ?", email)
+	}
+
+	err := query.Count(&totalData).Error
+	if err != nil {
+		return attempts, totalData, err
+	}
+
+	err = query.Order("id DESC").Scopes(utils.Paginate(pagination.Page, pagination.Limit)).Find(&attempts).Error
+	return attempts, totalData, err
+}
+
+func (r *loginAttemptRepository) FindThrottle(key string) (model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	err := r.db.Where("`key` = ?", key).First(&throttle).Error
+	return throttle, err
+}
+
+func (r *loginAttemptRepository) UpdateThrottle(key string, now time.Time, update func(throttle *model.LoginThrottle)) (model.LoginThrottle, error) {
+	// insert outside transaction, duplicate insert inside it hold shared lock and deadlock with FOR UPDATE
+	newThrottle := model.LoginThrottle{Key: key, LastFailedAt: now, CreatedAtDate: now, UpdatedAtDate: now}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&newThrottle).Error; err != nil {
+		return model.LoginThrottle{}, err
+	}
+
+	var throttle model.LoginThrottle
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("`key` = ?", key).First(&throttle).Error; err != nil {
+			return err
+		}
+		update(&throttle)
+		return tx.Save(&throttle).Error
+	})
+	return throttle, err
+}
+
+func (r *loginAttemptRepository) DeleteThrottle(key string) error {
+	return r.db.Where("`key` = ?", key).Delete(&model.LoginThrottle{}).Error
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+)
+
+// in memory version of OIDCRepository, no db needed (used in test)
+type memoryOIDCRepository struct {
+	mu         sync.Mutex
+	identities []model.UserIdentity
+	states     map[string]model.OIDCLoginState
+	lastID     uint
+}
+
+func NewMemoryOIDCRepository() OIDCRepository {
+	return &memoryOIDCRepository{states: map[string]model.OIDCLoginState{}}
+}
+
+func (r *memoryOIDCRepository) FindIdentity(provider, subject string) (model.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return model.UserIdentity{}, gorm.ErrRecordNotFound
+}
+
+func (r *memoryOIDCRepository) SaveIdentity(identity model.UserIdentity) (model.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject && existing.ID != identity.ID {
+			return identity, errors.New("duplicate provider subject")
+		}
+	}
+	for i, existing := range r.identities {
+		if identity.ID != 0 && existing.ID == identity.ID {
+			r.identities[i] = identity
+			return identity, nil
+		}
+	}
+
+	r.lastID++
+	identity.ID = r.lastID
+	r.identities = append(r.identities, identity)
+	return identity, nil
+}
+
+func (r *memoryOIDCRepository) SaveLoginState(state model.OIDCLoginState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.states[state.State]; exists {
+		return errors.New("duplicate state")
+	}
+	r.states[state.State] = state
+	return nil
+}
+
+func (r *memoryOIDCRepository) ConsumeLoginState(state string) (model.OIDCLoginState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loginState, ok := r.states[state]
+	if !ok {
+		return model.OIDCLoginState{}, gorm.ErrRecordNotFound
+	}
+	delete(r.states, state)
+	return loginState, nil
+}
+
+func (r *memoryOIDCRepository) DeleteExpiredLoginStates(before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, state := range r.states {
+		if state.ExpiresAt.Before(before) {
+			delete(r.states, key)
+		}
+	}
+	return nil
+}
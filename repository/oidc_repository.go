@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"time"
+
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+)
+
+type OIDCRepository interface {
+	FindIdentity(provider, subject string) (model.UserIdentity, error)
+	SaveIdentity(identity model.UserIdentity) (model.UserIdentity, error)
+
+	SaveLoginState(state model.OIDCLoginState) error
+	ConsumeLoginState(state string) (model.OIDCLoginState, error) // state deleted, can only be used once
+	DeleteExpiredLoginStates(before time.Time) error
+}
+
+type oidcRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCRepository(db *gorm.DB) OIDCRepository {
+	return &oidcRepository{db}
+}
+
+func (r *oidcRepository) FindIdentity(provider, subject string) (model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return identity, err
+}
+
+func (r *oidcRepository) SaveIdentity(identity model.UserIdentity) (model.UserIdentity, error) {
+	err := r.db.Save(&identity).Error
+	return identity, err
+}
+
+func (r *oidcRepository) SaveLoginState(state model.OIDCLoginState) error {
+	return r.db.Create(&state).Error
+}
+
+// only the request that really delete the row get the state, so callback cant be replayed
+func (r *oidcRepository) ConsumeLoginState(state string) (model.OIDCLoginState, error) {
+	var loginState model.OIDCLoginState
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state = ?", state).First(&loginState).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", loginState.ID).Delete(&model.OIDCLoginState{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	return loginState, err
+}
+
+func (r *oidcRepository) DeleteExpiredLoginStates(before time.Time) error {
+	return r.db.Where("expires_at < ?", before).Delete(&model.OIDCLoginState{}).Error
+}
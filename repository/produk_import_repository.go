@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"rakamin-evermos/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ProdukImportRepository interface {
+	Save(job model.ProdukImport) (model.ProdukImport, error)
+	Update(job model.ProdukImport) (model.ProdukImport, error)
+	FindByTokoIDAndID(tokoID, importID uint) (model.ProdukImport, error)
+
+	// oldest first, used by worker
+	FindByStatus(status string, limit int) ([]model.ProdukImport, error)
+	// pending -> processing, false when other worker already took it
+	Claim(importID uint, startedAt time.Time) (bool, error)
+}
+
+type produkImportRepository struct {
+	db *gorm.DB
+}
+
+func NewProdukImportRepository(db *gorm.DB) ProdukImportRepository {
+	return &produkImportRepository{db}
+}
+
+func (r *produkImportRepository) Save(job model.ProdukImport) (model.ProdukImport, error) {
+	err := r.db.Create(&job).Error
+	return job, err
+}
+
+func (r *produkImportRepository) Update(job model.ProdukImport) (model.ProdukImport, error) {
+	err := r.db.Save(&job).Error
+	return job, err
+}
+
+func (r *produkImportRepository) FindByTokoIDAndID(tokoID, importID uint) (model.ProdukImport, error) {
+	var job model.ProdukImport
+	err := r.db.Where("id = ? AND id_toko = ?", importID, tokoID).First(&job).Error
+	return job, err
+}
+
+func (r *produkImportRepository) FindByStatus(status string, limit int) ([]model.ProdukImport, error) {
+	var jobs []model.ProdukImport
+	err := r.db.Where("status = ?", status).Order("id ASC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *produkImportRepository) Claim(importID uint, startedAt time.Time) (bool, error) {
+	result := r.db.Model(&model.ProdukImport{}).
+		Where("id = ? AND status = ?", importID, model.ImportStatusPending).
+		Updates(map[string]interface{}{
+			"status":          model.ImportStatusProcessing,
+			"started_at":      startedAt,
+			"updated_at_date": startedAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
@@ -1,6 +1,11 @@
 package repository
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"rakamin-evermos/model"
 	"rakamin-evermos/utils"
 
@@ -11,22 +16,87 @@ import (
 //  produck parameter filter
 type FilterInput struct {
 	Search     string
-	CategoryID uint
+	SearchIDs  []uint // ranked hit of SearchIndex for Search, nil = use LIKE
+	CategoryID uint   // also include produk in sub category
+	Atribut    []AtributFilter
+	MinHarga   *int64 // harga_konsumen, inclusive
+	MaxHarga   *int64
+	InStock    bool
+	TokoID     uint
+	Status     string // seller list only, public list always published
+	Sort       string // one of Sort*, empty = most relevant when searched, else newest
+}
+
+// sort option of produk list
+const (
+	SortNewest      = "newest"
+	SortPriceAsc    = "price_asc"
+	SortPriceDesc   = "price_desc"
+	SortBestSelling = "best_selling"
+	SortRating      = "rating"
+)
+
+// harga_konsumen is saved as text, filter and sort use its indexed number copy
+const hargaKonsumenExpr = "harga_konsumen_angka"
+
+// jumlah produk per kategori, for facet of produk list
+type CategoryCount struct {
+	IDCategory   uint
+	NamaCategory string
+	Jumlah       int64
+}
+
+// filter by atribut kode, Nilai for exact match, Min/Max for number range
+type AtributFilter struct {
+	Kode  string
+	Nilai string
+	Min   *float64
+	Max   *float64
 }
 
 type ProdukRepository interface {
 	Save(produk model.Produk) (model.Produk, error)
 	Update(produk model.Produk) (model.Produk, error)
-	Delete(produk model.Produk) error
+	Delete(produk model.Produk) error // move to trash, atribut and foto kept for restore
 	FindByID(produkID uint) (model.Produk, error)
 
+	// trash of toko
+	FindDeletedByTokoID(tokoID uint, pagination utils.PaginationInput) ([]model.Produk, int64, error)
+	FindDeletedByTokoIDAndProdukID(tokoID, produkID uint) (model.Produk, error)
+	Restore(produk model.Produk) (model.Produk, error)
+
+	// produk in trash since before, purge remove foto and the row when not in trx history
+	FindPurgeable(deletedBefore time.Time, limit int) ([]model.Produk, error)
+	Purge(produkID uint) ([]string, error) // return uploaded file path that can be removed
+
 	FindByTokoIDAndProdukID(tokoID, produkID uint) (model.Produk, error)
 
 	FindAll(pagination utils.PaginationInput, filter FilterInput) ([]model.Produk, int64, error)
 	FindAllByTokoID(tokoID uint, pagination utils.PaginationInput, filter FilterInput) ([]model.Produk, int64, error)
 
+	// keyset pagination with same filter and sort, next nil = last page
+	FindAllAfter(after *utils.CursorPosition, limit int, filter FilterInput) ([]model.Produk, *utils.CursorPosition, error)
+	FindAllByTokoIDAfter(tokoID uint, after *utils.CursorPosition, limit int, filter FilterInput) ([]model.Produk, *utils.CursorPosition, error)
+
+	// same produk as public list (Produk.IsPublic) with Category and Toko, fn called per batch
+	FindAllPublicInBatches(batchSize int, fn func(produks []model.Produk) error) error
+	// every produk of toko / kategori, trash included, with Category and Toko. used to update search index
+	FindAllForIndexByTokoIDInBatches(tokoID uint, batchSize int, fn func(produks []model.Produk) error) error
+	FindAllForIndexByCategoryIDInBatches(categoryID uint, batchSize int, fn func(produks []model.Produk) error) error
+	// all produk of toko not in trash with Category and Atribut, used by export
+	FindAllByTokoIDInBatches(tokoID uint, batchSize int, fn func(produks []model.Produk) error) error
+	// kode of atribut filled on produk of toko not in trash, sorted
+	FindAtributKodeByTokoID(tokoID uint) ([]string, error)
+
+	// facet of public list, use same filter as FindAll
+	CountByCategory(filter FilterInput) ([]CategoryCount, error)
+	CountByHarga(filter FilterInput, bounds []int64) ([]int64, error)
+
 	FindByIDWithLock(tx *gorm.DB, produkID uint) (model.Produk, error)
 	UpdateWithTx(tx *gorm.DB, produk model.Produk) (model.Produk, error)
+
+	// run on startup, fill harga_konsumen_angka of produk saved before it exist
+	BackfillHargaAngka() (int64, error)
 }
 
 type produkRepository struct {
@@ -38,12 +108,33 @@ func NewProdukRepository(db *gorm.DB) ProdukRepository {
 }
 
 func (r *produkRepository) Save(produk model.Produk) (model.Produk, error) {
+	produk.HargaKonsumenAngka = hargaAngka(produk.HargaKonsumen)
 	err := r.db.Create(&produk).Error
 	return produk, err
 }
 
+// produk.Atribut replace all atribut value of produk, nil = keep current value
 func (r *produkRepository) Update(produk model.Produk) (model.Produk, error) {
-	err := r.db.Save(&produk).Error
+	produk.HargaKonsumenAngka = hargaAngka(produk.HargaKonsumen)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&produk).Error; err != nil {
+			return err
+		}
+		if produk.Atribut == nil {
+			return nil
+		}
+		if err := tx.Where("id_produk = ?", produk.ID).Delete(&model.ProdukAtribut{}).Error; err != nil {
+			return err
+		}
+		if len(produk.Atribut) == 0 {
+			return nil
+		}
+		for i := range produk.Atribut {
+			produk.Atribut[i].ID = 0
+			produk.Atribut[i].IDProduk = produk.ID
+		}
+		return tx.Omit("Atribut").Create(&produk.Atribut).Error
+	})
 	return produk, err
 }
 
@@ -51,10 +142,81 @@ func (r *produkRepository) Delete(produk model.Produk) error {
 	return r.db.Delete(&produk).Error
 }
 
+func (r *produkRepository) FindDeletedByTokoID(tokoID uint, pagination utils.PaginationInput) ([]model.Produk, int64, error) {
+	var produks []model.Produk
+	var totalData int64
+
+	query := r.db.Unscoped().Model(&model.Produk{}).Where("id_toko = ? AND deleted_at IS NOT NULL", tokoID)
+	if err := query.Count(&totalData).Error; err != nil {
+		return produks, totalData, err
+	}
+
+	err := query.Order("deleted_at DESC").Order("id DESC").Scopes(utils.Paginate(pagination.Page, pagination.Limit)).Preload("Category").Find(&produks).Error
+	return produks, totalData, err
+}
+
+func (r *produkRepository) FindDeletedByTokoIDAndProdukID(tokoID, produkID uint) (model.Produk, error) {
+	var produk model.Produk
+	err := r.db.Unscoped().Where("id = ? AND id_toko = ? AND deleted_at IS NOT NULL", produkID, tokoID).First(&produk).Error
+	return produk, err
+}
+
+func (r *produkRepository) Restore(produk model.Produk) (model.Produk, error) {
+	produk.DeletedAt = gorm.DeletedAt{}
+	produk.UpdatedAtDate = time.Now()
+	err := r.db.Unscoped().Model(&produk).
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at_date": produk.UpdatedAtDate}).Error
+	return produk, err
+}
+
+// produk still in trx history but already purged (no foto) not returned again
+func (r *produkRepository) FindPurgeable(deletedBefore time.Time, limit int) ([]model.Produk, error) {
+	var produks []model.Produk
+	soldIDs := r.db.Model(&model.LogProduk{}).Select("id_produk").Where("id_produk IS NOT NULL")
+	withFotoIDs := r.db.Model(&model.FotoProduk{}).Select("id_produk")
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at <= ?", deletedBefore).
+		Where("id NOT IN (?) OR id IN (?)", soldIDs, withFotoIDs).
+		Order("id").Limit(limit).Find(&produks).Error
+	return produks, err
+}
+
+// foto always removed. produk in log_produk is referenced by trx history, so the row stay in trash
+func (r *produkRepository) Purge(produkID uint) ([]string, error) {
+	var removedFiles []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var produk model.Produk
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_at IS NOT NULL", produkID).First(&produk).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.FotoProduk{}).Where("id_produk = ?", produkID).Pluck("url", &removedFiles).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id_produk = ?", produkID).Delete(&model.FotoProduk{}).Error; err != nil {
+			return err
+		}
+
+		var sold int64
+		if err := tx.Model(&model.LogProduk{}).Where("id_produk = ?", produkID).Count(&sold).Error; err != nil {
+			return err
+		}
+		if sold > 0 {
+			return nil
+		}
+		if err := tx.Where("id_produk = ?", produkID).Delete(&model.ProdukAtribut{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&produk).Error
+	})
+	return removedFiles, err
+}
+
 func (r *produkRepository) FindByID(produkID uint) (model.Produk, error) {
 	var produk model.Produk
 	// Preload Kategori and Toko for more data
-	err := r.db.Preload("Category").Preload("Toko").Where("id = ?", produkID).First(&produk).Error
+	err := r.db.Preload("Category").Preload("Toko").Preload("Atribut.Atribut").Where("id = ?", produkID).First(&produk).Error
 	return produk, err
 }
 
@@ -65,15 +227,90 @@ func (r *produkRepository) FindByTokoIDAndProdukID(tokoID, produkID uint) (model
 }
 
 // use filter to query GORM
-func buildFilterQuery(db *gorm.DB, filter FilterInput) *gorm.DB {
+func (r *produkRepository) buildFilterQuery(db *gorm.DB, filter FilterInput) (*gorm.DB, error) {
 	query := db
-	if filter.Search != "" {
+	if filter.SearchIDs != nil {
+		query = query.Where("id IN ?", filter.SearchIDs)
+	} else if filter.Search != "" {
 		query = query.Where("nama_produk LIKE ?", "%"+filter.Search+"%")
 	}
 	if filter.CategoryID != 0 {
-		query = query.Where("id_category = ?", filter.CategoryID)
+		var categories []model.Category
+		if err := r.db.Select("id", "id_parent").Find(&categories).Error; err != nil {
+			return query, err
+		}
+		query = query.Where("id_category IN ?", model.CategoryWithDescendants(categories, filter.CategoryID))
+	}
+	for _, atribut := range filter.Atribut {
+		matched := r.db.Model(&model.ProdukAtribut{}).Select("produk_atribut.id_produk").
+			Joins("JOIN category_atribut ON category_atribut.id = produk_atribut.id_atribut").
+			Where("category_atribut.kode = ?", atribut.Kode)
+		if atribut.Min != nil || atribut.Max != nil {
+			if atribut.Min != nil {
+				matched = matched.Where("produk_atribut.nilai_angka >= ?", *atribut.Min)
+			}
+			if atribut.Max != nil {
+				matched = matched.Where("produk_atribut.nilai_angka <= ?", *atribut.Max)
+			}
+		} else {
+			matched = matched.Where("produk_atribut.nilai = ?", atribut.Nilai)
+		}
+		query = query.Where("id IN (?)", matched)
+	}
+	if filter.MinHarga != nil {
+		query = query.Where(hargaKonsumenExpr+" >= ?", *filter.MinHarga)
+	}
+	if filter.MaxHarga != nil {
+		query = query.Where(hargaKonsumenExpr+" <= ?", *filter.MaxHarga)
+	}
+	if filter.InStock {
+		query = query.Where("stok > 0")
+	}
+	if filter.TokoID != 0 {
+		query = query.Where("id_toko = ?", filter.TokoID)
 	}
-	return query
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	return query, nil
+}
+
+// explicit sort win over search relevance, id keep order stable between page
+func applySort(query *gorm.DB, filter FilterInput) *gorm.DB {
+	switch filter.Sort {
+	case SortPriceAsc:
+		return query.Order(hargaKonsumenExpr + " ASC").Order("id ASC")
+	case SortPriceDesc:
+		return query.Order(hargaKonsumenExpr + " DESC").Order("id DESC")
+	case SortBestSelling:
+		return query.Order("(SELECT COALESCE(SUM(detail_trx.kuantitas), 0) FROM detail_trx " +
+			"JOIN log_produk ON log_produk.id = detail_trx.id_log_produk " +
+			"WHERE log_produk.id_produk = produk.id) DESC").Order("id DESC")
+	case SortRating:
+		// produk without ulasan count as 0, after every rated produk
+		return query.Order("(SELECT COALESCE(AVG(ulasan_produk.rating), 0) FROM ulasan_produk " +
+			"WHERE ulasan_produk.id_produk = produk.id) DESC").Order("id DESC")
+	case SortNewest:
+		return query.Order("created_at_date DESC").Order("id DESC")
+	}
+	// most relevant first when searched with SearchIndex
+	if len(filter.SearchIDs) > 0 {
+		return query.Order(clause.OrderBy{Expression: clause.Expr{SQL: "FIELD(id, ?)", Vars: []interface{}{filter.SearchIDs}, WithoutParentheses: true}})
+	}
+	return query.Order("created_at_date DESC").Order("id DESC")
+}
+
+// public list only show produk of active toko that not in mode libur
+func onlySellingToko(db *gorm.DB, now time.Time) *gorm.DB {
+	sellingTokoIDs := db.Session(&gorm.Session{NewDB: true}).Model(&model.Toko{}).Select("id").
+		Where("status = ?", model.TokoStatusActive).
+		Where("NOT (is_libur = ? AND (libur_sampai IS NULL OR libur_sampai > ?))", true, now)
+	return db.Where("id_toko IN (?)", sellingTokoIDs)
+}
+
+// published produk of selling toko, draft and archived only seen by seller
+func onlyPublicProduk(db *gorm.DB, now time.Time) *gorm.DB {
+	return onlySellingToko(db, now).Where("status = ?", model.ProdukStatusPublished)
 }
 
 func (r *produkRepository) FindAll(pagination utils.PaginationInput, filter FilterInput) ([]model.Produk, int64, error) {
@@ -82,18 +319,22 @@ func (r *produkRepository) FindAll(pagination utils.PaginationInput, filter Filt
 
 	// base query 
 	query := r.db.Model(&model.Produk{})
+	query = onlyPublicProduk(query, time.Now())
 
 	// apply Filter
-	query = buildFilterQuery(query, filter)
+	query, err := r.buildFilterQuery(query, filter)
+	if err != nil {
+		return produks, totalData, err
+	}
 
 	// count total data bfore pagination
-	err := query.Count(&totalData).Error
+	err = query.Count(&totalData).Error
 	if err != nil {
 		return produks, totalData, err
 	}
 
 	// apply Pagination from utils
-	err = query.Scopes(utils.Paginate(pagination.Page, pagination.Limit)).Preload("Category").Preload("Toko").Find(&produks).Error
+	err = applySort(query, filter).Scopes(utils.Paginate(pagination.Page, pagination.Limit)).Preload("Category").Preload("Toko").Find(&produks).Error
 
 	return produks, totalData, err
 }
@@ -104,18 +345,253 @@ func (r *produkRepository) FindAllByTokoID(tokoID uint, pagination utils.Paginat
 
 	query := r.db.Model(&model.Produk{}).Where("id_toko = ?", tokoID)
 
-	query = buildFilterQuery(query, filter)
+	query, err := r.buildFilterQuery(query, filter)
+	if err != nil {
+		return produks, totalData, err
+	}
 
-	err := query.Count(&totalData).Error
+	err = query.Count(&totalData).Error
 	if err != nil {
 		return produks, totalData, err
 	}
 
-	err = query.Scopes(utils.Paginate(pagination.Page, pagination.Limit)).Preload("Category").Find(&produks).Error
+	err = applySort(query, filter).Scopes(utils.Paginate(pagination.Page, pagination.Limit)).Preload("Category").Find(&produks).Error
 
 	return produks, totalData, err
 }
 
+// keyset of produk sort, value is sort key of produk saved in cursor
+type produkCursorKey struct {
+	cursorKey
+	value func(produk model.Produk) string
+	parse func(value string) (interface{}, error)
+}
+
+// same order as applySort. best_selling and rating change on every order or ulasan, can't be used as keyset
+func produkCursorKeyFor(filter FilterInput) (produkCursorKey, error) {
+	newest := produkCursorKey{
+		cursorKey: cursorKey{Scope: "produk:newest", Expr: "created_at_date", Desc: true},
+		value: func(produk model.Produk) string {
+			return produk.CreatedAtDate.Format(time.RFC3339Nano)
+		},
+		parse: func(value string) (interface{}, error) {
+			return time.Parse(time.RFC3339Nano, value)
+		},
+	}
+	harga := produkCursorKey{
+		cursorKey: cursorKey{Expr: hargaKonsumenExpr},
+		value: func(produk model.Produk) string {
+			return strconv.FormatUint(produk.HargaKonsumenAngka, 10)
+		},
+		parse: func(value string) (interface{}, error) {
+			return strconv.ParseUint(value, 10, 64)
+		},
+	}
+
+	switch filter.Sort {
+	case SortPriceAsc:
+		harga.Scope = "produk:price_asc"
+		return harga, nil
+	case SortPriceDesc:
+		harga.Scope = "produk:price_desc"
+		harga.Desc = true
+		return harga, nil
+	case SortBestSelling:
+		return produkCursorKey{}, fmt.Errorf("%w: sort best_selling only support page pagination", utils.ErrCursorNotValid)
+	case SortRating:
+		return produkCursorKey{}, fmt.Errorf("%w: sort rating only support page pagination", utils.ErrCursorNotValid)
+	case SortNewest:
+		return newest, nil
+	}
+	if len(filter.SearchIDs) == 0 {
+		return newest, nil
+	}
+
+	// rank of produk in search hit, start from 1
+	rank := make(map[uint]int, len(filter.SearchIDs))
+	for i, id := range filter.SearchIDs {
+		rank[id] = i + 1
+	}
+	return produkCursorKey{
+		cursorKey: cursorKey{Scope: "produk:relevance", Expr: "FIELD(id, ?)", Vars: []interface{}{filter.SearchIDs}},
+		value: func(produk model.Produk) string {
+			return strconv.Itoa(rank[produk.ID])
+		},
+		parse: func(value string) (interface{}, error) {
+			return strconv.Atoi(value)
+		},
+	}, nil
+}
+
+// harga_konsumen_angka value, same as CAST(... AS UNSIGNED) in mysql: leading digit only, none = 0
+func hargaAngka(harga string) uint64 {
+	harga = strings.TrimSpace(harga)
+	end := 0
+	for end < len(harga) && harga[end] >= '0' && harga[end] <= '9' {
+		end++
+	}
+	value, _ := strconv.ParseUint(harga[:end], 10, 64)
+	return value
+}
+
+func (r *produkRepository) findAfter(query *gorm.DB, after *utils.CursorPosition, limit int, filter FilterInput) ([]model.Produk, *utils.CursorPosition, error) {
+	key, err := produkCursorKeyFor(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkCursorScope(key.cursorKey, after); err != nil {
+		return nil, nil, err
+	}
+	var afterValue interface{}
+	if after != nil {
+		if afterValue, err = key.parse(after.Value); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", utils.ErrCursorNotValid, err)
+		}
+	}
+
+	query, err = r.buildFilterQuery(query, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var produks []model.Produk
+	if err := pageAfter(query, key.cursorKey, after, afterValue, limit).Find(&produks).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(produks) <= limit {
+		return produks, nil, nil
+	}
+	produks = produks[:limit]
+	last := produks[limit-1]
+	return produks, &utils.CursorPosition{Scope: key.Scope, Value: key.value(last), ID: last.ID}, nil
+}
+
+// no COUNT, stable when produk added while paging
+func (r *produkRepository) FindAllAfter(after *utils.CursorPosition, limit int, filter FilterInput) ([]model.Produk, *utils.CursorPosition, error) {
+	query := onlyPublicProduk(r.db.Model(&model.Produk{}), time.Now()).Preload("Category").Preload("Toko")
+	return r.findAfter(query, after, limit, filter)
+}
+
+func (r *produkRepository) FindAllByTokoIDAfter(tokoID uint, after *utils.CursorPosition, limit int, filter FilterInput) ([]model.Produk, *utils.CursorPosition, error) {
+	query := r.db.Model(&model.Produk{}).Where("id_toko = ?", tokoID).Preload("Category")
+	return r.findAfter(query, after, limit, filter)
+}
+
+// toko in libur still included, its produk come back after libur
+func (r *produkRepository) FindAllPublicInBatches(batchSize int, fn func(produks []model.Produk) error) error {
+	var produks []model.Produk
+	return onlyPublicProduk(r.db, time.Now()).Preload("Category").Preload("Toko").
+		FindInBatches(&produks, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(produks)
+		}).Error
+}
+
+func (r *produkRepository) findForIndexInBatches(query *gorm.DB, batchSize int, fn func(produks []model.Produk) error) error {
+	var produks []model.Produk
+	return query.Unscoped().Preload("Category").Preload("Toko").
+		FindInBatches(&produks, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(produks)
+		}).Error
+}
+
+func (r *produkRepository) FindAllForIndexByTokoIDInBatches(tokoID uint, batchSize int, fn func(produks []model.Produk) error) error {
+	return r.findForIndexInBatches(r.db.Where("id_toko = ?", tokoID), batchSize, fn)
+}
+
+func (r *produkRepository) FindAllForIndexByCategoryIDInBatches(categoryID uint, batchSize int, fn func(produks []model.Produk) error) error {
+	return r.findForIndexInBatches(r.db.Where("id_category = ?", categoryID), batchSize, fn)
+}
+
+func (r *produkRepository) FindAllByTokoIDInBatches(tokoID uint, batchSize int, fn func(produks []model.Produk) error) error {
+	var produks []model.Produk
+	return r.db.Preload("Category").Preload("Atribut.Atribut").Where("id_toko = ?", tokoID).
+		FindInBatches(&produks, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(produks)
+		}).Error
+}
+
+func (r *produkRepository) FindAtributKodeByTokoID(tokoID uint) ([]string, error) {
+	var kodes []string
+	err := r.db.Model(&model.ProdukAtribut{}).
+		Joins("JOIN category_atribut ON category_atribut.id = produk_atribut.id_atribut").
+		Joins("JOIN produk ON produk.id = produk_atribut.id_produk").
+		Where("produk.id_toko = ? AND produk.deleted_at IS NULL", tokoID).
+		Distinct().Order("category_atribut.kode").Pluck("category_atribut.kode", &kodes).Error
+	return kodes, err
+}
+
+// public produk matching filter, base of facet count
+func (r *produkRepository) facetQuery(filter FilterInput) (*gorm.DB, error) {
+	query := onlyPublicProduk(r.db.Model(&model.Produk{}), time.Now())
+	return r.buildFilterQuery(query, filter)
+}
+
+// count per kategori of produk, most produk first
+func (r *produkRepository) CountByCategory(filter FilterInput) ([]CategoryCount, error) {
+	query, err := r.facetQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []CategoryCount
+	err = query.Select("id_category, COUNT(*) AS jumlah").Group("id_category").
+		Order("jumlah DESC").Order("id_category").Scan(&counts).Error
+	if err != nil || len(counts) == 0 {
+		return counts, err
+	}
+
+	categoryIDs := make([]uint, 0, len(counts))
+	for _, count := range counts {
+		categoryIDs = append(categoryIDs, count.IDCategory)
+	}
+	var categories []model.Category
+	if err := r.db.Select("id", "nama_category").Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	names := map[uint]string{}
+	for _, category := range categories {
+		names[category.ID] = category.NamaCategory
+	}
+	for i := range counts {
+		counts[i].NamaCategory = names[counts[i].IDCategory]
+	}
+	return counts, nil
+}
+
+// count per harga range, bounds ascending. result has len(bounds)+1 item:
+// below bounds[0], [bounds[0], bounds[1]), ..., bounds[len-1] and above
+func (r *produkRepository) CountByHarga(filter FilterInput, bounds []int64) ([]int64, error) {
+	query, err := r.facetQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketExpr := "CASE"
+	vars := make([]interface{}, 0, len(bounds))
+	for i, bound := range bounds {
+		bucketExpr += fmt.Sprintf(" WHEN %s < ? THEN %d", hargaKonsumenExpr, i)
+		vars = append(vars, bound)
+	}
+	bucketExpr += fmt.Sprintf(" ELSE %d END", len(bounds))
+
+	var rows []struct {
+		Bucket int
+		Jumlah int64
+	}
+	err = query.Select(bucketExpr+" AS bucket, COUNT(*) AS jumlah", vars...).Group("bucket").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]int64, len(bounds)+1)
+	for _, row := range rows {
+		if row.Bucket >= 0 && row.Bucket < len(counts) {
+			counts[row.Bucket] = row.Jumlah
+		}
+	}
+	return counts, nil
+}
+
 // for get and lock db when update stock in transaksi
 func (r *produkRepository) FindByIDWithLock(tx *gorm.DB, produkID uint) (model.Produk, error) {
 	var produk model.Produk
@@ -127,6 +603,29 @@ func (r *produkRepository) FindByIDWithLock(tx *gorm.DB, produkID uint) (model.P
 // update data produk use tx
 func (r *produkRepository) UpdateWithTx(tx *gorm.DB, produk model.Produk) (model.Produk, error) {
 	// use tx
+	produk.HargaKonsumenAngka = hargaAngka(produk.HargaKonsumen)
 	err := tx.Save(&produk).Error
 	return produk, err
-}
\ No newline at end of file
+}
+func (r *produkRepository) BackfillHargaAngka() (int64, error) {
+	var total int64
+	var produks []model.Produk
+	err := r.db.Unscoped().Select("id", "harga_konsumen", "harga_konsumen_angka").
+		Where("harga_konsumen_angka = ? AND harga_konsumen <> ?", 0, "").
+		FindInBatches(&produks, 500, func(tx *gorm.DB, batch int) error {
+			for _, produk := range produks {
+				harga := hargaAngka(produk.HargaKonsumen)
+				if harga == 0 {
+					continue
+				}
+				err := r.db.Unscoped().Model(&model.Produk{}).Where("id = ?", produk.ID).
+					UpdateColumn("harga_konsumen_angka", harga).Error
+				if err != nil {
+					return err
+				}
+				total++
+			}
+			return nil
+		}).Error
+	return total, err
+}
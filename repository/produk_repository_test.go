@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"rakamin-evermos/utils"
+)
+
+func TestProdukCursorRejectOrderThatChange(t *testing.T) {
+	for _, sort := range []string{SortBestSelling, SortRating} {
+		if _, err := produkCursorKeyFor(FilterInput{Sort: sort}); !errors.Is(err, utils.ErrCursorNotValid) {
+			t.Fatalf("expected ErrCursorNotValid for sort %s, got %v", sort, err)
+		}
+	}
+	if _, err := produkCursorKeyFor(FilterInput{Sort: SortPriceAsc}); err != nil {
+		t.Fatalf("expected cursor for price_asc, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// insert in batch, full dataset has ~80k kelurahan
+const regionBatchSize = 1000
+
+type RegionRepository interface {
+	CountProvinsi() (int64, error)
+	CountKota() (int64, error)
+	CountKecamatan() (int64, error)
+	CountKelurahan() (int64, error)
+
+	// insert or update by id (used by seeder)
+	SaveProvinsi(provinsi []model.Provinsi) error
+	SaveKota(kota []model.Kota) error
+	SaveKecamatan(kecamatan []model.Kecamatan) error
+	SaveKelurahan(kelurahan []model.Kelurahan) error
+
+	FindAllProvinsi(search string) ([]model.Provinsi, error)
+	FindKotaByProvinsiID(provinsiID uint, search string) ([]model.Kota, error)
+	FindKecamatanByKotaID(kotaID uint, search string) ([]model.Kecamatan, error)
+	FindKelurahanByKecamatanID(kecamatanID uint, search string) ([]model.Kelurahan, error)
+	FindKelurahanByKodePos(kodePos string) ([]model.Kelurahan, error)
+
+	FindProvinsiByID(provinsiID uint) (model.Provinsi, error)
+	FindKotaByID(kotaID uint) (model.Kota, error)
+	FindKecamatanByID(kecamatanID uint) (model.Kecamatan, error)
+	FindKelurahanByID(kelurahanID uint64) (model.Kelurahan, error)
+}
+
+type regionRepository struct {
+	db *gorm.DB
+}
+
+func NewRegionRepository(db *gorm.DB) RegionRepository {
+	return &regionRepository{db}
+}
+
+func (r *regionRepository) count(table interface{}) (int64, error) {
+	var total int64
+	err := r.db.Model(table).Count(&total).Error
+	return total, err
+}
+
+func (r *regionRepository) CountProvinsi() (int64, error)  { return r.count(&model.Provinsi{}) }
+func (r *regionRepository) CountKota() (int64, error)      { return r.count(&model.Kota{}) }
+func (r *regionRepository) CountKecamatan() (int64, error) { return r.count(&model.Kecamatan{}) }
+func (r *regionRepository) CountKelurahan() (int64, error) { return r.count(&model.Kelurahan{}) }
+
+func (r *regionRepository) upsert(rows interface{}) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(rows, regionBatchSize).Error
+}
+
+func (r *regionRepository) SaveProvinsi(provinsi []model.Provinsi) error {
+	if len(provinsi) == 0 {
+		return nil
+	}
+	return r.upsert(&provinsi)
+}
+
+func (r *regionRepository) SaveKota(kota []model.Kota) error {
+	if len(kota) == 0 {
+		return nil
+	}
+	return r.upsert(&kota)
+}
+
+func (r *regionRepository) SaveKecamatan(kecamatan []model.Kecamatan) error {
+	if len(kecamatan) == 0 {
+		return nil
+	}
+	return r.upsert(&kecamatan)
+}
+
+func (r *regionRepository) SaveKelurahan(kelurahan []model.Kelurahan) error {
+	if len(kelurahan) == 0 {
+		return nil
+	}
+	return r.upsert(&kelurahan)
+}
+
+func searchByNama(db *gorm.DB, search string) *gorm.DB {
+	if search == "" {
+		return db
+	}
+	return db.Where("nama LIKE ?", "%"+search+"%")
+}
+
+func (r *regionRepository) FindAllProvinsi(search string) ([]model.Provinsi, error) {
+	var provinsi []model.Provinsi
+	err := searchByNama(r.db, search).Order("id").Find(&provinsi).Error
+	return provinsi, err
+}
+
+func (r *regionRepository) FindKotaByProvinsiID(provinsiID uint, search string) ([]model.Kota, error) {
+	var kota []model.Kota
+	err := searchByNama(r.db, search).Where("id_provinsi = ?", provinsiID).Order("id").Find(&kota).Error
+	return kota, err
+}
+
+func (r *regionRepository) FindKecamatanByKotaID(kotaID uint, search string) ([]model.Kecamatan, error) {
+	var kecamatan []model.Kecamatan
+	err := searchByNama(r.db, search).Where("id_kota = ?", kotaID).Order("id").Find(&kecamatan).Error
+	return kecamatan, err
+}
+
+func (r *regionRepository) FindKelurahanByKecamatanID(kecamatanID uint, search string) ([]model.Kelurahan, error) {
+	var kelurahan []model.Kelurahan
+	err := searchByNama(r.db, search).Where("id_kecamatan = ?", kecamatanID).Order("id").Find(&kelurahan).Error
+	return kelurahan, err
+}
+
+func (r *regionRepository) FindKelurahanByKodePos(kodePos string) ([]model.Kelurahan, error) {
+	var kelurahan []model.Kelurahan
+	err := r.db.Where("kode_pos = ?", kodePos).Order("id").Find(&kelurahan).Error
+	return kelurahan, err
+}
+
+func (r *regionRepository) FindProvinsiByID(provinsiID uint) (model.Provinsi, error) {
+	var provinsi model.Provinsi
+	err := r.db.Where("id = ?", provinsiID).First(&provinsi).Error
+	return provinsi, err
+}
+
+func (r *regionRepository) FindKotaByID(kotaID uint) (model.Kota, error) {
+	var kota model.Kota
+	err := r.db.Where("id = ?", kotaID).First(&kota).Error
+	return kota, err
+}
+
+func (r *regionRepository) FindKecamatanByID(kecamatanID uint) (model.Kecamatan, error) {
+	var kecamatan model.Kecamatan
+	err := r.db.Where("id = ?", kecamatanID).First(&kecamatan).Error
+	return kecamatan, err
+}
+
+func (r *regionRepository) FindKelurahanByID(kelurahanID uint64) (model.Kelurahan, error) {
+	var kelurahan model.Kelurahan
+	err := r.db.Where("id = ?", kelurahanID).First(&kelurahan).Error
+	return kelurahan, err
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	Save(role model.Role) (model.Role, error)
+	FindAll() ([]model.Role, error)
+	FindByID(roleID uint) (model.Role, error)
+	FindByName(name string) (model.Role, error)
+	FindByNames(names []string) ([]model.Role, error)
+
+	SavePermissions(permissions []model.Permission) error
+	FindAllPermissions() ([]model.Permission, error)
+	FindPermissionsByCodes(codes []string) ([]model.Permission, error)
+
+	FindRolesByUserID(userID uint) ([]model.Role, error)
+	ReplaceUserRoles(userID uint, roles []model.Role) error
+	FindPermissionCodesByUserID(userID uint) ([]string, error)
+	AssignRoleToAdminFlagUsers(roleID uint) error
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db}
+}
+
+// create or update role, permission list is replaced
+func (r *roleRepository) Save(role model.Role) (model.Role, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		permissions := role.Permissions
+		if err := tx.Omit("Permissions").Save(&role).Error; err != nil {
+			return err
+		}
+		return tx.Model(&role).Association("Permissions").Replace(permissions)
+	})
+	return role, err
+}
+
+func (r *roleRepository) FindAll() ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.Preload("Permissions").Order("id").Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) FindByID(roleID uint) (model.Role, error) {
+	var role model.Role
+	err := r.db.Preload("Permissions").Where("id = ?", roleID).First(&role).Error
+	return role, err
+}
+
+func (r *roleRepository) FindByName(name string) (model.Role, error) {
+	var role model.Role
+	err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error
+	return role, err
+}
+
+func (r *roleRepository) FindByNames(names []string) ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.Where("name IN ?", names).Find(&roles).Error
+	return roles, err
+}
+
+// insert permission if code not exist yet (used by seeder)
+func (r *roleRepository) SavePermissions(permissions []model.Permission) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&permissions).Error
+}
+
+func (r *roleRepository) FindAllPermissions() ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.Order("code").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *roleRepository) FindPermissionsByCodes(codes []string) ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.Where("code IN ?", codes).Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *roleRepository) FindRolesByUserID(userID uint) ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.Preload("Permissions").
+		Joins("JOIN user_role ON user_role.id_role = role.id").
+		Where("user_role.id_user = ?", userID).
+		Find(&roles).Error
+	return roles, err
+}
+
+// replace all role of user, is_admin flag synced with admin role in same transaction
+func (r *roleRepository) ReplaceUserRoles(userID uint, roles []model.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		user := model.User{ID: userID}
+		if err := tx.Model(&user).Association("Roles").Replace(roles); err != nil {
+			return err
+		}
+
+		isAdmin := false
+		for _, role := range roles {
+			if role.Name == model.RoleAdmin {
+				isAdmin = true
+			}
+		}
+		return tx.Model(&model.User{}).Where("id = ?", userID).Update("is_admin", isAdmin).Error
+	})
+}
+
+func (r *roleRepository) FindPermissionCodesByUserID(userID uint) ([]string, error) {
+	var codes []string
+	err := r.db.Model(&model.Permission{}).
+		Distinct("permission.code").
+		Joins("JOIN role_permission ON role_permission.id_permission = permission.id").
+		Joins("JOIN user_role ON user_role.id_role = role_permission.id_role").
+		Where("user_role.id_user = ?", userID).
+		Pluck("permission.code", &codes).Error
+	return codes, err
+}
+
+// old admin (is_admin = true) without any role get admin role
+func (r *roleRepository) AssignRoleToAdminFlagUsers(roleID uint) error {
+	return r.db.Exec(
+		"INSERT INTO user_role (id_user, id_role) SELECT id, ? FROM `user` WHERE is_admin = ? AND id NOT IN (SELECT id_user FROM user_role)",
+		roleID, true,
+	).Error
+}
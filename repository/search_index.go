@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"strings"
+
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+)
+
+// text of produk that can be searched
+type SearchDocument struct {
+	ProdukID     uint
+	NamaProduk   string
+	Deskripsi    string
+	IDCategory   uint
+	NamaCategory string
+	IDToko       uint
+	NamaToko     string
+}
+
+type SearchHit struct {
+	ProdukID uint
+	Score    float64
+}
+
+// SearchIndex find produk by text, hit ordered by relevance (best first).
+// Index and Remove called by produk usecase every time produk change
+type SearchIndex interface {
+	Index(doc SearchDocument) error
+	Remove(produkID uint) error
+	Search(query string, limit int) ([]SearchHit, error)
+}
+
+func NewSearchDocument(produk model.Produk) SearchDocument {
+	doc := SearchDocument{
+		ProdukID:     produk.ID,
+		NamaProduk:   produk.NamaProduk,
+		Deskripsi:    produk.Deskripsi,
+		IDCategory:   produk.IDCategory,
+		NamaCategory: produk.Category.NamaCategory,
+		IDToko:       produk.IDToko,
+	}
+	if produk.Toko != nil {
+		doc.NamaToko = produk.Toko.NamaToko
+	}
+	return doc
+}
+
+// mysqlSearchIndex use FULLTEXT index on produk, category and toko table,
+// so it always read current data and Index / Remove has nothing to do
+type mysqlSearchIndex struct {
+	db *gorm.DB
+}
+
+func NewMySQLSearchIndex(db *gorm.DB) SearchIndex {
+	return &mysqlSearchIndex{db}
+}
+
+func (s *mysqlSearchIndex) Index(doc SearchDocument) error {
+	return nil
+}
+
+func (s *mysqlSearchIndex) Remove(produkID uint) error {
+	return nil
+}
+
+// nama produk weighted more than deskripsi, kategori and nama toko
+func (s *mysqlSearchIndex) Search(query string, limit int) ([]SearchHit, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []SearchHit{}, nil
+	}
+
+	const score = `3 * MATCH(produk.nama_produk) AGAINST (@q IN NATURAL LANGUAGE MODE)
+		+ MATCH(produk.nama_produk, produk.deskripsi) AGAINST (@q IN NATURAL LANGUAGE MODE)
+		+ 2 * MATCH(category.nama_category) AGAINST (@q IN NATURAL LANGUAGE MODE)
+		+ 1.5 * MATCH(toko.nama_toko) AGAINST (@q IN NATURAL LANGUAGE MODE)`
+
+	var rows []struct {
+		ID    uint
+		Score float64
+	}
+	err := s.db.Raw(`SELECT produk.id AS id, `+score+` AS score
+		FROM produk
+		LEFT JOIN category ON category.id = produk.id_category
+		LEFT JOIN toko ON toko.id = produk.id_toko
+		WHERE produk.deleted_at IS NULL AND produk.status = @status
+			AND (MATCH(produk.nama_produk, produk.deskripsi) AGAINST (@q IN NATURAL LANGUAGE MODE)
+				OR MATCH(category.nama_category) AGAINST (@q IN NATURAL LANGUAGE MODE)
+				OR MATCH(toko.nama_toko) AGAINST (@q IN NATURAL LANGUAGE MODE))
+		ORDER BY score DESC, produk.id
+		LIMIT @limit`, map[string]interface{}{"q": query, "status": model.ProdukStatusPublished, "limit": limit}).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]SearchHit, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, SearchHit{ProdukID: row.ID, Score: row.Score})
+	}
+	return hits, nil
+}
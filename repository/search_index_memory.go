@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// weight of every field, same order as mysql search
+var searchFieldWeights = map[string]float64{
+	"nama_produk":   4, // 3 from nama only + 1 from nama and deskripsi in mysql
+	"deskripsi":     1,
+	"nama_category": 2,
+	"nama_toko":     1.5,
+}
+
+// memorySearchIndex is inverted index kept in memory, for test and local run without mysql.
+// data not saved, must be filled again with Index after restart
+type memorySearchIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[uint]float64 // term -> produk -> weighted term frequency
+	terms    map[uint][]string           // produk -> term, for remove
+}
+
+func NewMemorySearchIndex() SearchIndex {
+	return &memorySearchIndex{
+		postings: map[string]map[uint]float64{},
+		terms:    map[uint][]string{},
+	}
+}
+
+// lowercase word of letter and digit
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (s *memorySearchIndex) Index(doc SearchDocument) error {
+	fields := map[string]string{
+		"nama_produk":   doc.NamaProduk,
+		"deskripsi":     doc.Deskripsi,
+		"nama_category": doc.NamaCategory,
+		"nama_toko":     doc.NamaToko,
+	}
+	weights := map[string]float64{}
+	for field, text := range fields {
+		for _, term := range tokenize(text) {
+			weights[term] += searchFieldWeights[field]
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(doc.ProdukID)
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		if s.postings[term] == nil {
+			s.postings[term] = map[uint]float64{}
+		}
+		s.postings[term][doc.ProdukID] = weight
+		terms = append(terms, term)
+	}
+	s.terms[doc.ProdukID] = terms
+	return nil
+}
+
+func (s *memorySearchIndex) Remove(produkID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(produkID)
+	return nil
+}
+
+func (s *memorySearchIndex) removeLocked(produkID uint) {
+	for _, term := range s.terms[produkID] {
+		delete(s.postings[term], produkID)
+		if len(s.postings[term]) == 0 {
+			delete(s.postings, term)
+		}
+	}
+	delete(s.terms, produkID)
+}
+
+// produk match any term, score = sum of weighted tf * idf, so rare term count more
+func (s *memorySearchIndex) Search(query string, limit int) ([]SearchHit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	total := float64(len(s.terms))
+	scores := map[uint]float64{}
+	seen := map[string]bool{}
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		postings := s.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		idf := math.Log(1 + total/float64(len(postings)))
+		for produkID, weight := range postings {
+			scores[produkID] += weight * idf
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for produkID, score := range scores {
+		hits = append(hits, SearchHit{ProdukID: produkID, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ProdukID < hits[j].ProdukID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func hitIDs(hits []SearchHit) []uint {
+	ids := []uint{}
+	for _, hit := range hits {
+		ids = append(ids, hit.ProdukID)
+	}
+	return ids
+}
+
+func TestMemorySearchIndexRanking(t *testing.T) {
+	index := NewMemorySearchIndex()
+	docs := []SearchDocument{
+		{ProdukID: 1, NamaProduk: "Kaos Polos", Deskripsi: "cocok dipakai dengan laptop bag", NamaCategory: "Fashion", NamaToko: "Toko Budi"},
+		{ProdukID: 2, NamaProduk: "Laptop Asus 14", Deskripsi: "RAM 16GB", NamaCategory: "Laptop", NamaToko: "Elektro Jaya"},
+		{ProdukID: 3, NamaProduk: "Mouse Wireless", Deskripsi: "untuk laptop dan PC", NamaCategory: "Aksesoris", NamaToko: "Elektro Jaya"},
+		{ProdukID: 4, NamaProduk: "Sepatu Lari", Deskripsi: "ringan", NamaCategory: "Olahraga", NamaToko: "Toko Budi"},
+	}
+	for _, doc := range docs {
+		if err := index.Index(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// nama and kategori match rank above deskripsi only match
+	hits, _ := index.Search("LAPTOP", 10)
+	if ids := hitIDs(hits); !reflect.DeepEqual(ids, []uint{2, 1, 3}) {
+		t.Errorf("laptop: got %v", ids)
+	}
+
+	// nama toko searchable, limit respected
+	hits, _ = index.Search("budi", 1)
+	if ids := hitIDs(hits); len(ids) != 1 || (ids[0] != 1 && ids[0] != 4) {
+		t.Errorf("budi: got %v", ids)
+	}
+
+	// more matched term rank higher
+	hits, _ = index.Search("mouse laptop", 10)
+	if ids := hitIDs(hits); ids[0] != 3 {
+		t.Errorf("mouse laptop: got %v", ids)
+	}
+
+	if hits, _ := index.Search("  ", 10); len(hits) != 0 {
+		t.Errorf("empty query must not match, got %v", hitIDs(hits))
+	}
+}
+
+func TestMemorySearchIndexUpdateAndRemove(t *testing.T) {
+	index := NewMemorySearchIndex()
+	index.Index(SearchDocument{ProdukID: 1, NamaProduk: "Kemeja Batik"})
+	index.Index(SearchDocument{ProdukID: 1, NamaProduk: "Kemeja Flanel"})
+
+	if hits, _ := index.Search("batik", 10); len(hits) != 0 {
+		t.Errorf("old term must be removed on reindex, got %v", hitIDs(hits))
+	}
+	if hits, _ := index.Search("flanel", 10); !reflect.DeepEqual(hitIDs(hits), []uint{1}) {
+		t.Errorf("flanel: got %v", hitIDs(hits))
+	}
+
+	index.Remove(1)
+	if hits, _ := index.Search("kemeja", 10); len(hits) != 0 {
+		t.Errorf("removed produk still found: %v", hitIDs(hits))
+	}
+}
@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tipe of suggestion
+const (
+	SuggestProduk   = "produk"
+	SuggestCategory = "kategori"
+	SuggestToko     = "toko"
+)
+
+// Koreksi true when matched only after typo correction
+type Suggestion struct {
+	Tipe    string
+	ID      uint
+	Teks    string
+	Jumlah  int // produk with this kategori / toko, 1 for produk
+	Koreksi bool
+}
+
+// SuggestIndex complete text typed by user from nama produk, kategori and toko.
+// Index and Remove called by produk usecase every time produk change
+type SuggestIndex interface {
+	Index(doc SearchDocument) error
+	Remove(produkID uint) error
+	Suggest(query string, limit int) ([]Suggestion, error)
+}
+
+// kategori and toko stay while there is produk using it
+type suggestEntry struct {
+	tipe   string
+	id     uint
+	teks   string
+	terms  []string
+	produk map[uint]bool
+}
+
+// memorySuggestIndex keep sorted term list for prefix lookup and scan it for typo.
+// data not saved, filled again on startup
+type memorySuggestIndex struct {
+	mu       sync.RWMutex
+	entries  map[string]*suggestEntry   // tipe:id -> entry
+	postings map[string]map[string]bool // term -> entry key
+	vocab    []string                   // all term, sorted
+	byProduk map[uint][]string          // produk -> entry key, for remove
+}
+
+func NewMemorySuggestIndex() SuggestIndex {
+	return &memorySuggestIndex{
+		entries:  map[string]*suggestEntry{},
+		postings: map[string]map[string]bool{},
+		byProduk: map[uint][]string{},
+	}
+}
+
+func suggestKey(tipe string, id uint) string {
+	return tipe + ":" + strconv.FormatUint(uint64(id), 10)
+}
+
+// produk without nama toko / kategori only suggested by nama produk
+func (s *memorySuggestIndex) Index(doc SearchDocument) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(doc.ProdukID)
+
+	s.addLocked(doc.ProdukID, SuggestProduk, doc.ProdukID, doc.NamaProduk)
+	if doc.IDCategory != 0 && doc.NamaCategory != "" {
+		s.addLocked(doc.ProdukID, SuggestCategory, doc.IDCategory, doc.NamaCategory)
+	}
+	if doc.IDToko != 0 && doc.NamaToko != "" {
+		s.addLocked(doc.ProdukID, SuggestToko, doc.IDToko, doc.NamaToko)
+	}
+	return nil
+}
+
+func (s *memorySuggestIndex) Remove(produkID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(produkID)
+	return nil
+}
+
+// last indexed produk decide teks, so renamed kategori / toko follow new name
+func (s *memorySuggestIndex) addLocked(produkID uint, tipe string, id uint, teks string) {
+	key := suggestKey(tipe, id)
+	entry := s.entries[key]
+	if entry == nil {
+		entry = &suggestEntry{tipe: tipe, id: id, produk: map[uint]bool{}}
+		s.entries[key] = entry
+	}
+	if entry.teks != teks {
+		s.unlinkTermsLocked(key, entry.terms)
+		entry.teks = teks
+		entry.terms = tokenize(teks)
+		for _, term := range entry.terms {
+			if s.postings[term] == nil {
+				s.postings[term] = map[string]bool{}
+				s.insertVocabLocked(term)
+			}
+			s.postings[term][key] = true
+		}
+	}
+	entry.produk[produkID] = true
+	s.byProduk[produkID] = append(s.byProduk[produkID], key)
+}
+
+func (s *memorySuggestIndex) removeLocked(produkID uint) {
+	for _, key := range s.byProduk[produkID] {
+		entry := s.entries[key]
+		if entry == nil {
+			continue
+		}
+		delete(entry.produk, produkID)
+		if len(entry.produk) == 0 {
+			s.unlinkTermsLocked(key, entry.terms)
+			delete(s.entries, key)
+		}
+	}
+	delete(s.byProduk, produkID)
+}
+
+func (s *memorySuggestIndex) unlinkTermsLocked(key string, terms []string) {
+	for _, term := range terms {
+		delete(s.postings[term], key)
+		if len(s.postings[term]) == 0 {
+			delete(s.postings, term)
+			s.removeVocabLocked(term)
+		}
+	}
+}
+
+func (s *memorySuggestIndex) insertVocabLocked(term string) {
+	i := sort.SearchStrings(s.vocab, term)
+	s.vocab = append(s.vocab, "")
+	copy(s.vocab[i+1:], s.vocab[i:])
+	s.vocab[i] = term
+}
+
+func (s *memorySuggestIndex) removeVocabLocked(term string) {
+	i := sort.SearchStrings(s.vocab, term)
+	if i < len(s.vocab) && s.vocab[i] == term {
+		s.vocab = append(s.vocab[:i], s.vocab[i+1:]...)
+	}
+}
+
+// typo allowed per word, short word must be typed correctly
+func maxTypo(term string) int {
+	switch n := len([]rune(term)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// edit distance between query and closest prefix of term, -1 when more than max
+func prefixDistance(query, term string, maxDist int) int {
+	q, t := []rune(query), []rune(term)
+	prev := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(q); i++ {
+		cur := make([]int, len(t)+1)
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if q[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > maxDist {
+			return -1
+		}
+		prev = cur
+	}
+	best := prev[0]
+	for _, d := range prev {
+		best = min(best, d)
+	}
+	if best > maxDist {
+		return -1
+	}
+	return best
+}
+
+// term starting with word, or close to it when no term start with it
+func (s *memorySuggestIndex) matchTermsLocked(word string) (map[string]bool, bool) {
+	matched := map[string]bool{}
+	for i := sort.SearchStrings(s.vocab, word); i < len(s.vocab) && strings.HasPrefix(s.vocab[i], word); i++ {
+		matched[s.vocab[i]] = true
+	}
+	if len(matched) > 0 {
+		return matched, false
+	}
+
+	maxDist := maxTypo(word)
+	if maxDist == 0 {
+		return matched, false
+	}
+	for _, term := range s.vocab {
+		if prefixDistance(word, term, maxDist) >= 0 {
+			matched[term] = true
+		}
+	}
+	return matched, true
+}
+
+// every word of query must match a word of suggestion, typed word can be prefix.
+// most produk first, then shorter teks
+func (s *memorySuggestIndex) Suggest(query string, limit int) ([]Suggestion, error) {
+	words := tokenize(query)
+	if len(words) == 0 {
+		return []Suggestion{}, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var candidates map[string]bool
+	koreksi := false
+	for _, word := range words {
+		terms, fuzzy := s.matchTermsLocked(word)
+		koreksi = koreksi || fuzzy
+		keys := map[string]bool{}
+		for term := range terms {
+			for key := range s.postings[term] {
+				if candidates == nil || candidates[key] {
+					keys[key] = true
+				}
+			}
+		}
+		candidates = keys
+		if len(candidates) == 0 {
+			return []Suggestion{}, nil
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(candidates))
+	seen := map[string]bool{}
+	for key := range candidates {
+		entry := s.entries[key]
+		suggestions = append(suggestions, Suggestion{
+			Tipe:    entry.tipe,
+			ID:      entry.id,
+			Teks:    entry.teks,
+			Jumlah:  len(entry.produk),
+			Koreksi: koreksi,
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Jumlah != b.Jumlah {
+			return a.Jumlah > b.Jumlah
+		}
+		if len(a.Teks) != len(b.Teks) {
+			return len(a.Teks) < len(b.Teks)
+		}
+		if a.Teks != b.Teks {
+			return a.Teks < b.Teks
+		}
+		if a.Tipe != b.Tipe {
+			return a.Tipe < b.Tipe
+		}
+		return a.ID < b.ID
+	})
+
+	// produk with same nama only suggested once
+	result := make([]Suggestion, 0, limit)
+	for _, suggestion := range suggestions {
+		dedupKey := suggestion.Tipe + ":" + strings.ToLower(suggestion.Teks)
+		if seen[dedupKey] {
+			continue
+		}
+		seen[dedupKey] = true
+		result = append(result, suggestion)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
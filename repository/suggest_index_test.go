@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func suggestionTeks(suggestions []Suggestion) []string {
+	teks := []string{}
+	for _, suggestion := range suggestions {
+		teks = append(teks, suggestion.Tipe+":"+suggestion.Teks)
+	}
+	return teks
+}
+
+func TestMemorySuggestIndex(t *testing.T) {
+	index := NewMemorySuggestIndex()
+	docs := []SearchDocument{
+		{ProdukID: 1, NamaProduk: "Laptop Asus 14", IDCategory: 10, NamaCategory: "Laptop", IDToko: 5, NamaToko: "Elektro Jaya"},
+		{ProdukID: 2, NamaProduk: "Laptop Lenovo", IDCategory: 10, NamaCategory: "Laptop", IDToko: 5, NamaToko: "Elektro Jaya"},
+		{ProdukID: 3, NamaProduk: "Lampu Meja", IDCategory: 11, NamaCategory: "Perabot", IDToko: 6, NamaToko: "Toko Budi"},
+		{ProdukID: 4, NamaProduk: "laptop asus 14", IDCategory: 10, NamaCategory: "Laptop", IDToko: 6, NamaToko: "Toko Budi"},
+	}
+	for _, doc := range docs {
+		if err := index.Index(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// prefix, kategori with most produk first, same nama produk once
+	got, _ := index.Suggest("lap", 10)
+	expected := []string{"kategori:Laptop", "produk:Laptop Lenovo", "produk:Laptop Asus 14"}
+	if teks := suggestionTeks(got); !reflect.DeepEqual(teks, expected) {
+		t.Errorf("lap: got %v", teks)
+	}
+	if got[0].Koreksi {
+		t.Error("prefix match must not be koreksi")
+	}
+
+	// every word must match
+	got, _ = index.Suggest("laptop len", 10)
+	if teks := suggestionTeks(got); !reflect.DeepEqual(teks, []string{"produk:Laptop Lenovo"}) {
+		t.Errorf("laptop len: got %v", teks)
+	}
+
+	// typo corrected, short word not
+	got, _ = index.Suggest("elektor", 10)
+	if teks := suggestionTeks(got); !reflect.DeepEqual(teks, []string{"toko:Elektro Jaya"}) || !got[0].Koreksi {
+		t.Errorf("elektor: got %+v", got)
+	}
+	if got, _ = index.Suggest("lpm", 10); len(got) != 0 {
+		t.Errorf("lpm: expected nothing, got %v", suggestionTeks(got))
+	}
+
+	// kategori and toko gone with last produk
+	index.Remove(3)
+	index.Remove(4)
+	if got, _ = index.Suggest("budi", 10); len(got) != 0 {
+		t.Errorf("budi after remove: got %v", suggestionTeks(got))
+	}
+	if got, _ = index.Suggest("lampu", 10); len(got) != 0 {
+		t.Errorf("lampu after remove: got %v", suggestionTeks(got))
+	}
+
+	// rename follow last indexed produk
+	index.Index(SearchDocument{ProdukID: 2, NamaProduk: "Laptop Lenovo", IDCategory: 10, NamaCategory: "Notebook", IDToko: 5, NamaToko: "Elektro Jaya"})
+	got, _ = index.Suggest("note", 10)
+	if teks := suggestionTeks(got); !reflect.DeepEqual(teks, []string{"kategori:Notebook"}) {
+		t.Errorf("note: got %v", teks)
+	}
+}
+
+func TestPrefixDistance(t *testing.T) {
+	cases := []struct {
+		query, term string
+		expected    int
+	}{
+		{"lapt", "laptop", 0},
+		{"lpatop", "laptop", 2},
+		{"laptp", "laptop", 1},
+		{"sepatu", "laptop", -1},
+	}
+	for _, c := range cases {
+		if got := prefixDistance(c.query, c.term, 2); got != c.expected {
+			t.Errorf("prefixDistance(%q, %q) = %d, expected %d", c.query, c.term, got, c.expected)
+		}
+	}
+}
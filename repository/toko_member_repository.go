@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+)
+
+// invitation already accepted or revoked by other request
+var ErrInvitationNotPending = errors.New("invitation already used or revoked")
+
+type TokoMemberRepository interface {
+	FindMember(tokoID, userID uint) (model.TokoMember, error)
+	FindAllByUserID(userID uint) ([]model.TokoMember, error)
+	FindAllByTokoID(tokoID uint) ([]model.TokoMember, error)
+	Update(member model.TokoMember) (model.TokoMember, error)
+	Delete(member model.TokoMember) error
+	EnsureOwnerMemberships() error
+
+	SaveInvitation(invitation model.TokoInvitation) (model.TokoInvitation, error)
+	FindInvitationsByTokoID(tokoID uint) ([]model.TokoInvitation, error)
+	FindInvitationByID(tokoID, invitationID uint) (model.TokoInvitation, error)
+	FindInvitationByTokenHash(tokenHash string) (model.TokoInvitation, error)
+	UpdateInvitation(invitation model.TokoInvitation) (model.TokoInvitation, error)
+	AcceptInvitation(invitation model.TokoInvitation, member model.TokoMember, now time.Time) (model.TokoMember, error)
+}
+
+type tokoMemberRepository struct {
+	db *gorm.DB
+}
+
+func NewTokoMemberRepository(db *gorm.DB) TokoMemberRepository {
+	return &tokoMemberRepository{db}
+}
+
+func (r *tokoMemberRepository) FindMember(tokoID, userID uint) (model.TokoMember, error) {
+	var member model.TokoMember
+	err := r.db.Preload("Toko").Where("id_toko = ? AND id_user = ?", tokoID, userID).First(&member).Error
+	return member, err
+}
+
+// all toko where user is staff, owner first
+func (r *tokoMemberRepository) FindAllByUserID(userID uint) ([]model.TokoMember, error) {
+	var members []model.TokoMember
+	err := r.db.Preload("Toko").Where("id_user = ?", userID).
+		Order("role = '" + model.TokoRoleOwner + "' DESC").Order("id").
+		Find(&members).Error
+	return members, err
+}
+
+func (r *tokoMemberRepository) FindAllByTokoID(tokoID uint) ([]model.TokoMember, error) {
+	var members []model.TokoMember
+	err := r.db.Preload("User").Where("id_toko = ?", tokoID).Order("id").Find(&members).Error
+	return members, err
+}
+
+func (r *tokoMemberRepository) Update(member model.TokoMember) (model.TokoMember, error) {
+	err := r.db.Omit("User", "Toko").Save(&member).Error
+	return member, err
+}
+
+func (r *tokoMemberRepository) Delete(member model.TokoMember) error {
+	return r.db.Delete(&member).Error
+}
+
+// toko created before staff feature get owner row for Toko.IDUser
+func (r *tokoMemberRepository) EnsureOwnerMemberships() error {
+	return r.db.Exec(
+		"INSERT INTO toko_member (id_toko, id_user, role, created_at_date, updated_at_date) "+
+			"SELECT toko.id, toko.id_user, ?, toko.created_at_date, toko.created_at_date FROM toko "+
+			"WHERE NOT EXISTS (SELECT 1 FROM toko_member WHERE toko_member.id_toko = toko.id AND toko_member.id_user = toko.id_user)",
+		model.TokoRoleOwner,
+	).Error
+}
+
+func (r *tokoMemberRepository) SaveInvitation(invitation model.TokoInvitation) (model.TokoInvitation, error) {
+	err := r.db.Create(&invitation).Error
+	return invitation, err
+}
+
+func (r *tokoMemberRepository) FindInvitationsByTokoID(tokoID uint) ([]model.TokoInvitation, error) {
+	var invitations []model.TokoInvitation
+	err := r.db.Where("id_toko = ?", tokoID).Order("id DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *tokoMemberRepository) FindInvitationByID(tokoID, invitationID uint) (model.TokoInvitation, error) {
+	var invitation model.TokoInvitation
+	err := r.db.Where("id = ? AND id_toko = ?", invitationID, tokoID).First(&invitation).Error
+	return invitation, err
+}
+
+func (r *tokoMemberRepository) FindInvitationByTokenHash(tokenHash string) (model.TokoInvitation, error) {
+	var invitation model.TokoInvitation
+	err := r.db.Where("token_hash = ?", tokenHash).First(&invitation).Error
+	return invitation, err
+}
+
+func (r *tokoMemberRepository) UpdateInvitation(invitation model.TokoInvitation) (model.TokoInvitation, error) {
+	err := r.db.Save(&invitation).Error
+	return invitation, err
+}
+
+// mark invitation accepted and create member in one transaction,
+// conditional update make sure one invitation only accepted once
+func (r *tokoMemberRepository) AcceptInvitation(invitation model.TokoInvitation, member model.TokoMember, now time.Time) (model.TokoMember, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.TokoInvitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"accepted_at": now, "accepted_by": member.IDUser, "updated_at_date": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationNotPending
+		}
+		return tx.Omit("User", "Toko").Create(&member).Error
+	})
+	return member, err
+}
@@ -1,15 +1,63 @@
 package repository
 
 import (
-	"rakamin-evermos/model" 
+	"errors"
+	"strings"
+	"time"
 
+	"rakamin-evermos/model"
+	"rakamin-evermos/utils"
+
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const tokoSlugIndex = "idx_toko_slug_unique"
+
+// returned by Save / Update when other toko saved the same slug first
+var ErrTokoSlugTaken = errors.New("slug toko already used by other toko")
+
 type TokoRepository interface {
 	Save(toko model.Toko) (model.Toko, error)
 	FindByUserID(userID uint) (model.Toko, error)
 	Update(toko model.Toko) (model.Toko, error)
+
+	// public storefront
+	FindByID(tokoID uint) (model.Toko, error)
+	FindBySlug(slug string) (model.Toko, error)
+	CountProduk(tokoID uint) (int64, error)
+
+	SlugExists(slug string, exceptTokoID uint) (bool, error)
+	FindAllWithoutSlug() ([]model.Toko, error)
+	EnsureSlugUniqueIndex() error
+
+	ReplaceJamOperasional(tokoID uint, jamOperasional []model.JamOperasional) error
+
+	// checkout, lock toko so mode libur can't change until transaksi done
+	FindByIDWithTx(tx *gorm.DB, tokoID uint) (model.Toko, error)
+
+	// dokumen verifikasi
+	SaveDokumen(dokumen model.TokoDokumen) (model.TokoDokumen, error)
+	FindDokumenByTokoID(tokoID uint) ([]model.TokoDokumen, error)
+	FindDokumenByID(tokoID, dokumenID uint) (model.TokoDokumen, error)
+	DeleteDokumen(dokumen model.TokoDokumen) error
+
+	// admin review queue
+	FindAllForReview(pagination utils.PaginationInput, filter TokoReviewFilterInput) ([]model.Toko, int64, error)
+	ActivateLegacyToko(now time.Time) (int64, error)
+
+	// toko with libur_sampai in (from, to], produk visible again without any change
+	FindLiburEndedBetween(from, to time.Time) ([]uint, error)
+}
+
+type TokoReviewFilterInput struct {
+	Status string
+	Search string
+}
+
+func preloadJamOperasional(db *gorm.DB) *gorm.DB {
+	return db.Order("hari")
 }
 
 type tokoRepository struct {
@@ -20,27 +68,173 @@ func NewTokoRepository(db *gorm.DB) TokoRepository {
 	return &tokoRepository{db}
 }
 
+// owner of new toko also saved as toko member with owner role
 func (r *tokoRepository) Save(toko model.Toko) (model.Toko, error) {
-	err := r.db.Create(&toko).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&toko).Error; err != nil {
+			return err
+		}
+		owner := model.TokoMember{
+			IDToko:        toko.ID,
+			IDUser:        toko.IDUser,
+			Role:          model.TokoRoleOwner,
+			CreatedAtDate: toko.CreatedAtDate,
+			UpdatedAtDate: toko.CreatedAtDate,
+		}
+		return tx.Create(&owner).Error
+	})
 	if err != nil {
-		return toko, err
+		return toko, slugTakenError(err)
 	}
 	return toko, nil
 }
 
 func (r *tokoRepository) FindByUserID(userID uint) (model.Toko, error) {
 	var toko model.Toko
-	err := r.db.Where("id_user = ?", userID).First(&toko).Error
+	err := r.db.Preload("JamOperasional", preloadJamOperasional).Where("id_user = ?", userID).First(&toko).Error
 	if err != nil {
 		return toko, err
 	}
 	return toko, nil
 }
 
+// jam operasional saved by ReplaceJamOperasional
 func (r *tokoRepository) Update(toko model.Toko) (model.Toko, error) {
-	err := r.db.Save(&toko).Error
+	err := r.db.Omit(clause.Associations).Save(&toko).Error
 	if err != nil {
-		return toko, err
+		return toko, slugTakenError(err)
 	}
 	return toko, nil
-}
\ No newline at end of file
+}
+
+func (r *tokoRepository) FindByID(tokoID uint) (model.Toko, error) {
+	var toko model.Toko
+	err := r.db.Preload("JamOperasional", preloadJamOperasional).Where("id = ?", tokoID).First(&toko).Error
+	return toko, err
+}
+
+func (r *tokoRepository) FindBySlug(slug string) (model.Toko, error) {
+	var toko model.Toko
+	err := r.db.Preload("JamOperasional", preloadJamOperasional).Where("slug = ?", slug).First(&toko).Error
+	return toko, err
+}
+
+// published produk only, shown in public profile
+func (r *tokoRepository) CountProduk(tokoID uint) (int64, error) {
+	var total int64
+	err := r.db.Model(&model.Produk{}).Where("id_toko = ? AND status = ?", tokoID, model.ProdukStatusPublished).Count(&total).Error
+	return total, err
+}
+
+func (r *tokoRepository) SlugExists(slug string, exceptTokoID uint) (bool, error) {
+	var total int64
+	err := r.db.Model(&model.Toko{}).Where("slug = ? AND id <> ?", slug, exceptTokoID).Count(&total).Error
+	return total > 0, err
+}
+
+// toko created before slug exist
+func (r *tokoRepository) FindAllWithoutSlug() ([]model.Toko, error) {
+	var tokos []model.Toko
+	err := r.db.Where("slug = ? OR slug IS NULL", "").Order("id").Find(&tokos).Error
+	return tokos, err
+}
+
+// legacy toko all had empty slug, so the index can only be made after BackfillSlugs, not by AutoMigrate
+func (r *tokoRepository) EnsureSlugUniqueIndex() error {
+	migrator := r.db.Migrator()
+	if migrator.HasIndex(&model.Toko{}, "idx_toko_slug") {
+		if err := migrator.DropIndex(&model.Toko{}, "idx_toko_slug"); err != nil {
+			return err
+		}
+	}
+	if migrator.HasIndex(&model.Toko{}, tokoSlugIndex) {
+		return nil
+	}
+	return r.db.Exec("CREATE UNIQUE INDEX " + tokoSlugIndex + " ON toko (slug)").Error
+}
+
+func slugTakenError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 && strings.Contains(mysqlErr.Message, tokoSlugIndex) {
+		return ErrTokoSlugTaken
+	}
+	return err
+}
+
+func (r *tokoRepository) ReplaceJamOperasional(tokoID uint, jamOperasional []model.JamOperasional) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id_toko = ?", tokoID).Delete(&model.JamOperasional{}).Error; err != nil {
+			return err
+		}
+		if len(jamOperasional) == 0 {
+			return nil
+		}
+		for i := range jamOperasional {
+			jamOperasional[i].IDToko = tokoID
+		}
+		return tx.Create(&jamOperasional).Error
+	})
+}
+
+func (r *tokoRepository) FindByIDWithTx(tx *gorm.DB, tokoID uint) (model.Toko, error) {
+	var toko model.Toko
+	err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Where("id = ?", tokoID).First(&toko).Error
+	return toko, err
+}
+
+func (r *tokoRepository) SaveDokumen(dokumen model.TokoDokumen) (model.TokoDokumen, error) {
+	err := r.db.Save(&dokumen).Error
+	return dokumen, err
+}
+
+func (r *tokoRepository) FindDokumenByTokoID(tokoID uint) ([]model.TokoDokumen, error) {
+	var dokumen []model.TokoDokumen
+	err := r.db.Where("id_toko = ?", tokoID).Order("id").Find(&dokumen).Error
+	return dokumen, err
+}
+
+func (r *tokoRepository) FindDokumenByID(tokoID, dokumenID uint) (model.TokoDokumen, error) {
+	var dokumen model.TokoDokumen
+	err := r.db.Where("id = ? AND id_toko = ?", dokumenID, tokoID).First(&dokumen).Error
+	return dokumen, err
+}
+
+func (r *tokoRepository) DeleteDokumen(dokumen model.TokoDokumen) error {
+	return r.db.Delete(&dokumen).Error
+}
+
+// oldest submission first, so review is first come first served
+func (r *tokoRepository) FindAllForReview(pagination utils.PaginationInput, filter TokoReviewFilterInput) ([]model.Toko, int64, error) {
+	var tokos []model.Toko
+	var totalData int64
+
+	query := r.db.Model(&model.Toko{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Search != "" {
+		search := "%" + filter.Search + "%"
+		query = query.Where("nama_toko LIKE ? OR slug LIKE ?", search, search)
+	}
+	if err := query.Count(&totalData).Error; err != nil {
+		return tokos, 0, err
+	}
+
+	err := query.Scopes(utils.Paginate(pagination.Page, pagination.Limit)).
+		Order("submitted_at IS NULL, submitted_at, id").Find(&tokos).Error
+	return tokos, totalData, err
+}
+
+// toko created before verifikasi exist already selling, keep them active
+func (r *tokoRepository) ActivateLegacyToko(now time.Time) (int64, error) {
+	result := r.db.Model(&model.Toko{}).Where("status = ? OR status IS NULL", "").
+		Updates(map[string]interface{}{"status": model.TokoStatusActive, "reviewed_at": now})
+	return result.RowsAffected, result.Error
+}
+
+func (r *tokoRepository) FindLiburEndedBetween(from, to time.Time) ([]uint, error) {
+	var tokoIDs []uint
+	err := r.db.Model(&model.Toko{}).Where("is_libur = ? AND libur_sampai > ? AND libur_sampai <= ?", true, from, to).
+		Pluck("id", &tokoIDs).Error
+	return tokoIDs, err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"rakamin-evermos/model"
+	"rakamin-evermos/utils"
 
 	"gorm.io/gorm"
 )
@@ -11,6 +12,7 @@ type TransaksiRepository interface {
 
 	// for see history
 	FindAllByUserID(userID uint) ([]model.Trx, error)
+	FindAllByUserIDAfter(userID uint, after *utils.CursorPosition, limit int) ([]model.Trx, *utils.CursorPosition, error)
 	FindByUserAndTrxID(userID, trxID uint) (model.Trx, error)
 }
 
@@ -31,12 +33,31 @@ func (r *transaksiRepository) Save(tx *gorm.DB, trx model.Trx) (model.Trx, error
 func (r *transaksiRepository) FindAllByUserID(userID uint) ([]model.Trx, error) {
 	var trxs []model.Trx
 	// get all transaksi and also pre load detail
-	err := r.db.Preload("DetailTrx").Preload("DetailTrx.LogProduk").Where("id_user = ?", userID).Find(&trxs).Error
+	err := r.db.Preload("DetailTrx").Preload("DetailTrx.LogProduk").Preload("LogAlamat").Where("id_user = ?", userID).Find(&trxs).Error
 	return trxs, err
 }
 
+// newest transaksi first, next nil = last page
+func (r *transaksiRepository) FindAllByUserIDAfter(userID uint, after *utils.CursorPosition, limit int) ([]model.Trx, *utils.CursorPosition, error) {
+	key := cursorKey{Scope: "trx", Desc: true}
+	if err := checkCursorScope(key, after); err != nil {
+		return nil, nil, err
+	}
+
+	var trxs []model.Trx
+	query := r.db.Preload("DetailTrx").Preload("DetailTrx.LogProduk").Preload("LogAlamat").Where("id_user = ?", userID)
+	if err := pageAfter(query, key, after, nil, limit).Find(&trxs).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(trxs) <= limit {
+		return trxs, nil, nil
+	}
+	trxs = trxs[:limit]
+	return trxs, &utils.CursorPosition{Scope: key.Scope, ID: trxs[limit-1].ID}, nil
+}
+
 func (r *transaksiRepository) FindByUserAndTrxID(userID, trxID uint) (model.Trx, error) {
 	var trx model.Trx
-	err := r.db.Preload("DetailTrx").Preload("DetailTrx.LogProduk").Where("id = ? AND id_user = ?", trxID, userID).First(&trx).Error
+	err := r.db.Preload("DetailTrx").Preload("DetailTrx.LogProduk").Preload("LogAlamat").Where("id = ? AND id_user = ?", trxID, userID).First(&trx).Error
 	return trx, err
 }
\ No newline at end of file
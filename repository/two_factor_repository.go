@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"time"
+
+	"rakamin-evermos/model"
+
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository interface {
+	FindByUserID(userID uint) (model.UserTwoFactor, error)
+	Save(twoFactor model.UserTwoFactor) (model.UserTwoFactor, error)
+	DeleteByUserID(userID uint) error
+	// false when step not newer than last used, so one code accepted once even in parallel request
+	UseTOTPStep(twoFactorID uint, step int64, now time.Time) (bool, error)
+
+	ReplaceRecoveryCodes(userID uint, codes []model.RecoveryCode) error
+	FindUnusedRecoveryCodes(userID uint) ([]model.RecoveryCode, error)
+	MarkRecoveryCodeUsed(codeID uint) error
+}
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db}
+}
+
+func (r *twoFactorRepository) FindByUserID(userID uint) (model.UserTwoFactor, error) {
+	var twoFactor model.UserTwoFactor
+	err := r.db.Where("id_user = ?", userID).First(&twoFactor).Error
+	return twoFactor, err
+}
+
+func (r *twoFactorRepository) Save(twoFactor model.UserTwoFactor) (model.UserTwoFactor, error) {
+	err := r.db.Save(&twoFactor).Error
+	return twoFactor, err
+}
+
+func (r *twoFactorRepository) UseTOTPStep(twoFactorID uint, step int64, now time.Time) (bool, error) {
+	result := r.db.Model(&model.UserTwoFactor{}).
+		Where("id = ? AND last_used_step < ?", twoFactorID, step).
+		Updates(map[string]interface{}{"last_used_step": step, "updated_at_date": now})
+	return result.RowsAffected == 1, result.Error
+}
+
+// remove totp setting and all recovery code together
+func (r *twoFactorRepository) DeleteByUserID(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id_user = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id_user = ?", userID).Delete(&model.UserTwoFactor{}).Error
+	})
+}
+
+// old codes is invalid once new codes generated
+func (r *twoFactorRepository) ReplaceRecoveryCodes(userID uint, codes []model.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id_user = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *twoFactorRepository) FindUnusedRecoveryCodes(userID uint) ([]model.RecoveryCode, error) {
+	var codes []model.RecoveryCode
+	err := r.db.Where("id_user = ? AND used_at IS NULL", userID).Find(&codes).Error
+	return codes, err
+}
+
+func (r *twoFactorRepository) MarkRecoveryCodeUsed(codeID uint) error {
+	// only update if still unused, so code cant be used twice in parallel request
+	result := r.db.Model(&model.RecoveryCode{}).Where("id = ? AND used_at IS NULL", codeID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
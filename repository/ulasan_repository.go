@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"rakamin-evermos/model"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// average rating and total ulasan, Rata 0 when no ulasan
+type RatingSummary struct {
+	Rata   float64
+	Jumlah int64
+}
+
+type UlasanRepository interface {
+	// ulasan of same user and produk replaced
+	Save(ulasan model.UlasanProduk) (model.UlasanProduk, error)
+	FindByProdukID(produkID uint, pagination utils.PaginationInput) ([]model.UlasanProduk, int64, error)
+	SummaryByTokoID(tokoID uint) (RatingSummary, error)
+	// user has trx with produk in it
+	HasBought(userID, produkID uint) (bool, error)
+}
+
+type ulasanRepository struct {
+	db *gorm.DB
+}
+
+func NewUlasanRepository(db *gorm.DB) UlasanRepository {
+	return &ulasanRepository{db}
+}
+
+func (r *ulasanRepository) Save(ulasan model.UlasanProduk) (model.UlasanProduk, error) {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id_user"}, {Name: "id_produk"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rating", "komentar", "updated_at_date"}),
+	}).Create(&ulasan).Error
+	if err != nil {
+		return ulasan, err
+	}
+
+	// id and created_at_date of replaced row
+	var saved model.UlasanProduk
+	err = r.db.Where("id_user = ? AND id_produk = ?", ulasan.IDUser, ulasan.IDProduk).First(&saved).Error
+	return saved, err
+}
+
+// newest first
+func (r *ulasanRepository) FindByProdukID(produkID uint, pagination utils.PaginationInput) ([]model.UlasanProduk, int64, error) {
+	var ulasans []model.UlasanProduk
+	var totalData int64
+
+	query := r.db.Model(&model.UlasanProduk{}).Where("id_produk = ?", produkID)
+	if err := query.Count(&totalData).Error; err != nil {
+		return ulasans, totalData, err
+	}
+
+	err := query.Order("updated_at_date DESC").Order("id DESC").Scopes(utils.Paginate(pagination.Page, pagination.Limit)).Find(&ulasans).Error
+	return ulasans, totalData, err
+}
+
+// produk in trash included, its ulasan still part of toko history
+func (r *ulasanRepository) SummaryByTokoID(tokoID uint) (RatingSummary, error) {
+	var summary RatingSummary
+	err := r.db.Model(&model.UlasanProduk{}).
+		Select("COALESCE(AVG(ulasan_produk.rating), 0) AS rata, COUNT(*) AS jumlah").
+		Joins("JOIN produk ON produk.id = ulasan_produk.id_produk").
+		Where("produk.id_toko = ?", tokoID).
+		Scan(&summary).Error
+	return summary, err
+}
+
+func (r *ulasanRepository) HasBought(userID, produkID uint) (bool, error) {
+	var total int64
+	err := r.db.Model(&model.DetailTrx{}).
+		Joins("JOIN trx ON trx.id = detail_trx.id_trx").
+		Joins("JOIN log_produk ON log_produk.id = detail_trx.id_log_produk").
+		Where("trx.id_user = ? AND log_produk.id_produk = ?", userID, produkID).
+		Count(&total).Error
+	return total > 0, err
+}
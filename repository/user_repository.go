@@ -1,16 +1,31 @@
 package repository
 
 import (
+	"time"
+
 	"rakamin-evermos/model" 
+	"rakamin-evermos/utils"
 
 	"gorm.io/gorm"
 )
 
+// user list parameter filter (admin), nil pointer is ignored
+type UserFilterInput struct {
+	Search    string // nama, email or no_telp
+	Role      string
+	IsAdmin   *bool
+	Suspended *bool
+}
+
 type UserRepository interface {
 	Save(user model.User) (model.User, error)
 	FindByEmail(email string) (model.User, error)
 	FindByID(userID uint) (model.User, error)
 	Update(user model.User) (model.User, error)
+	UpdateSuspension(userID uint, suspendedAt *time.Time, reason string, now time.Time) error
+
+	FindAll(pagination utils.PaginationInput, filter UserFilterInput) ([]model.User, int64, error)
+	FindStatusByID(userID uint) (model.User, error) // only status column, checked every request
 }
 
 type userRepository struct {
@@ -48,10 +63,60 @@ func (r *userRepository) FindByID(userID uint) (model.User, error) {
 	return user, nil
 }
 
+// suspension column only changed by UpdateSuspension, profile saved at same time cant lift a suspend
 func (r *userRepository) Update(user model.User) (model.User, error) {
-	err := r.db.Save(&user).Error
+	err := r.db.Omit("suspended_at", "suspended_reason").Save(&user).Error
 	if err != nil {
 		return user, err
 	}
 	return user, nil
-}
\ No newline at end of file
+}
+
+func (r *userRepository) UpdateSuspension(userID uint, suspendedAt *time.Time, reason string, now time.Time) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"suspended_at":     suspendedAt,
+		"suspended_reason": reason,
+		"updated_at_date":  now,
+	}).Error
+}
+
+func (r *userRepository) FindAll(pagination utils.PaginationInput, filter UserFilterInput) ([]model.User, int64, error) {
+	var users []model.User
+	var totalData int64
+
+	query := r.db.Model(&model.User{})
+	if filter.Search != "" {
+		search := "%" + filter.Search + "%"
+		query = query.Where("nama LIKE ? OR email LIKE ? OR no_telp LIKE ?", search, search, search)
+	}
+	if filter.Role != "" {
+		query = query.Where("id IN (?)", r.db.Table("user_role").
+			Select("user_role.id_user").
+			Joins("JOIN role ON role.id = user_role.id_role").
+			Where("role.name = ?", filter.Role))
+	}
+	if filter.IsAdmin != nil {
+		query = query.Where("is_admin = ?", *filter.IsAdmin)
+	}
+	if filter.Suspended != nil {
+		if *filter.Suspended {
+			query = query.Where("suspended_at IS NOT NULL")
+		} else {
+			query = query.Where("suspended_at IS NULL")
+		}
+	}
+
+	err := query.Count(&totalData).Error
+	if err != nil {
+		return users, totalData, err
+	}
+
+	err = query.Preload("Roles").Order("id DESC").Scopes(utils.Paginate(pagination.Page, pagination.Limit)).Find(&users).Error
+	return users, totalData, err
+}
+
+func (r *userRepository) FindStatusByID(userID uint) (model.User, error) {
+	var user model.User
+	err := r.db.Select("id", "suspended_at", "anonymized_at").Where("id = ?", userID).First(&user).Error
+	return user, err
+}
@@ -5,6 +5,7 @@ import (
 
 	"rakamin-evermos/handler"
 	"rakamin-evermos/middleware"
+	"rakamin-evermos/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,19 +17,55 @@ func SetupRouter(r *gin.Engine,
 	 categoryHandler handler.CategoryHandler,
 	 tokoHandler handler.TokoHandler,
 	 produkHandler handler.ProdukHandler,
+	 produkImportHandler handler.ProdukImportHandler,
 	 transaksiHandler handler.TransaksiHandler,
+	 twoFactorHandler handler.TwoFactorHandler,
+	 roleHandler handler.RoleHandler,
+	 apiKeyHandler handler.APIKeyHandler,
+	 adminUserHandler handler.AdminUserHandler,
+	 accountHandler handler.AccountHandler,
+	 regionHandler handler.RegionHandler,
+	 tokoMemberHandler handler.TokoMemberHandler,
+	 adminTokoHandler handler.AdminTokoHandler,
+	 ulasanHandler handler.UlasanHandler,
+	 permission *middleware.PermissionMiddleware,
+	 accounts middleware.AccountChecker,
+	 apiKeyAuth middleware.APIKeyAuthenticator,
 ) {
 
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	api := r.Group("/api/v1")
 
 	api.POST("/register", authHandler.Register)
 	api.POST("/login", authHandler.Login)
+	api.POST("/login/2fa", authHandler.VerifyTwoFactor)
+	api.GET("/auth/oidc/:provider/login", authHandler.OIDCLogin)
+	api.GET("/auth/oidc/:provider/callback", authHandler.OIDCCallback)
 
 	api.GET("/produk", produkHandler.GetAllProduk)
+	api.GET("/produk/suggest", produkHandler.GetSuggestions)
 	api.GET("/produk/:id", produkHandler.GetProdukByID)
+	api.GET("/produk/:id/ulasan", ulasanHandler.GetUlasan)
+
+	// Public kategori tree, :id is ID kategori or slug
+	api.GET("/categories", categoryHandler.GetCategoryTree)
+	api.GET("/categories/:id", categoryHandler.GetCategoryNode)
+	api.GET("/categories/:id/atribut", categoryHandler.GetAtribut)
+
+	// Public storefront, :id is ID toko or slug
+	api.GET("/toko/:id", tokoHandler.GetTokoProfile)
+	api.GET("/toko/:id/produk", tokoHandler.GetTokoProduk)
+
+	// Region reference (wilayah) routes
+	api.GET("/wilayah/provinsi", regionHandler.GetProvinsi)
+	api.GET("/wilayah/provinsi/:id/kota", regionHandler.GetKota)
+	api.GET("/wilayah/kota/:id/kecamatan", regionHandler.GetKecamatan)
+	api.GET("/wilayah/kecamatan/:id/kelurahan", regionHandler.GetKelurahan)
+	api.GET("/wilayah/kode-pos/:kode", regionHandler.GetKelurahanByKodePos)
 
 	authenticated := api.Group("")
-	authenticated.Use(middleware.AuthMiddleware())
+	authenticated.Use(middleware.AuthMiddleware(accounts, nil))
 	{
 		// protected route example
 		authenticated.GET("/test-auth", func(c *gin.Context) {
@@ -45,40 +82,122 @@ func SetupRouter(r *gin.Engine,
 		authenticated.GET("users/me", userHandler.GetProfile)
 		authenticated.PUT("users/me", userHandler.UpdateProfile)
 
+		// Account data export & deletion
+		authenticated.GET("/users/me/export", accountHandler.ExportData)
+		authenticated.DELETE("/users/me", accountHandler.DeleteAccount)
+		authenticated.POST("/users/me/cancel-deletion", accountHandler.CancelDeletion)
+
+		// 2FA (TOTP) routes
+		authenticated.POST("/users/me/2fa/enroll", twoFactorHandler.Enroll)
+		authenticated.POST("/users/me/2fa/confirm", twoFactorHandler.Confirm)
+		authenticated.POST("/users/me/2fa/disable", twoFactorHandler.Disable)
+		authenticated.POST("/users/me/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+
+		// API key routes (only with login session, not with api key)
+		authenticated.POST("/users/me/api-keys", apiKeyHandler.CreateAPIKey)
+		authenticated.GET("/users/me/api-keys", apiKeyHandler.GetAPIKeys)
+		authenticated.DELETE("/users/me/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+
 		// Address routes
 		authenticated.POST("/addresses", addressHandler.CreateAddress)
 		authenticated.GET("/addresses", addressHandler.GetAddresses)
 		authenticated.GET("/addresses/:id", addressHandler.GetAddressByID)
 		authenticated.PUT("/addresses/:id", addressHandler.UpdateAddress)
+		authenticated.PUT("/addresses/:id/default", addressHandler.SetDefaultAddress)
 		authenticated.DELETE("/addresses/:id", addressHandler.DeleteAddress)
 
+		// Ulasan routes, only buyer of produk
+		authenticated.POST("/produk/:id/ulasan", ulasanHandler.CreateUlasan)
+
 		// Toko routes
 		authenticated.GET("/toko/me", tokoHandler.GetMyToko)
 		authenticated.PUT("/toko/me", tokoHandler.UpdateMyToko)
 		authenticated.POST("/toko/me/photo", tokoHandler.UploadTokoPhoto)
+		authenticated.PUT("/toko/me/libur", tokoHandler.SetLibur)
+
+		// Toko verifikasi routes, owner upload dokumen then submit to admin review
+		authenticated.GET("/toko/me/dokumen", tokoHandler.GetDokumen)
+		authenticated.POST("/toko/me/dokumen", tokoHandler.UploadDokumen)
+		authenticated.DELETE("/toko/me/dokumen/:id", tokoHandler.DeleteDokumen)
+		authenticated.POST("/toko/me/submit", tokoHandler.SubmitVerifikasi)
+
+		// Toko staff routes, acting toko chosen with X-Toko-ID header
+		authenticated.GET("/users/me/toko", tokoMemberHandler.GetMyTokoList)
+		authenticated.POST("/toko/invitations/accept", tokoMemberHandler.AcceptInvitation)
+		authenticated.GET("/toko/me/staff", tokoMemberHandler.GetStaff)
+		authenticated.PUT("/toko/me/staff/:userId", tokoMemberHandler.UpdateStaffRole)
+		authenticated.DELETE("/toko/me/staff/:userId", tokoMemberHandler.RemoveStaff)
+		authenticated.POST("/toko/me/invitations", tokoMemberHandler.InviteStaff)
+		authenticated.GET("/toko/me/invitations", tokoMemberHandler.GetInvitations)
+		authenticated.DELETE("/toko/me/invitations/:id", tokoMemberHandler.RevokeInvitation)
+	}
 
+	// route can be used with JWT or scoped api key (ERP integration), api key rejected on route without RequireScope
+	// RequireScope go before integrationAuth, it set the scope checked by AuthMiddleware
+	integration := api.Group("")
+	integrationAuth := middleware.AuthMiddleware(accounts, apiKeyAuth)
+	{
 		// Produk routes
-		authenticated.POST("/my-produk", produkHandler.CreateProduk)
-		authenticated.GET("/my-produk", produkHandler.GetMyProduk)
-		authenticated.PUT("/my-produk/:id", produkHandler.UpdateProduk)
-		authenticated.DELETE("/my-produk/:id", produkHandler.DeleteProduk)
-		authenticated.POST("/my-produk/:id/photo", produkHandler.UploadFotoProduk)
+		integration.POST("/my-produk", middleware.RequireScope(model.ScopeProdukWrite), integrationAuth, produkHandler.CreateProduk)
+		integration.GET("/my-produk", middleware.RequireScope(model.ScopeProdukRead), integrationAuth, produkHandler.GetMyProduk)
+		integration.PUT("/my-produk/:id", middleware.RequireScope(model.ScopeProdukWrite), integrationAuth, produkHandler.UpdateProduk)
+		integration.PUT("/my-produk/:id/status", middleware.RequireScope(model.ScopeProdukWrite), integrationAuth, produkHandler.SetProdukStatus)
+		integration.DELETE("/my-produk/:id", middleware.RequireScope(model.ScopeProdukWrite), integrationAuth, produkHandler.DeleteProduk)
+		integration.POST("/my-produk/import", middleware.RequireScope(model.ScopeProdukWrite), integrationAuth, produkImportHandler.ImportProduk)
+		integration.GET("/my-produk/import/:id", middleware.RequireScope(model.ScopeProdukRead), integrationAuth, produkImportHandler.GetImport)
+		integration.GET("/my-produk/export", middleware.RequireScope(model.ScopeProdukRead), integrationAuth, produkImportHandler.ExportProduk)
+		integration.GET("/my-produk/deleted", middleware.RequireScope(model.ScopeProdukRead), integrationAuth, produkHandler.GetDeletedProduk)
+		integration.POST("/my-produk/:id/restore", middleware.RequireScope(model.ScopeProdukWrite), integrationAuth, produkHandler.RestoreProduk)
+		integration.POST("/my-produk/:id/photo", middleware.RequireScope(model.ScopeProdukWrite), integrationAuth, produkHandler.UploadFotoProduk)
 
 		// Transaksi routes
-		authenticated.POST("/transaksi", transaksiHandler.CreateTransaksi) // Checkout
-		authenticated.GET("/transaksi", transaksiHandler.GetMyTransaksi)   // history
-		authenticated.GET("/transaksi/:id", transaksiHandler.GetMyTransaksiByID) // Detail history
+		integration.POST("/transaksi", middleware.RequireScope(model.ScopeTransaksiWrite), integrationAuth, transaksiHandler.CreateTransaksi) // Checkout
+		integration.GET("/transaksi", middleware.RequireScope(model.ScopeTransaksiRead), integrationAuth, transaksiHandler.GetMyTransaksi)   // history
+		integration.GET("/transaksi/:id", middleware.RequireScope(model.ScopeTransaksiRead), integrationAuth, transaksiHandler.GetMyTransaksiByID) // Detail history
 	}
 
+	// admin area, every route guarded by permission from user role
 	admin := api.Group("")
-	admin.Use(middleware.AuthMiddleware(), middleware.AdminOnlyMiddleware())
+	admin.Use(middleware.AuthMiddleware(accounts, nil))
 	{
 		// Category routes
-		admin.POST("/categories", categoryHandler.CreateCategory)
-		admin.GET("/categories", categoryHandler.GetAllCategories)
-		admin.GET("/categories/:id", categoryHandler.GetCategoryByID)
-		admin.PUT("/categories/:id", categoryHandler.UpdateCategory)
-		admin.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+		admin.POST("/categories", permission.RequirePermission(model.PermissionCategoryWrite), categoryHandler.CreateCategory)
+		admin.GET("/admin/categories", permission.RequirePermission(model.PermissionCategoryRead), categoryHandler.GetAllCategories)
+		admin.GET("/admin/categories/:id", permission.RequirePermission(model.PermissionCategoryRead), categoryHandler.GetCategoryByID)
+		admin.PUT("/categories/:id", permission.RequirePermission(model.PermissionCategoryWrite), categoryHandler.UpdateCategory)
+		admin.DELETE("/categories/:id", permission.RequirePermission(model.PermissionCategoryWrite), categoryHandler.DeleteCategory)
+		admin.POST("/categories/:id/merge", permission.RequirePermission(model.PermissionCategoryWrite), categoryHandler.MergeCategory)
+		admin.POST("/categories/:id/atribut", permission.RequirePermission(model.PermissionCategoryWrite), categoryHandler.CreateAtribut)
+		admin.PUT("/categories/:id/atribut/:atributId", permission.RequirePermission(model.PermissionCategoryWrite), categoryHandler.UpdateAtribut)
+		admin.DELETE("/categories/:id/atribut/:atributId", permission.RequirePermission(model.PermissionCategoryWrite), categoryHandler.DeleteAtribut)
+
+		// Login audit & lockout routes
+		admin.GET("/admin/login-attempts", permission.RequirePermission(model.PermissionLoginAudit), authHandler.GetLoginAttempts)
+		admin.POST("/admin/login-attempts/unlock", permission.RequirePermission(model.PermissionLoginUnlock), authHandler.UnlockLogin)
+
+		// Role routes
+		admin.GET("/admin/roles", permission.RequirePermission(model.PermissionRoleManage), roleHandler.GetRoles)
+		admin.POST("/admin/roles", permission.RequirePermission(model.PermissionRoleManage), roleHandler.CreateRole)
+		admin.PUT("/admin/roles/:id", permission.RequirePermission(model.PermissionRoleManage), roleHandler.UpdateRole)
+		admin.GET("/admin/permissions", permission.RequirePermission(model.PermissionRoleManage), roleHandler.GetPermissions)
+		admin.GET("/admin/users/:id/roles", permission.RequirePermission(model.PermissionRoleManage), roleHandler.GetUserRoles)
+		admin.PUT("/admin/users/:id/roles", permission.RequirePermission(model.PermissionRoleManage), roleHandler.AssignUserRoles)
+
+		// User management routes
+		admin.GET("/admin/users", permission.RequirePermission(model.PermissionUserRead), adminUserHandler.GetUsers)
+		admin.GET("/admin/users/:id", permission.RequirePermission(model.PermissionUserRead), adminUserHandler.GetUserByID)
+		admin.POST("/admin/users/:id/suspend", permission.RequirePermission(model.PermissionUserWrite), adminUserHandler.SuspendUser)
+		admin.POST("/admin/users/:id/unsuspend", permission.RequirePermission(model.PermissionUserWrite), adminUserHandler.UnsuspendUser)
+		admin.GET("/admin/audit-logs", permission.RequirePermission(model.PermissionAuditRead), adminUserHandler.GetAuditLogs)
+
+		// Toko verifikasi routes
+		admin.GET("/admin/toko", permission.RequirePermission(model.PermissionTokoReview), adminTokoHandler.GetReviewQueue)
+		admin.GET("/admin/toko/:id", permission.RequirePermission(model.PermissionTokoReview), adminTokoHandler.GetTokoByID)
+		admin.GET("/admin/toko/:id/dokumen/:dokumenId", permission.RequirePermission(model.PermissionTokoReview), adminTokoHandler.GetDokumenFile)
+		admin.POST("/admin/toko/:id/approve", permission.RequirePermission(model.PermissionTokoReview), adminTokoHandler.ApproveToko)
+		admin.POST("/admin/toko/:id/reject", permission.RequirePermission(model.PermissionTokoReview), adminTokoHandler.RejectToko)
+		admin.POST("/admin/toko/:id/suspend", permission.RequirePermission(model.PermissionTokoReview), adminTokoHandler.SuspendToko)
+		admin.POST("/admin/toko/:id/unsuspend", permission.RequirePermission(model.PermissionTokoReview), adminTokoHandler.UnsuspendToko)
 	}
 
-}
\ No newline at end of file
+}
@@ -0,0 +1,303 @@
+package usecase
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAccountDeletionGrace = 30 * 24 * time.Hour
+	// delete account need fresh login, stolen old token is not enough
+	accountDeletionRecentLogin = 15 * time.Minute
+)
+
+// ACCOUNT_DELETION_GRACE_DAYS, default 30 days
+func AccountDeletionGraceFromEnv() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"))
+	if err != nil || days < 0 {
+		return defaultAccountDeletionGrace
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// profile without password and internal flag
+type ExportProfile struct {
+	ID                  uint       `json:"id"`
+	Nama                string     `json:"nama"`
+	Email               string     `json:"email"`
+	NoTelp              string     `json:"no_telp"`
+	TanggalLahir        *time.Time `json:"tanggal_lahir"`
+	JenisKelamin        string     `json:"jenis_kelamin"`
+	Tentang             string     `json:"tentang"`
+	Pekerjaan           string     `json:"pekerjaan"`
+	IDProvinsi          int        `json:"id_provinsi"`
+	IDKota              int        `json:"id_kota"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+	CreatedAtDate       time.Time  `json:"created_at_date"`
+}
+
+type AccountExport struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Profile    ExportProfile  `json:"profile"`
+	Addresses  []model.Alamat `json:"addresses"`
+	Toko       *model.Toko    `json:"toko"`
+	Produk     []model.Produk `json:"produk"`
+	Transaksi  []model.Trx    `json:"transaksi"`
+}
+
+type AccountUsecase interface {
+	ExportData(userID uint) (AccountExport, error)
+	ExportZip(userID uint, w io.Writer) error // json file + uploaded photo
+
+	RequestDeletion(userID uint, tokenIssuedAt time.Time, ipAddress string) (time.Time, error) // return when deletion become final
+	CancelDeletion(userID uint, ipAddress string) error
+	PurgeDueAccounts(now time.Time) (int, error)
+}
+
+type accountUsecase struct {
+	accountRepo  repository.AccountRepository
+	userRepo     repository.UserRepository
+	auditLogRepo repository.AuditLogRepository
+	indexer      ProdukIndexer
+	gracePeriod  time.Duration
+}
+
+func NewAccountUsecase(
+	accountRepo repository.AccountRepository,
+	userRepo repository.UserRepository,
+	auditLogRepo repository.AuditLogRepository,
+	indexer ProdukIndexer,
+	gracePeriod time.Duration,
+) AccountUsecase {
+	return &accountUsecase{accountRepo, userRepo, auditLogRepo, indexer, gracePeriod}
+}
+
+func (uc *accountUsecase) ExportData(userID uint) (AccountExport, error) {
+	data, err := uc.accountRepo.FindAccountData(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return AccountExport{}, errors.New("profile user cant be found")
+		}
+		return AccountExport{}, fmt.Errorf("failed get account data: %w", err)
+	}
+
+	user := data.User
+	return AccountExport{
+		ExportedAt: time.Now(),
+		Profile: ExportProfile{
+			ID:                  user.ID,
+			Nama:                user.Nama,
+			Email:               user.Email,
+			NoTelp:              user.NoTelp,
+			TanggalLahir:        user.TanggalLahir,
+			JenisKelamin:        user.JenisKelamin,
+			Tentang:             user.Tentang,
+			Pekerjaan:           user.Pekerjaan,
+			IDProvinsi:          user.IDProvinsi,
+			IDKota:              user.IDKota,
+			DeletionScheduledAt: user.DeletionScheduledAt,
+			CreatedAtDate:       user.CreatedAtDate,
+		},
+		Addresses: data.Addresses,
+		Toko:      data.Toko,
+		Produk:    data.Produk,
+		Transaksi: data.Trx,
+	}, nil
+}
+
+// zip content: export.json, one json per section, and photos/ with uploaded file that still exist
+func (uc *accountUsecase) ExportZip(userID uint, w io.Writer) error {
+	export, err := uc.ExportData(userID)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"export.json", export},
+		{"profile.json", export.Profile},
+		{"addresses.json", export.Addresses},
+		{"toko.json", export.Toko},
+		{"produk.json", export.Produk},
+		{"transaksi.json", export.Transaksi},
+	}
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed encode %s: %w", file.name, err)
+		}
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(content); err != nil {
+			return err
+		}
+	}
+
+	var photos []string
+	if export.Toko != nil && export.Toko.UrlFoto != "" {
+		photos = append(photos, export.Toko.UrlFoto)
+	}
+	for _, produk := range export.Produk {
+		for _, foto := range produk.FotoProduk {
+			photos = append(photos, foto.Url)
+		}
+	}
+	for _, photo := range photos {
+		if err := addFileToZip(archive, photo, "photos/"+filepath.Base(photo)); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// missing file is skipped, db can point to file already removed
+func addFileToZip(archive *zip.Writer, path, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+// account still usable during grace period, so user can cancel or export data
+func (uc *accountUsecase) RequestDeletion(userID uint, tokenIssuedAt time.Time, ipAddress string) (time.Time, error) {
+	now := time.Now()
+	if now.Sub(tokenIssuedAt) > accountDeletionRecentLogin {
+		return time.Time{}, errors.New("please login again before deleting your account")
+	}
+
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil {
+		return time.Time{}, errors.New("profile user cant be found")
+	}
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, errors.New("account deletion already requested")
+	}
+
+	scheduledAt := now.Add(uc.gracePeriod)
+	user.DeletionScheduledAt = &scheduledAt
+	user.UpdatedAtDate = now
+	if _, err := uc.userRepo.Update(user); err != nil {
+		return time.Time{}, fmt.Errorf("failed request deletion: %w", err)
+	}
+
+	recordAudit(uc.auditLogRepo, userID, model.AuditAccountDeletionRequest, "user", userID, map[string]interface{}{"scheduled_at": scheduledAt}, ipAddress)
+
+	// grace period 0 mean delete now
+	if uc.gracePeriod == 0 {
+		if err := uc.anonymize(userID, now); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return scheduledAt, nil
+}
+
+func (uc *accountUsecase) CancelDeletion(userID uint, ipAddress string) error {
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("profile user cant be found")
+	}
+	if user.DeletionScheduledAt == nil {
+		return errors.New("account deletion not requested")
+	}
+
+	user.DeletionScheduledAt = nil
+	user.UpdatedAtDate = time.Now()
+	if _, err := uc.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed cancel deletion: %w", err)
+	}
+
+	recordAudit(uc.auditLogRepo, userID, model.AuditAccountDeletionCancel, "user", userID, nil, ipAddress)
+	return nil
+}
+
+// run by background job, one failed account dont stop the others
+func (uc *accountUsecase) PurgeDueAccounts(now time.Time) (int, error) {
+	users, err := uc.accountRepo.FindDueDeletions(now)
+	if err != nil {
+		return 0, fmt.Errorf("failed get due deletion: %w", err)
+	}
+
+	purged := 0
+	for _, user := range users {
+		if err := uc.anonymize(user.ID, now); err != nil {
+			fmt.Printf("failed anonymize user %d: %v\n", user.ID, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (uc *accountUsecase) anonymize(userID uint, now time.Time) error {
+	removedFiles, err := uc.accountRepo.Anonymize(userID, now)
+	if err != nil {
+		return fmt.Errorf("failed anonymize account: %w", err)
+	}
+
+	// file removed after commit, leftover file is better than missing file for live data
+	for _, path := range removedFiles {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("failed remove file %s: %v\n", path, err)
+		}
+	}
+	// toko suspended and renamed, its produk leave the index
+	uc.indexer.ReindexUserToko(userID)
+
+	recordAudit(uc.auditLogRepo, 0, model.AuditAccountAnonymize, "user", userID, map[string]interface{}{"removed_files": len(removedFiles)}, "")
+	return nil
+}
+
+// StartAccountPurgeJob anonymize account past grace period every interval, call returned func to stop
+func StartAccountPurgeJob(uc AccountUsecase, interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			purged, err := uc.PurgeDueAccounts(time.Now())
+			if err != nil {
+				fmt.Printf("account purge job: %v\n", err)
+			} else if purged > 0 {
+				fmt.Printf("account purge job: %d account anonymized\n", purged)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
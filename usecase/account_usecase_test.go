@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+)
+
+type fakeAccountRepo struct {
+	repository.AccountRepository
+	userRepo     *fakeUserRepo
+	toko         *model.Toko
+	produk       []model.Produk
+	removedFiles []string
+	anonymized   []uint
+}
+
+func (r *fakeAccountRepo) FindAccountData(userID uint) (repository.AccountData, error) {
+	user, err := r.userRepo.FindByID(userID)
+	if err != nil {
+		return repository.AccountData{}, err
+	}
+	return repository.AccountData{User: user, Toko: r.toko, Produk: r.produk}, nil
+}
+
+func (r *fakeAccountRepo) FindDueDeletions(now time.Time) ([]model.User, error) {
+	var users []model.User
+	for _, user := range r.userRepo.users {
+		if user.DeletionScheduledAt != nil && !user.DeletionScheduledAt.After(now) && user.AnonymizedAt == nil {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *fakeAccountRepo) Anonymize(userID uint, now time.Time) ([]string, error) {
+	r.anonymized = append(r.anonymized, userID)
+	user := &r.userRepo.users[userID-1]
+	user.Email = "deleted@deleted.invalid"
+	user.DeletionScheduledAt = nil
+	user.AnonymizedAt = &now
+	return r.removedFiles, nil
+}
+
+func newAccountTestUsecase(gracePeriod time.Duration) (*accountUsecase, *fakeAccountRepo, *fakeProdukIndexer) {
+	userRepo := &fakeUserRepo{}
+	userRepo.Save(model.User{Nama: "Budi", Email: "budi@example.com", NoTelp: "0812", KataSandi: "hashed"})
+	userRepo.Save(model.User{Nama: "Sari", Email: "sari@example.com", NoTelp: "0813"})
+	accountRepo := &fakeAccountRepo{userRepo: userRepo}
+	indexer := &fakeProdukIndexer{}
+	uc := NewAccountUsecase(accountRepo, userRepo, &fakeAuditLogRepo{}, indexer, gracePeriod).(*accountUsecase)
+	return uc, accountRepo, indexer
+}
+
+func TestExportZipContainProfileAndPhoto(t *testing.T) {
+	uc, accountRepo, _ := newAccountTestUsecase(time.Hour)
+	photo := filepath.Join(t.TempDir(), "foto-toko.jpg")
+	if err := os.WriteFile(photo, []byte("jpeg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	accountRepo.toko = &model.Toko{ID: 1, NamaToko: "Toko Budi", UrlFoto: photo}
+	accountRepo.produk = []model.Produk{{ID: 1, FotoProduk: []model.FotoProduk{{Url: "missing.jpg"}}}}
+
+	var buffer bytes.Buffer
+	if err := uc.ExportZip(1, &buffer); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[file.Name], _ = io.ReadAll(reader)
+		reader.Close()
+	}
+
+	for _, name := range []string{"export.json", "profile.json", "addresses.json", "toko.json", "produk.json", "transaksi.json"} {
+		if _, ok := entries[name]; !ok {
+			t.Errorf("expected %s in zip", name)
+		}
+	}
+	// missing foto skipped
+	if string(entries["photos/foto-toko.jpg"]) != "jpeg" || len(entries) != 7 {
+		t.Fatalf("expected only foto toko in photos, got %d entries", len(entries))
+	}
+
+	var profile map[string]interface{}
+	if err := json.Unmarshal(entries["profile.json"], &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile["email"] != "budi@example.com" || profile["kata_sandi"] != nil || profile["KataSandi"] != nil {
+		t.Fatalf("unexpected profile %v", profile)
+	}
+}
+
+func TestAccountDeletionGracePeriod(t *testing.T) {
+	uc, accountRepo, indexer := newAccountTestUsecase(30 * 24 * time.Hour)
+	removed := filepath.Join(t.TempDir(), "foto-produk.jpg")
+	if err := os.WriteFile(removed, []byte("jpeg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	accountRepo.removedFiles = []string{removed}
+
+	if _, err := uc.RequestDeletion(1, time.Now().Add(-time.Hour), ""); err == nil {
+		t.Fatal("expected old login refused")
+	}
+	scheduledAt, err := uc.RequestDeletion(1, time.Now(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.RequestDeletion(1, time.Now(), ""); err == nil {
+		t.Fatal("expected second request refused")
+	}
+
+	// still in grace period
+	if purged, _ := uc.PurgeDueAccounts(scheduledAt.Add(-time.Minute)); purged != 0 || len(accountRepo.anonymized) != 0 {
+		t.Fatalf("expected nothing purged in grace period, got %d", purged)
+	}
+
+	if err := uc.CancelDeletion(1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if purged, _ := uc.PurgeDueAccounts(scheduledAt.Add(time.Minute)); purged != 0 {
+		t.Fatalf("expected canceled deletion not purged, got %d", purged)
+	}
+
+	scheduledAt, err = uc.RequestDeletion(1, time.Now(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	purged, err := uc.PurgeDueAccounts(scheduledAt.Add(time.Minute))
+	if err != nil || purged != 1 || len(accountRepo.anonymized) != 1 || accountRepo.anonymized[0] != 1 {
+		t.Fatalf("expected user 1 anonymized, got %d %v %v", purged, accountRepo.anonymized, err)
+	}
+	if _, err := os.Stat(removed); !os.IsNotExist(err) {
+		t.Fatalf("expected foto removed after anonymize, stat error %v", err)
+	}
+	if len(indexer.userIDs) != 1 || indexer.userIDs[0] != 1 {
+		t.Fatalf("expected produk of user toko reindexed, got %v", indexer.userIDs)
+	}
+
+	// already anonymized, not purged again
+	if purged, _ := uc.PurgeDueAccounts(scheduledAt.Add(time.Hour)); purged != 0 {
+		t.Fatalf("expected anonymized account skipped, got %d", purged)
+	}
+}
+
+func TestAccountDeletionWithoutGracePeriod(t *testing.T) {
+	uc, accountRepo, _ := newAccountTestUsecase(0)
+	if _, err := uc.RequestDeletion(2, time.Now(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(accountRepo.anonymized) != 1 || accountRepo.anonymized[0] != 2 {
+		t.Fatalf("expected user 2 anonymized immediately, got %v", accountRepo.anonymized)
+	}
+}
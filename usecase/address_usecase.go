@@ -16,18 +16,61 @@ type AddressUsecase interface {
 	GetAddresses(userID uint) ([]model.Alamat, error)
 	GetAddressByID(addressID, userID uint) (model.Alamat, error)
 	UpdateAddress(addressID, userID uint, inputAlamat model.Alamat) (model.Alamat, error)
+	SetDefaultAddress(addressID, userID uint) (model.Alamat, error)
 	DeleteAddress(addressID, userID uint) error
 }
 
+// input alamat not valid (other than region), handler return 400
+var ErrAddressNotValid = errors.New("alamat not valid")
+
 type addressUsecase struct {
-	addressRepo repository.AddressRepository
+	addressRepo   repository.AddressRepository
+	regionUsecase RegionUsecase
+}
+
+func NewAddressUsecase(addressRepo repository.AddressRepository, regionUsecase RegionUsecase) AddressUsecase {
+	return &addressUsecase{addressRepo, regionUsecase}
+}
+
+func alamatRegion(alamat model.Alamat) RegionInput {
+	return RegionInput{
+		IDProvinsi:  alamat.IDProvinsi,
+		IDKota:      alamat.IDKota,
+		IDKecamatan: alamat.IDKecamatan,
+		IDKelurahan: alamat.IDKelurahan,
+		KodePos:     alamat.KodePos,
+	}
+}
+
+// latitude and longitude is optional, but must be filled together
+func validateCoordinate(alamat model.Alamat) error {
+	if (alamat.Latitude == nil) != (alamat.Longitude == nil) {
+		return fmt.Errorf("%w: latitude and longitude must be filled together", ErrAddressNotValid)
+	}
+	if alamat.Latitude == nil {
+		return nil
+	}
+	if *alamat.Latitude < -90 || *alamat.Latitude > 90 {
+		return fmt.Errorf("%w: latitude must be between -90 and 90", ErrAddressNotValid)
+	}
+	if *alamat.Longitude < -180 || *alamat.Longitude > 180 {
+		return fmt.Errorf("%w: longitude must be between -180 and 180", ErrAddressNotValid)
+	}
+	return nil
 }
 
-func NewAddressUsecase(addressRepo repository.AddressRepository) AddressUsecase {
-	return &addressUsecase{addressRepo}
+func (uc *addressUsecase) validateAddress(alamat model.Alamat) error {
+	if err := validateCoordinate(alamat); err != nil {
+		return err
+	}
+	return uc.regionUsecase.ValidateRegion(alamatRegion(alamat))
 }
 
+// first alamat of user become default automatically
 func (uc *addressUsecase) CreateAddress(userID uint, alamat model.Alamat) (model.Alamat, error) {
+	if err := uc.validateAddress(alamat); err != nil {
+		return model.Alamat{}, err
+	}
 	alamat.IDUser = userID
 
 	now := time.Now()
@@ -69,20 +112,49 @@ func (uc *addressUsecase) UpdateAddress(addressID, userID uint, inputAlamat mode
 		return model.Alamat{}, fmt.Errorf("failed verify address: %w", err)
 	}
 
+	if err := uc.validateAddress(inputAlamat); err != nil {
+		return model.Alamat{}, err
+	}
+
 	// field can be updated
 	existingAlamat.JudulAlamat = inputAlamat.JudulAlamat
 	existingAlamat.NamaPenerima = inputAlamat.NamaPenerima
 	existingAlamat.NoTelp = inputAlamat.NoTelp
 	existingAlamat.DetailAlamat = inputAlamat.DetailAlamat
+	existingAlamat.IDProvinsi = inputAlamat.IDProvinsi
+	existingAlamat.IDKota = inputAlamat.IDKota
+	existingAlamat.IDKecamatan = inputAlamat.IDKecamatan
+	existingAlamat.IDKelurahan = inputAlamat.IDKelurahan
+	existingAlamat.KodePos = inputAlamat.KodePos
+	existingAlamat.Jalan = inputAlamat.Jalan
+	existingAlamat.Catatan = inputAlamat.Catatan
+	existingAlamat.Latitude = inputAlamat.Latitude
+	existingAlamat.Longitude = inputAlamat.Longitude
 	existingAlamat.UpdatedAtDate = time.Now()
 
 	updatedAlamat, err := uc.addressRepo.Update(existingAlamat)
 	if err != nil {
 		return updatedAlamat, fmt.Errorf("failed update address: %w", err)
 	}
+
+	// default can only be moved to other alamat, not removed
+	if inputAlamat.IsDefault && !updatedAlamat.IsDefault {
+		return uc.SetDefaultAddress(addressID, userID)
+	}
 	return updatedAlamat, nil
 }
 
+func (uc *addressUsecase) SetDefaultAddress(addressID, userID uint) (model.Alamat, error) {
+	err := uc.addressRepo.SetDefault(addressID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Alamat{}, errors.New("alamat tidak ditemukan atau anda tidak memiliki akses")
+		}
+		return model.Alamat{}, fmt.Errorf("failed set default address: %w", err)
+	}
+	return uc.GetAddressByID(addressID, userID)
+}
+
 func (uc *addressUsecase) DeleteAddress(addressID, userID uint) error {
 	existingAlamat, err := uc.addressRepo.FindByIDAndUserID(addressID, userID)
 	if err != nil {
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+// verifikasi toko by admin: draft -> pending_review (seller submit) -> active / draft (rejected),
+// active toko can be suspended and unsuspended
+type AdminTokoUsecase interface {
+	GetReviewQueue(pagination utils.PaginationInput, filter repository.TokoReviewFilterInput) (utils.PaginationResult, error)
+	GetTokoByID(tokoID uint) (model.Toko, error) // with dokumen
+	GetDokumen(tokoID, dokumenID uint) (model.TokoDokumen, error)
+
+	ApproveToko(actorID, tokoID uint, ipAddress string) (model.Toko, error)
+	RejectToko(actorID, tokoID uint, reason, ipAddress string) (model.Toko, error)
+	SuspendToko(actorID, tokoID uint, reason, ipAddress string) (model.Toko, error)
+	UnsuspendToko(actorID, tokoID uint, ipAddress string) (model.Toko, error)
+}
+
+type adminTokoUsecase struct {
+	tokoRepo     repository.TokoRepository
+	auditLogRepo repository.AuditLogRepository
+	indexer      ProdukIndexer
+}
+
+func NewAdminTokoUsecase(tokoRepo repository.TokoRepository, auditLogRepo repository.AuditLogRepository, indexer ProdukIndexer) AdminTokoUsecase {
+	return &adminTokoUsecase{tokoRepo, auditLogRepo, indexer}
+}
+
+func (uc *adminTokoUsecase) GetReviewQueue(pagination utils.PaginationInput, filter repository.TokoReviewFilterInput) (utils.PaginationResult, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	tokos, totalData, err := uc.tokoRepo.FindAllForReview(pagination, filter)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("failed get toko: %w", err)
+	}
+	return utils.GeneratePaginationResult(tokos, totalData, pagination.Page, pagination.Limit), nil
+}
+
+func (uc *adminTokoUsecase) findToko(tokoID uint) (model.Toko, error) {
+	toko, err := uc.tokoRepo.FindByID(tokoID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Toko{}, errors.New("toko not found")
+		}
+		return model.Toko{}, fmt.Errorf("failed get toko: %w", err)
+	}
+	return toko, nil
+}
+
+func (uc *adminTokoUsecase) GetTokoByID(tokoID uint) (model.Toko, error) {
+	toko, err := uc.findToko(tokoID)
+	if err != nil {
+		return toko, err
+	}
+	dokumen, err := uc.tokoRepo.FindDokumenByTokoID(tokoID)
+	if err != nil {
+		return toko, fmt.Errorf("failed get dokumen toko: %w", err)
+	}
+	toko.Dokumen = dokumen
+	return toko, nil
+}
+
+func (uc *adminTokoUsecase) GetDokumen(tokoID, dokumenID uint) (model.TokoDokumen, error) {
+	dokumen, err := uc.tokoRepo.FindDokumenByID(tokoID, dokumenID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dokumen, errors.New("dokumen not found")
+		}
+		return dokumen, fmt.Errorf("failed get dokumen toko: %w", err)
+	}
+	return dokumen, nil
+}
+
+// change status of toko when current status is one of from, then write audit log
+func (uc *adminTokoUsecase) changeStatus(actorID, tokoID uint, from []string, to, reason, action, ipAddress string) (model.Toko, error) {
+	toko, err := uc.findToko(tokoID)
+	if err != nil {
+		return toko, err
+	}
+
+	allowed := false
+	for _, status := range from {
+		if toko.Status == status {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return model.Toko{}, fmt.Errorf("toko with status %s can't be changed to %s", toko.Status, to)
+	}
+
+	now := time.Now()
+	previousStatus := toko.Status
+	toko.Status = to
+	toko.StatusReason = strings.TrimSpace(reason)
+	toko.ReviewedAt = &now
+	toko.ReviewedBy = &actorID
+	toko.UpdatedAtDate = now
+
+	savedToko, err := uc.tokoRepo.Update(toko)
+	if err != nil {
+		return model.Toko{}, fmt.Errorf("failed update status toko: %w", err)
+	}
+	// produk of toko shown or hidden with it
+	uc.indexer.ReindexToko(tokoID)
+
+	detail := map[string]interface{}{"from": previousStatus, "to": to}
+	if toko.StatusReason != "" {
+		detail["reason"] = toko.StatusReason
+	}
+	recordAudit(uc.auditLogRepo, actorID, action, "toko", tokoID, detail, ipAddress)
+	return savedToko, nil
+}
+
+func (uc *adminTokoUsecase) ApproveToko(actorID, tokoID uint, ipAddress string) (model.Toko, error) {
+	return uc.changeStatus(actorID, tokoID, []string{model.TokoStatusPendingReview}, model.TokoStatusActive, "", model.AuditTokoApprove, ipAddress)
+}
+
+// rejected toko back to draft, seller fix dokumen then submit again
+func (uc *adminTokoUsecase) RejectToko(actorID, tokoID uint, reason, ipAddress string) (model.Toko, error) {
+	return uc.changeStatus(actorID, tokoID, []string{model.TokoStatusPendingReview}, model.TokoStatusDraft, reason, model.AuditTokoReject, ipAddress)
+}
+
+func (uc *adminTokoUsecase) SuspendToko(actorID, tokoID uint, reason, ipAddress string) (model.Toko, error) {
+	from := []string{model.TokoStatusDraft, model.TokoStatusPendingReview, model.TokoStatusActive}
+	return uc.changeStatus(actorID, tokoID, from, model.TokoStatusSuspended, reason, model.AuditTokoSuspend, ipAddress)
+}
+
+func (uc *adminTokoUsecase) UnsuspendToko(actorID, tokoID uint, ipAddress string) (model.Toko, error) {
+	return uc.changeStatus(actorID, tokoID, []string{model.TokoStatusSuspended}, model.TokoStatusActive, "", model.AuditTokoUnsuspend, ipAddress)
+}
@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"testing"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+)
+
+type fakeAuditLogRepo struct {
+	repository.AuditLogRepository
+	logs []model.AuditLog
+}
+
+func (r *fakeAuditLogRepo) Save(log model.AuditLog) (model.AuditLog, error) {
+	r.logs = append(r.logs, log)
+	return log, nil
+}
+
+func TestAdminTokoStatusTransition(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   string
+		action   func(uc AdminTokoUsecase) (model.Toko, error)
+		want     string
+		wantFail bool
+	}{
+		{"approve pending", model.TokoStatusPendingReview, func(uc AdminTokoUsecase) (model.Toko, error) { return uc.ApproveToko(9, 1, "") }, model.TokoStatusActive, false},
+		{"approve draft", model.TokoStatusDraft, func(uc AdminTokoUsecase) (model.Toko, error) { return uc.ApproveToko(9, 1, "") }, "", true},
+		{"reject pending back to draft", model.TokoStatusPendingReview, func(uc AdminTokoUsecase) (model.Toko, error) { return uc.RejectToko(9, 1, "ktp blur", "") }, model.TokoStatusDraft, false},
+		{"reject active", model.TokoStatusActive, func(uc AdminTokoUsecase) (model.Toko, error) { return uc.RejectToko(9, 1, "x", "") }, "", true},
+		{"suspend active", model.TokoStatusActive, func(uc AdminTokoUsecase) (model.Toko, error) { return uc.SuspendToko(9, 1, "fraud", "") }, model.TokoStatusSuspended, false},
+		{"suspend suspended", model.TokoStatusSuspended, func(uc AdminTokoUsecase) (model.Toko, error) { return uc.SuspendToko(9, 1, "fraud", "") }, "", true},
+		{"unsuspend", model.TokoStatusSuspended, func(uc AdminTokoUsecase) (model.Toko, error) { return uc.UnsuspendToko(9, 1, "") }, model.TokoStatusActive, false},
+		{"unsuspend active", model.TokoStatusActive, func(uc AdminTokoUsecase) (model.Toko, error) { return uc.UnsuspendToko(9, 1, "") }, "", true},
+	}
+
+	for _, tc := range cases {
+		tokoRepo := &fakeTokoRepo{tokos: []model.Toko{{ID: 1, Status: tc.status}}}
+		auditRepo := &fakeAuditLogRepo{}
+		indexer := &fakeProdukIndexer{}
+		uc := NewAdminTokoUsecase(tokoRepo, auditRepo, indexer)
+
+		toko, err := tc.action(uc)
+		if tc.wantFail {
+			if err == nil {
+				t.Errorf("%s: expected error", tc.name)
+			}
+			if len(auditRepo.logs) != 0 || tokoRepo.tokos[0].Status != tc.status || len(indexer.tokoIDs) != 0 {
+				t.Errorf("%s: failed transition must not change toko or write audit", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		if toko.Status != tc.want || tokoRepo.tokos[0].Status != tc.want {
+			t.Errorf("%s: expected status %s, got %s", tc.name, tc.want, toko.Status)
+		}
+		if toko.ReviewedBy == nil || *toko.ReviewedBy != 9 {
+			t.Errorf("%s: reviewer not recorded", tc.name)
+		}
+		if len(auditRepo.logs) != 1 || auditRepo.logs[0].TargetID != 1 {
+			t.Errorf("%s: expected one audit log for toko 1, got %+v", tc.name, auditRepo.logs)
+		}
+		if len(indexer.tokoIDs) != 1 || indexer.tokoIDs[0] != 1 {
+			t.Errorf("%s: expected produk of toko 1 reindexed, got %v", tc.name, indexer.tokoIDs)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+var ErrAccountSuspended = errors.New("account suspended, please contact support")
+
+// target can manage role or has permission the actor dont have
+var ErrSuspendNotAllowed = errors.New("cant suspend user with role:manage or higher permission than yours")
+
+type AdminUserUsecase interface {
+	GetUsers(pagination utils.PaginationInput, filter repository.UserFilterInput) (utils.PaginationResult, error)
+	GetUserByID(userID uint) (model.User, error)
+	SuspendUser(actorID, userID uint, reason, ipAddress string) (model.User, error)
+	UnsuspendUser(actorID, userID uint, ipAddress string) (model.User, error)
+	GetAuditLogs(filter repository.AuditLogFilter, pagination utils.PaginationInput) (utils.PaginationResult, error)
+}
+
+type adminUserUsecase struct {
+	userRepo     repository.UserRepository
+	roleRepo     repository.RoleRepository
+	auditLogRepo repository.AuditLogRepository
+}
+
+func NewAdminUserUsecase(
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	auditLogRepo repository.AuditLogRepository,
+) AdminUserUsecase {
+	return &adminUserUsecase{userRepo, roleRepo, auditLogRepo}
+}
+
+// detail saved as json, failing to write audit must not cancel the action that already done
+func recordAudit(auditLogRepo repository.AuditLogRepository, actorID uint, action, targetType string, targetID uint, detail map[string]interface{}, ipAddress string) {
+	detailJSON, err := json.Marshal(detail)
+	if err != nil {
+		detailJSON = []byte("{}")
+	}
+
+	log := model.AuditLog{
+		IDActor:       actorID,
+		Action:        action,
+		TargetType:    targetType,
+		TargetID:      targetID,
+		Detail:        string(detailJSON),
+		IPAddress:     ipAddress,
+		CreatedAtDate: time.Now(),
+	}
+	if _, err := auditLogRepo.Save(log); err != nil {
+		fmt.Printf("failed save audit log %s by user %d: %v\n", action, actorID, err)
+	}
+}
+
+func (uc *adminUserUsecase) GetUsers(pagination utils.PaginationInput, filter repository.UserFilterInput) (utils.PaginationResult, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	users, totalData, err := uc.userRepo.FindAll(pagination, filter)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("failed get users: %w", err)
+	}
+
+	result := utils.GeneratePaginationResult(users, totalData, pagination.Page, pagination.Limit)
+	return result, nil
+}
+
+func (uc *adminUserUsecase) findUser(userID uint) (model.User, error) {
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.User{}, errors.New("user not found")
+		}
+		return model.User{}, fmt.Errorf("failed get user: %w", err)
+	}
+	return user, nil
+}
+
+func (uc *adminUserUsecase) GetUserByID(userID uint) (model.User, error) {
+	user, err := uc.findUser(userID)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	roles, err := uc.roleRepo.FindRolesByUserID(userID)
+	if err != nil {
+		return model.User{}, fmt.Errorf("failed get user roles: %w", err)
+	}
+	user.Roles = roles
+	return user, nil
+}
+
+func (uc *adminUserUsecase) SuspendUser(actorID, userID uint, reason, ipAddress string) (model.User, error) {
+	if actorID == userID {
+		return model.User{}, errors.New("cant suspend your own account")
+	}
+
+	user, err := uc.findUser(userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if user.SuspendedAt != nil {
+		return model.User{}, errors.New("user already suspended")
+	}
+	if err := uc.checkCanSuspend(actorID, user); err != nil {
+		return model.User{}, err
+	}
+
+	now := time.Now()
+	user.SuspendedAt = &now
+	user.SuspendedReason = strings.TrimSpace(reason)
+	user.UpdatedAtDate = now
+
+	if err := uc.userRepo.UpdateSuspension(userID, user.SuspendedAt, user.SuspendedReason, now); err != nil {
+		return model.User{}, fmt.Errorf("failed suspend user: %w", err)
+	}
+
+	recordAudit(uc.auditLogRepo, actorID, model.AuditUserSuspend, "user", userID, map[string]interface{}{"reason": user.SuspendedReason}, ipAddress)
+	return user, nil
+}
+
+// user:write is given to support too, they must not lock out admin or user ranked above them
+func (uc *adminUserUsecase) checkCanSuspend(actorID uint, target model.User) error {
+	if target.IsAdmin {
+		return ErrSuspendNotAllowed
+	}
+	targetCodes, err := uc.roleRepo.FindPermissionCodesByUserID(target.ID)
+	if err != nil {
+		return fmt.Errorf("failed get user permissions: %w", err)
+	}
+	actorCodes, err := uc.roleRepo.FindPermissionCodesByUserID(actorID)
+	if err != nil {
+		return fmt.Errorf("failed get your permissions: %w", err)
+	}
+
+	actorHas := map[string]bool{}
+	for _, code := range actorCodes {
+		actorHas[code] = true
+	}
+	for _, code := range targetCodes {
+		if code == model.PermissionRoleManage || !actorHas[code] {
+			return ErrSuspendNotAllowed
+		}
+	}
+	return nil
+}
+
+func (uc *adminUserUsecase) UnsuspendUser(actorID, userID uint, ipAddress string) (model.User, error) {
+	user, err := uc.findUser(userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if user.SuspendedAt == nil {
+		return model.User{}, errors.New("user is not suspended")
+	}
+
+	previousReason := user.SuspendedReason
+	user.SuspendedAt = nil
+	user.SuspendedReason = ""
+	user.UpdatedAtDate = time.Now()
+
+	if err := uc.userRepo.UpdateSuspension(userID, nil, "", user.UpdatedAtDate); err != nil {
+		return model.User{}, fmt.Errorf("failed unsuspend user: %w", err)
+	}
+
+	recordAudit(uc.auditLogRepo, actorID, model.AuditUserUnsuspend, "user", userID, map[string]interface{}{"previous_reason": previousReason}, ipAddress)
+	return user, nil
+}
+
+func (uc *adminUserUsecase) GetAuditLogs(filter repository.AuditLogFilter, pagination utils.PaginationInput) (utils.PaginationResult, error) {
+	logs, totalData, err := uc.auditLogRepo.FindAll(filter, pagination)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("failed get audit logs: %w", err)
+	}
+
+	result := utils.GeneratePaginationResult(logs, totalData, pagination.Page, pagination.Limit)
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"rakamin-evermos/model"
+)
+
+func TestSuspendUserRank(t *testing.T) {
+	supportCodes := []string{model.PermissionUserRead, model.PermissionUserWrite, model.PermissionLoginAudit, model.PermissionLoginUnlock, model.PermissionTrxRead}
+	adminCodes := []string{}
+	for _, permission := range defaultPermissions {
+		adminCodes = append(adminCodes, permission.Code)
+	}
+
+	cases := []struct {
+		name        string
+		actorID     uint
+		targetID    uint
+		wantAllowed bool
+	}{
+		{"support suspend customer", 1, 4, true},
+		{"support suspend admin", 1, 2, false},
+		{"support suspend catalog manager", 1, 3, false},
+		{"support suspend other support", 1, 5, true},
+		{"admin suspend catalog manager", 2, 3, true},
+		{"admin suspend role manager", 2, 6, false},
+	}
+
+	for _, tc := range cases {
+		userRepo := &fakeUserRepo{}
+		userRepo.Save(model.User{Email: "support@example.com", NoTelp: "1"})
+		userRepo.Save(model.User{Email: "admin@example.com", NoTelp: "2", IsAdmin: true})
+		userRepo.Save(model.User{Email: "catalog@example.com", NoTelp: "3"})
+		userRepo.Save(model.User{Email: "customer@example.com", NoTelp: "4"})
+		userRepo.Save(model.User{Email: "support2@example.com", NoTelp: "5"})
+		userRepo.Save(model.User{Email: "roles@example.com", NoTelp: "6"})
+		roleRepo := &fakeRoleRepo{userCodes: map[uint][]string{
+			1: supportCodes,
+			2: adminCodes,
+			3: {model.PermissionCategoryRead, model.PermissionCategoryWrite},
+			5: supportCodes,
+			6: {model.PermissionRoleManage},
+		}}
+		auditRepo := &fakeAuditLogRepo{}
+		uc := NewAdminUserUsecase(userRepo, roleRepo, auditRepo)
+
+		_, err := uc.SuspendUser(tc.actorID, tc.targetID, "spam", "")
+		suspended := userRepo.users[tc.targetID-1].SuspendedAt != nil
+		if tc.wantAllowed {
+			if err != nil || !suspended || len(auditRepo.logs) != 1 {
+				t.Errorf("%s: expected suspended, got %v", tc.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrSuspendNotAllowed) || suspended || len(auditRepo.logs) != 0 {
+			t.Errorf("%s: expected ErrSuspendNotAllowed, got %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+// last_used_at only written if older than this, avoid db write every request
+const apiKeyLastUsedInterval = time.Minute
+
+type APIKeyUsecase interface {
+	CreateAPIKey(userID uint, name string, scopes []string, expiresAt *time.Time) (model.APIKey, string, error) // return raw key (shown once)
+	GetAPIKeys(userID uint) ([]model.APIKey, error)
+	RevokeAPIKey(userID, apiKeyID uint) error
+
+	// for AuthMiddleware
+	AuthenticateAPIKey(rawKey string) (uint, []string, error)
+}
+
+type apiKeyUsecase struct {
+	apiKeyRepo repository.APIKeyRepository
+}
+
+func NewAPIKeyUsecase(apiKeyRepo repository.APIKeyRepository) APIKeyUsecase {
+	return &apiKeyUsecase{apiKeyRepo}
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		valid := false
+		for _, allowed := range model.APIKeyScopes {
+			if scope == allowed {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("scope %q not valid, allowed: %s", scope, strings.Join(model.APIKeyScopes, ", "))
+		}
+	}
+	return nil
+}
+
+func (uc *apiKeyUsecase) CreateAPIKey(userID uint, name string, scopes []string, expiresAt *time.Time) (model.APIKey, string, error) {
+	scopes = uniqueStrings(scopes)
+	if err := validateScopes(scopes); err != nil {
+		return model.APIKey{}, "", err
+	}
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return model.APIKey{}, "", errors.New("expires_at must be in the future")
+	}
+
+	rawKey, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return model.APIKey{}, "", fmt.Errorf("failed generate api key: %w", err)
+	}
+
+	now := time.Now()
+	apiKey := model.APIKey{
+		IDUser:        userID,
+		Name:          name,
+		Prefix:        prefix,
+		KeyHash:       utils.HashAPIKey(rawKey),
+		Scopes:        strings.Join(scopes, ","),
+		ExpiresAt:     expiresAt,
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	}
+
+	savedAPIKey, err := uc.apiKeyRepo.Save(apiKey)
+	if err != nil {
+		return savedAPIKey, "", fmt.Errorf("failed save api key: %w", err)
+	}
+	return savedAPIKey, rawKey, nil
+}
+
+func (uc *apiKeyUsecase) GetAPIKeys(userID uint) ([]model.APIKey, error) {
+	apiKeys, err := uc.apiKeyRepo.FindAllByUserID(userID)
+	if err != nil {
+		return apiKeys, fmt.Errorf("failed get api keys: %w", err)
+	}
+	return apiKeys, nil
+}
+
+// revoked key is kept for history, just cant be used anymore
+func (uc *apiKeyUsecase) RevokeAPIKey(userID, apiKeyID uint) error {
+	apiKey, err := uc.apiKeyRepo.FindByIDAndUserID(apiKeyID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("api key not found or you dont have access")
+		}
+		return fmt.Errorf("failed verify api key: %w", err)
+	}
+	if apiKey.RevokedAt != nil {
+		return errors.New("api key already revoked")
+	}
+
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	apiKey.UpdatedAtDate = now
+
+	if _, err := uc.apiKeyRepo.Update(apiKey); err != nil {
+		return fmt.Errorf("failed revoke api key: %w", err)
+	}
+	return nil
+}
+
+func (uc *apiKeyUsecase) AuthenticateAPIKey(rawKey string) (uint, []string, error) {
+	apiKey, err := uc.apiKeyRepo.FindByHash(utils.HashAPIKey(rawKey))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil, errors.New("api key not valid")
+		}
+		return 0, nil, fmt.Errorf("failed verify api key: %w", err)
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil {
+		return 0, nil, errors.New("api key has been revoked")
+	}
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now) {
+		return 0, nil, errors.New("api key has expired")
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedInterval {
+		if err := uc.apiKeyRepo.UpdateLastUsed(apiKey.ID, now); err != nil {
+			fmt.Printf("failed update last used api key %d: %v\n", apiKey.ID, err)
+		}
+	}
+
+	return apiKey.IDUser, apiKey.ScopeList(), nil
+}
@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+// user must finish login at the provider within this time
+const oidcLoginStateTTL = 10 * time.Minute
+
+// provider not trusted to link by email, user must login to existing account first then link
+var ErrOIDCEmailRegistered = errors.New("email already registered, login with password first to link this provider")
+
+func (uc *authUsecase) getOIDCProvider(name string) (*utils.OIDCProvider, error) {
+	provider, ok := uc.oidcProviders[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("login provider %q not supported", name)
+	}
+	return provider, nil
+}
+
+// state, nonce and PKCE verifier saved server side, only state and challenge go to the browser
+func (uc *authUsecase) StartOIDCLogin(providerName string) (string, error) {
+	provider, err := uc.getOIDCProvider(providerName)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := utils.RandomURLString(32)
+	if err != nil {
+		return "", fmt.Errorf("failed create state: %w", err)
+	}
+	nonce, err := utils.RandomURLString(32)
+	if err != nil {
+		return "", fmt.Errorf("failed create nonce: %w", err)
+	}
+	verifier, challenge, err := utils.GeneratePKCE()
+	if err != nil {
+		return "", fmt.Errorf("failed create pkce: %w", err)
+	}
+
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, challenge)
+	if err != nil {
+		return "", fmt.Errorf("failed contact login provider: %w", err)
+	}
+
+	now := time.Now()
+	if err := uc.oidcRepo.DeleteExpiredLoginStates(now); err != nil {
+		fmt.Printf("failed clean expired oidc state: %v\n", err)
+	}
+	loginState := model.OIDCLoginState{
+		State:         state,
+		Provider:      provider.Name,
+		CodeVerifier:  verifier,
+		Nonce:         nonce,
+		ExpiresAt:     now.Add(oidcLoginStateTTL),
+		CreatedAtDate: now,
+	}
+	if err := uc.oidcRepo.SaveLoginState(loginState); err != nil {
+		return "", fmt.Errorf("failed save login state: %w", err)
+	}
+
+	return authURL, nil
+}
+
+// callback from provider: check state, exchange code, verify id_token, then login like password login
+func (uc *authUsecase) FinishOIDCLogin(providerName, code, state, ipAddress string) (LoginResult, error) {
+	provider, err := uc.getOIDCProvider(providerName)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	loginState, err := uc.oidcRepo.ConsumeLoginState(state)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return LoginResult{}, errors.New("login state not valid or already used, please login again")
+		}
+		return LoginResult{}, fmt.Errorf("failed get login state: %w", err)
+	}
+	if loginState.Provider != provider.Name || time.Now().After(loginState.ExpiresAt) {
+		return LoginResult{}, errors.New("login state not valid or expired, please login again")
+	}
+
+	ctx := context.Background()
+	rawIDToken, err := provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("failed exchange code: %w", err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, loginState.Nonce)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	user, err := uc.findOrCreateOIDCUser(provider, claims)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	if err := uc.loginGuard.Check(user.Email, ipAddress); err != nil {
+		var lockedErr *LoginLockedError
+		if errors.As(err, &lockedErr) {
+			uc.recordAttempt(&user.ID, user.Email, ipAddress, false, "locked")
+		}
+		return LoginResult{}, err
+	}
+
+	return uc.beginSession(user, ipAddress)
+}
+
+// linked identity first, then existing user with same verified email (only when provider LinkByEmail), else create new user + toko
+func (uc *authUsecase) findOrCreateOIDCUser(provider *utils.OIDCProvider, claims utils.OIDCClaims) (model.User, error) {
+	providerName := provider.Name
+	identity, err := uc.oidcRepo.FindIdentity(providerName, claims.Subject)
+	if err == nil {
+		user, err := uc.userRepo.FindByID(identity.IDUser)
+		if err != nil {
+			return model.User{}, fmt.Errorf("failed get linked user: %w", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.User{}, fmt.Errorf("failed get identity: %w", err)
+	}
+
+	// unverified email could be anyone's email, never link or create with it
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" || !claims.EmailVerified {
+		return model.User{}, errors.New("login provider did not return a verified email")
+	}
+
+	now := time.Now()
+	user, err := uc.userRepo.FindByEmail(email)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.User{}, fmt.Errorf("failed get user: %w", err)
+		}
+		user, err = uc.createOIDCUser(providerName, claims, email, now)
+		if err != nil {
+			return model.User{}, err
+		}
+	} else if !provider.LinkByEmail {
+		// any provider can claim any email, auto link would hand over the account
+		return model.User{}, ErrOIDCEmailRegistered
+	}
+
+	_, err = uc.oidcRepo.SaveIdentity(model.UserIdentity{
+		IDUser:        user.ID,
+		Provider:      providerName,
+		Subject:       claims.Subject,
+		Email:         email,
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	})
+	if err != nil {
+		return model.User{}, fmt.Errorf("failed link identity: %w", err)
+	}
+	return user, nil
+}
+
+func (uc *authUsecase) createOIDCUser(providerName string, claims utils.OIDCClaims, email string, now time.Time) (model.User, error) {
+	// random password, user can only login with provider until password is set
+	randomPassword, err := utils.RandomURLString(32)
+	if err != nil {
+		return model.User{}, fmt.Errorf("failed create password: %w", err)
+	}
+	hashedPassword, err := utils.HashPassword(randomPassword)
+	if err != nil {
+		return model.User{}, fmt.Errorf("failed encrypt pswrd: %w", err)
+	}
+
+	nama := claims.Name
+	if nama == "" {
+		nama = strings.Split(email, "@")[0]
+	}
+
+	// no_telp is unique, placeholder until user update profile
+	noTelp := fmt.Sprintf("%s:%s", providerName, claims.Subject)
+	if len(noTelp) > 255 {
+		noTelp = noTelp[:255]
+	}
+
+	savedUser, err := uc.userRepo.Save(model.User{
+		Nama:          nama,
+		KataSandi:     hashedPassword,
+		NoTelp:        noTelp,
+		Email:         email,
+		IsAdmin:       false,
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	})
+	if err != nil {
+		return model.User{}, fmt.Errorf("failed save user: %w", err)
+	}
+
+	uc.createDefaultToko(savedUser, now)
+	return savedUser, nil
+}
@@ -0,0 +1,383 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+	"rakamin-evermos/utils/oidcmock"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+)
+
+// fake repos, only what auth usecase use
+type fakeUserRepo struct {
+	users []model.User
+}
+
+func (r *fakeUserRepo) Save(user model.User) (model.User, error) {
+	for _, existing := range r.users {
+		if existing.Email == user.Email || existing.NoTelp == user.NoTelp {
+			return user, errors.New("duplicate entry")
+		}
+	}
+	user.ID = uint(len(r.users) + 1)
+	r.users = append(r.users, user)
+	return user, nil
+}
+
+func (r *fakeUserRepo) FindByEmail(email string) (model.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return model.User{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepo) FindByID(userID uint) (model.User, error) {
+	for _, user := range r.users {
+		if user.ID == userID {
+			return user, nil
+		}
+	}
+	return model.User{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepo) Update(user model.User) (model.User, error) {
+	r.users[user.ID-1] = user
+	return user, nil
+}
+
+func (r *fakeUserRepo) UpdateSuspension(userID uint, suspendedAt *time.Time, reason string, now time.Time) error {
+	r.users[userID-1].SuspendedAt = suspendedAt
+	r.users[userID-1].SuspendedReason = reason
+	return nil
+}
+
+func (r *fakeUserRepo) FindAll(pagination utils.PaginationInput, filter repository.UserFilterInput) ([]model.User, int64, error) {
+	return r.users, int64(len(r.users)), nil
+}
+
+func (r *fakeUserRepo) FindStatusByID(userID uint) (model.User, error) {
+	return r.FindByID(userID)
+}
+
+type fakeTokoRepo struct {
+	repository.TokoRepository
+	tokos []model.Toko
+}
+
+func (r *fakeTokoRepo) Save(toko model.Toko) (model.Toko, error) {
+	toko.ID = uint(len(r.tokos) + 1)
+	r.tokos = append(r.tokos, toko)
+	return toko, nil
+}
+
+func (r *fakeTokoRepo) FindByUserID(userID uint) (model.Toko, error) {
+	for _, toko := range r.tokos {
+		if toko.IDUser == userID {
+			return toko, nil
+		}
+	}
+	return model.Toko{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeTokoRepo) FindByID(tokoID uint) (model.Toko, error) {
+	for _, toko := range r.tokos {
+		if toko.ID == tokoID {
+			return toko, nil
+		}
+	}
+	return model.Toko{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeTokoRepo) Update(toko model.Toko) (model.Toko, error) {
+	for i := range r.tokos {
+		if r.tokos[i].ID == toko.ID {
+			r.tokos[i] = toko
+		}
+	}
+	return toko, nil
+}
+
+func (r *fakeTokoRepo) SlugExists(slug string, exceptTokoID uint) (bool, error) {
+	for _, toko := range r.tokos {
+		if toko.SlugValue() == slug && toko.ID != exceptTokoID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeTwoFactorRepo struct {
+	settings map[uint]model.UserTwoFactor
+}
+
+func (r *fakeTwoFactorRepo) FindByUserID(userID uint) (model.UserTwoFactor, error) {
+	setting, ok := r.settings[userID]
+	if !ok {
+		return model.UserTwoFactor{}, gorm.ErrRecordNotFound
+	}
+	return setting, nil
+}
+
+func (r *fakeTwoFactorRepo) Save(twoFactor model.UserTwoFactor) (model.UserTwoFactor, error) {
+	r.settings[twoFactor.IDUser] = twoFactor
+	return twoFactor, nil
+}
+
+func (r *fakeTwoFactorRepo) DeleteByUserID(userID uint) error {
+	delete(r.settings, userID)
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) UseTOTPStep(twoFactorID uint, step int64, now time.Time) (bool, error) {
+	for userID, setting := range r.settings {
+		if setting.ID == twoFactorID && setting.LastUsedStep < step {
+			setting.LastUsedStep = step
+			r.settings[userID] = setting
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeTwoFactorRepo) ReplaceRecoveryCodes(userID uint, codes []model.RecoveryCode) error {
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) FindUnusedRecoveryCodes(userID uint) ([]model.RecoveryCode, error) {
+	return nil, nil
+}
+
+func (r *fakeTwoFactorRepo) MarkRecoveryCodeUsed(codeID uint) error {
+	return nil
+}
+
+type oidcTestEnv struct {
+	uc        AuthUsecase
+	idp       *oidcmock.Server
+	users     *fakeUserRepo
+	tokos     *fakeTokoRepo
+	twoFactor *fakeTwoFactorRepo
+	provider  *utils.OIDCProvider
+}
+
+func newOIDCTestEnv(t *testing.T) *oidcTestEnv {
+	t.Helper()
+	utils.SetJWTKeySet(&utils.JWTKeySet{HMACSecret: []byte("test-secret"), Issuer: "test", Audience: "test-api"})
+
+	idp, testServer, err := oidcmock.NewTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(testServer.Close)
+
+	redirectURL := "http://localhost:8080/api/v1/auth/oidc/mock/callback"
+	idp.AddClient("evermos", "client-secret", redirectURL)
+	providers := map[string]*utils.OIDCProvider{
+		"mock": {
+			Name:         "mock",
+			Issuer:       idp.Issuer,
+			ClientID:     "evermos",
+			ClientSecret: "client-secret",
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+	}
+
+	loginAttemptRepo := repository.NewMemoryLoginAttemptRepository()
+	env := &oidcTestEnv{
+		idp:       idp,
+		users:     &fakeUserRepo{},
+		tokos:     &fakeTokoRepo{},
+		twoFactor: &fakeTwoFactorRepo{settings: map[uint]model.UserTwoFactor{}},
+		provider:  providers["mock"],
+	}
+	env.uc = NewAuthUsecase(
+		env.users,
+		env.tokos,
+		loginAttemptRepo,
+		env.twoFactor,
+		NewLoginGuard(loginAttemptRepo, DefaultLoginGuardConfig()),
+		repository.NewMemoryOIDCRepository(),
+		providers,
+	)
+	return env
+}
+
+// start login, let mock provider approve it, return code and state from redirect
+func (env *oidcTestEnv) authorize(t *testing.T) (string, string) {
+	t.Helper()
+	authURL, err := env.uc.StartOIDCLogin("mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(authURL, "code_challenge_method=S256") {
+		t.Fatalf("authorization url missing PKCE: %s", authURL)
+	}
+
+	code, state, err := oidcmock.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, state
+}
+
+func TestOIDCLoginCreatesUserAndToko(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	env.idp.SetUser(oidcmock.User{Subject: "sub-1", Email: "Budi@Example.com", EmailVerified: true, Name: "Budi"})
+
+	code, state := env.authorize(t)
+	result, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := utils.ParseAccessToken(result.Token)
+	if err != nil {
+		t.Fatalf("token not valid: %v", err)
+	}
+	if len(env.users.users) != 1 || env.users.users[0].Email != "budi@example.com" || claims.UserID != env.users.users[0].ID {
+		t.Fatalf("unexpected users: %+v", env.users.users)
+	}
+	if len(env.tokos.tokos) != 1 || env.tokos.tokos[0].NamaToko != "Budi's Toko" {
+		t.Fatalf("unexpected tokos: %+v", env.tokos.tokos)
+	}
+
+	// second login with same subject use the linked user
+	code, state = env.authorize(t)
+	if _, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.users.users) != 1 || len(env.tokos.tokos) != 1 {
+		t.Fatalf("second login should not create user, got %d users", len(env.users.users))
+	}
+}
+
+func TestOIDCLoginLinksExistingUserByVerifiedEmail(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	env.provider.LinkByEmail = true
+	existing, _ := env.users.Save(model.User{Nama: "Sari", Email: "sari@example.com", NoTelp: "081234567890"})
+
+	env.idp.SetUser(oidcmock.User{Subject: "sub-2", Email: "sari@example.com", EmailVerified: true})
+	code, state := env.authorize(t)
+	result, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, _ := utils.ParseAccessToken(result.Token)
+	if claims.UserID != existing.ID || len(env.users.users) != 1 {
+		t.Fatalf("expected login as existing user %d, got %d", existing.ID, claims.UserID)
+	}
+}
+
+func TestOIDCLoginNotLinkByEmailByDefault(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	env.users.Save(model.User{Nama: "Sari", Email: "sari@example.com", NoTelp: "081234567890"})
+
+	env.idp.SetUser(oidcmock.User{Subject: "sub-5", Email: "sari@example.com", EmailVerified: true})
+	code, state := env.authorize(t)
+	if _, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1"); !errors.Is(err, ErrOIDCEmailRegistered) {
+		t.Fatalf("expected ErrOIDCEmailRegistered, got %v", err)
+	}
+	if len(env.users.users) != 1 {
+		t.Fatalf("no user should be created, got %d users", len(env.users.users))
+	}
+}
+
+func TestOIDCLoginRejectsUnverifiedEmail(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	env.users.Save(model.User{Nama: "Sari", Email: "sari@example.com", NoTelp: "081234567890"})
+
+	env.idp.SetUser(oidcmock.User{Subject: "attacker", Email: "sari@example.com", EmailVerified: false})
+	code, state := env.authorize(t)
+	if _, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1"); err == nil {
+		t.Fatal("unverified email must not be linked")
+	}
+}
+
+func TestOIDCLoginRequiresTwoFactorWhenEnabled(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	env.provider.LinkByEmail = true
+	user, _ := env.users.Save(model.User{Nama: "Sari", Email: "sari@example.com", NoTelp: "081234567890"})
+	env.twoFactor.settings[user.ID] = model.UserTwoFactor{IDUser: user.ID, Enabled: true}
+
+	env.idp.SetUser(oidcmock.User{Subject: "sub-3", Email: "sari@example.com", EmailVerified: true})
+	code, state := env.authorize(t)
+	result, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.TwoFactorRequired || result.Token != "" || result.ChallengeToken == "" {
+		t.Fatalf("expected 2FA challenge, got %+v", result)
+	}
+}
+
+func TestOIDCLoginRejectsSuspendedUser(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	env.provider.LinkByEmail = true
+	user, _ := env.users.Save(model.User{Nama: "Sari", Email: "sari@example.com", NoTelp: "081234567890"})
+	suspendedAt := time.Now()
+	user.SuspendedAt = &suspendedAt
+	env.users.Update(user)
+
+	env.idp.SetUser(oidcmock.User{Subject: "sub-4", Email: "sari@example.com", EmailVerified: true})
+	code, state := env.authorize(t)
+	if _, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1"); !errors.Is(err, ErrAccountSuspended) {
+		t.Fatalf("expected ErrAccountSuspended, got %v", err)
+	}
+}
+
+func TestOIDCLoginStateIsSingleUse(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	code, state := env.authorize(t)
+	if _, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1"); err == nil {
+		t.Fatal("replayed callback must fail")
+	}
+	if _, err := env.uc.FinishOIDCLogin("mock", code, "unknown-state", "10.0.0.1"); err == nil {
+		t.Fatal("unknown state must fail")
+	}
+}
+
+func TestOIDCLoginRejectsBadIDToken(t *testing.T) {
+	cases := map[string]func(claims jwt.MapClaims){
+		"wrong audience": func(claims jwt.MapClaims) { claims["aud"] = "other-client" },
+		"wrong issuer":   func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" },
+		"wrong nonce":    func(claims jwt.MapClaims) { claims["nonce"] = "other" },
+		"expired":        func(claims jwt.MapClaims) { claims["exp"] = 1 },
+	}
+
+	for name, hook := range cases {
+		t.Run(name, func(t *testing.T) {
+			env := newOIDCTestEnv(t)
+			env.idp.IDTokenHook = hook
+
+			code, state := env.authorize(t)
+			if _, err := env.uc.FinishOIDCLogin("mock", code, state, "10.0.0.1"); err == nil {
+				t.Fatal("expected id_token rejected")
+			}
+			if len(env.users.users) != 0 {
+				t.Fatal("no user should be created")
+			}
+		})
+	}
+}
+
+func TestOIDCLoginUnknownProvider(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	if _, err := env.uc.StartOIDCLogin("unknown"); err == nil {
+		t.Fatal("unknown provider must fail")
+	}
+}
@@ -9,20 +9,51 @@ import (
 	"rakamin-evermos/model"
 	"rakamin-evermos/repository"
 	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
 )
 
+// if 2FA enabled, Token is empty and ChallengeToken must be sent to VerifyTwoFactor
+type LoginResult struct {
+	Token             string `json:"token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
 type AuthUsecase interface {
 	Register(user model.User) (model.User, error)
-	Login(email, password string) (string, error) // return JWT token
+	Login(email, password, ipAddress string) (LoginResult, error)
+	VerifyTwoFactor(challengeToken, code, ipAddress string) (string, error) // return JWT token
+
+	// OIDC social login (authorization code + PKCE)
+	StartOIDCLogin(provider string) (string, error) // return authorization url
+	FinishOIDCLogin(provider, code, state, ipAddress string) (LoginResult, error)
+
+	// admin only
+	GetLoginAttempts(email string, pagination utils.PaginationInput) (utils.PaginationResult, error)
+	UnlockLogin(email, ipAddress string) error
 }
 
 type authUsecase struct {
-	userRepo repository.UserRepository
-	tokoRepo repository.TokoRepository 
+	userRepo         repository.UserRepository
+	tokoRepo         repository.TokoRepository 
+	loginAttemptRepo repository.LoginAttemptRepository
+	twoFactorRepo    repository.TwoFactorRepository
+	loginGuard       LoginGuard
+	oidcRepo         repository.OIDCRepository
+	oidcProviders    map[string]*utils.OIDCProvider
 }
 
-func NewAuthUsecase(userRepo repository.UserRepository, tokoRepo repository.TokoRepository) AuthUsecase {
-	return &authUsecase{userRepo, tokoRepo}
+func NewAuthUsecase(
+	userRepo repository.UserRepository,
+	tokoRepo repository.TokoRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	twoFactorRepo repository.TwoFactorRepository,
+	loginGuard LoginGuard,
+	oidcRepo repository.OIDCRepository,
+	oidcProviders map[string]*utils.OIDCProvider,
+) AuthUsecase {
+	return &authUsecase{userRepo, tokoRepo, loginAttemptRepo, twoFactorRepo, loginGuard, oidcRepo, oidcProviders}
 }
 
 func (uc *authUsecase) Register(user model.User) (model.User, error) {
@@ -50,30 +81,149 @@ func (uc *authUsecase) Register(user model.User) (model.User, error) {
 	}
 
 	// create toko after create user
+	uc.createDefaultToko(savedUser, now)
+
+	return savedUser, nil
+}
+
+// every user get one toko, also used by OIDC sign up
+func (uc *authUsecase) createDefaultToko(user model.User, now time.Time) {
 	newToko := model.Toko{
-		IDUser:        savedUser.ID,
-		NamaToko:      fmt.Sprintf("%s's Toko", savedUser.Nama), // toko default name
-        UrlFoto:       "",
-        CreatedAtDate: now,
-        UpdatedAtDate: now,
+		IDUser:        user.ID,
+		NamaToko:      fmt.Sprintf("%s's Toko", user.Nama), // toko default name
+		UrlFoto:       "",
+		Status:        model.TokoStatusDraft, // must be verified by admin before selling
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
 	}
-	_, err = uc.tokoRepo.Save(newToko)
+
+	// NULL slug (not blocked by unique index) filled later by BackfillSlugs
+	slug, err := uniqueTokoSlug(uc.tokoRepo, newToko.NamaToko, 0)
 	if err != nil {
-		fmt.Printf("failed make toko for user %d: %v\n", savedUser.ID, err)
+		fmt.Printf("failed make slug toko for user %d: %v\n", user.ID, err)
+	} else {
+		newToko.Slug = &slug
 	}
 
-	return savedUser, nil
+	_, err = saveTokoWithSlug(uc.tokoRepo, newToko, uc.tokoRepo.Save)
+	if err != nil {
+		fmt.Printf("failed make toko for user %d: %v\n", user.ID, err)
+	}
 }
 
-func (uc *authUsecase) Login(email, password string) (string, error) {
+func (uc *authUsecase) Login(email, password, ipAddress string) (LoginResult, error) {
+	// stop here if account or ip still locked, else attempt counted as failed until password is correct
+	reservation, err := uc.loginGuard.Reserve(email, ipAddress)
+	if err != nil {
+		var lockedErr *LoginLockedError
+		if errors.As(err, &lockedErr) {
+			uc.recordAttempt(nil, email, ipAddress, false, "locked")
+		}
+		return LoginResult{}, err
+	}
+
 	user, err := uc.userRepo.FindByEmail(email)
 	if err != nil {
-		return "", errors.New("password or email incorrect")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return LoginResult{}, fmt.Errorf("failed get user: %w", err)
+		}
+		uc.recordAttempt(nil, email, ipAddress, false, "unknown_email")
+		return LoginResult{}, errors.New("password or email incorrect")
 	}
 
 	if !utils.CheckPasswordHash(password, user.KataSandi) {
-		return "", errors.New("password or email incorrect")
+		uc.recordAttempt(&user.ID, email, ipAddress, false, "wrong_password")
+		return LoginResult{}, errors.New("password or email incorrect")
+	}
+	if err := uc.loginGuard.Release(reservation); err != nil {
+		return LoginResult{}, err
+	}
+
+	// password ok, but second factor still needed
+	return uc.beginSession(user, ipAddress)
+}
+
+// first factor passed (password or OIDC), ask 2FA code if enabled
+func (uc *authUsecase) beginSession(user model.User, ipAddress string) (LoginResult, error) {
+	if err := uc.rejectSuspended(user, ipAddress); err != nil {
+		return LoginResult{}, err
+	}
+
+	twoFactor, err := uc.twoFactorRepo.FindByUserID(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return LoginResult{}, fmt.Errorf("failed get 2FA setting: %w", err)
+	}
+	if twoFactor.Enabled {
+		challengeToken, err := utils.GenerateChallengeToken(user.ID)
+		if err != nil {
+			return LoginResult{}, fmt.Errorf("failed create challenge token: %w", err)
+		}
+		uc.recordAttempt(&user.ID, user.Email, ipAddress, false, "2fa_required")
+		return LoginResult{TwoFactorRequired: true, ChallengeToken: challengeToken}, nil
+	}
+
+	token, err := uc.completeLogin(user, ipAddress)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	return LoginResult{Token: token}, nil
+}
+
+// second step login, code can be totp code or recovery code
+func (uc *authUsecase) VerifyTwoFactor(challengeToken, code, ipAddress string) (string, error) {
+	userID, err := utils.ValidateChallengeToken(challengeToken)
+	if err != nil {
+		return "", errors.New("challenge token not valid or expired, please login again")
+	}
+
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil {
+		return "", errors.New("challenge token not valid or expired, please login again")
+	}
+
+	reservation, err := uc.loginGuard.Reserve(user.Email, ipAddress)
+	if err != nil {
+		var lockedErr *LoginLockedError
+		if errors.As(err, &lockedErr) {
+			uc.recordAttempt(&user.ID, user.Email, ipAddress, false, "locked")
+		}
+		return "", err
+	}
+
+	twoFactor, err := uc.twoFactorRepo.FindByUserID(user.ID)
+	if err != nil || !twoFactor.Enabled {
+		return "", errors.New("2FA not enabled for this account")
+	}
+
+	if err := verifySecondFactor(uc.twoFactorRepo, &twoFactor, code); err != nil {
+		uc.recordAttempt(&user.ID, user.Email, ipAddress, false, "wrong_2fa_code")
+		return "", err
+	}
+	if err := uc.loginGuard.Release(reservation); err != nil {
+		return "", err
+	}
+
+	return uc.completeLogin(user, ipAddress)
+}
+
+// checked after credential is correct, so suspend status not leaked to wrong password
+func (uc *authUsecase) rejectSuspended(user model.User, ipAddress string) error {
+	if user.SuspendedAt == nil {
+		return nil
+	}
+	uc.recordAttempt(&user.ID, user.Email, ipAddress, false, "suspended")
+	return ErrAccountSuspended
+}
+
+func (uc *authUsecase) completeLogin(user model.User, ipAddress string) (string, error) {
+	if err := uc.rejectSuspended(user, ipAddress); err != nil {
+		return "", err
+	}
+
+	if err := uc.loginGuard.RecordSuccess(user.Email); err != nil {
+		return "", err
 	}
+	uc.recordAttempt(&user.ID, user.Email, ipAddress, true, "success")
 
 	token, err := utils.GenerateToken(user.ID, user.IsAdmin)
 	if err != nil {
@@ -81,4 +231,36 @@ func (uc *authUsecase) Login(email, password string) (string, error) {
 	}
 
 	return token, nil
-}
\ No newline at end of file
+}
+
+// audit trail, failing to write it must not block login
+func (uc *authUsecase) recordAttempt(userID *uint, email, ipAddress string, success bool, reason string) {
+	attempt := model.LoginAttempt{
+		IDUser:        userID,
+		Email:         email,
+		IPAddress:     ipAddress,
+		Success:       success,
+		Reason:        reason,
+		CreatedAtDate: time.Now(),
+	}
+	if _, err := uc.loginAttemptRepo.SaveAttempt(attempt); err != nil {
+		fmt.Printf("failed save login attempt for %s: %v\n", email, err)
+	}
+}
+
+func (uc *authUsecase) GetLoginAttempts(email string, pagination utils.PaginationInput) (utils.PaginationResult, error) {
+	attempts, totalData, err := uc.loginAttemptRepo.FindAttempts(email, pagination)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("failed get login attempts: %w", err)
+	}
+
+	result := utils.GeneratePaginationResult(attempts, totalData, pagination.Page, pagination.Limit)
+	return result, nil
+}
+
+func (uc *authUsecase) UnlockLogin(email, ipAddress string) error {
+	if email == "" && ipAddress == "" {
+		return errors.New("email or ip_address is required")
+	}
+	return uc.loginGuard.Unlock(email, ipAddress)
+}
@@ -3,10 +3,14 @@ package usecase
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"rakamin-evermos/model"
 	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
 
 	"gorm.io/gorm"
 )
@@ -16,20 +20,161 @@ type CategoryUsecase interface {
 	GetAllCategories() ([]model.Category, error)
 	GetCategoryByID(categoryID uint) (model.Category, error)
 	UpdateCategory(categoryID uint, input model.Category) (model.Category, error)
-	DeleteCategory(categoryID uint) error
+	DeleteCategory(categoryID, targetID uint) error // targetID 0 = refuse when kategori still used
+	MergeCategory(categoryID, targetID uint) (CategoryMergeResult, error)
+
+	// public, idOrSlug is ID category or slug
+	GetCategoryTree() ([]*CategoryNode, error)
+	GetCategoryNode(idOrSlug string) (*CategoryNode, error)
+
+	// atribut schema, GetAtribut include atribut of parent kategori
+	GetAtribut(categoryID uint) ([]model.CategoryAtribut, error)
+	CreateAtribut(categoryID uint, input model.CategoryAtribut) (model.CategoryAtribut, error)
+	UpdateAtribut(categoryID, atributID uint, input model.CategoryAtribut) (model.CategoryAtribut, error)
+	DeleteAtribut(categoryID, atributID uint) error
+
+	BackfillSlugs() error
+}
+
+// category with sub category, used by public tree
+type CategoryNode struct {
+	ID           uint            `json:"id"`
+	IDParent     *uint           `json:"id_parent"`
+	NamaCategory string          `json:"nama_category"`
+	Slug         string          `json:"slug"`
+	Urutan       int             `json:"urutan"`
+	Children     []*CategoryNode `json:"children"`
+}
+
+// result of merge, produk and sub kategori now under target
+type CategoryMergeResult struct {
+	Target            model.Category `json:"target"`
+	JumlahProduk      int64          `json:"jumlah_produk"`
+	JumlahSubKategori int64          `json:"jumlah_sub_kategori"`
 }
 
+// parent not found or parent make a loop, handler return 400
+var ErrCategoryNotValid = errors.New("kategori not valid")
+
+var ErrCategoryNotFound = errors.New("kategori not found")
+
+var atributKodePattern = regexp.MustCompile(`^[a-z0-9_]{1,100}$`)
+
+// delete without target while produk or sub kategori still use it, handler return 409
+var ErrCategoryInUse = errors.New("kategori still in use")
+
 type categoryUsecase struct {
 	categoryRepo repository.CategoryRepository
+	indexer      ProdukIndexer
 }
 
-func NewCategoryUsecase(categoryRepo repository.CategoryRepository) CategoryUsecase {
-	return &categoryUsecase{categoryRepo}
+func NewCategoryUsecase(categoryRepo repository.CategoryRepository, indexer ProdukIndexer) CategoryUsecase {
+	return &categoryUsecase{categoryRepo, indexer}
 }
 
+// categories must already ordered by urutan, order kept inside every level.
+// category with unknown parent shown as root so it's not lost
+func BuildCategoryTree(categories []model.Category) []*CategoryNode {
+	nodes := make(map[uint]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{
+			ID:           category.ID,
+			IDParent:     category.IDParent,
+			NamaCategory: category.NamaCategory,
+			Slug:         category.Slug,
+			Urutan:       category.Urutan,
+			Children:     []*CategoryNode{},
+		}
+	}
+
+	roots := []*CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.IDParent != nil {
+			if parent, ok := nodes[*category.IDParent]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// slug from nama, "-2", "-3" added when already used. never only digit,
+// so /categories/:id can tell ID and slug apart
+func uniqueCategorySlug(categoryRepo repository.CategoryRepository, text string, exceptCategoryID uint) (string, error) {
+	base := utils.Slugify(text)
+	if base == "" {
+		base = "kategori"
+	} else if _, err := strconv.ParseUint(base, 10, 64); err == nil {
+		base = "kategori-" + base
+	}
+
+	slug := base
+	for i := 2; ; i++ {
+		exists, err := categoryRepo.SlugExists(slug, exceptCategoryID)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// parent must exist and must not be the category itself or below it
+func (uc *categoryUsecase) validateParent(categoryID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if _, err := uc.categoryRepo.FindByID(*parentID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: parent kategori not found", ErrCategoryNotValid)
+		}
+		return fmt.Errorf("failed verify parent kategori: %w", err)
+	}
+	if categoryID == 0 {
+		return nil
+	}
+
+	categories, err := uc.categoryRepo.FindAll()
+	if err != nil {
+		return fmt.Errorf("failed get all kategori: %w", err)
+	}
+	for _, id := range model.CategoryWithDescendants(categories, categoryID) {
+		if id == *parentID {
+			return fmt.Errorf("%w: kategori can't be moved under itself or its sub kategori", ErrCategoryNotValid)
+		}
+	}
+	return nil
+}
+
+// slug from input when filled, otherwise from nama
+func (uc *categoryUsecase) categorySlug(input model.Category, exceptCategoryID uint) (string, error) {
+	text := strings.TrimSpace(input.Slug)
+	if text == "" {
+		text = input.NamaCategory
+	}
+	slug, err := uniqueCategorySlug(uc.categoryRepo, text, exceptCategoryID)
+	if err != nil {
+		return "", fmt.Errorf("failed create slug kategori: %w", err)
+	}
+	return slug, nil
+}
 
 func (uc *categoryUsecase) CreateCategory(input model.Category) (model.Category, error) {
+	if err := uc.validateParent(0, input.IDParent); err != nil {
+		return model.Category{}, err
+	}
+	slug, err := uc.categorySlug(input, 0)
+	if err != nil {
+		return model.Category{}, err
+	}
+
 	now := time.Now()
+	input.Slug = slug
 	input.CreatedAtDate = now
 	input.UpdatedAtDate = now
 
@@ -52,7 +197,7 @@ func (uc *categoryUsecase) GetCategoryByID(categoryID uint) (model.Category, err
 	category, err := uc.categoryRepo.FindByID(categoryID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return category, errors.New("kategori not found")
+			return category, ErrCategoryNotFound
 		}
 		return category, fmt.Errorf("failed get kategori: %w", err)
 	}
@@ -67,18 +212,37 @@ func (uc *categoryUsecase) UpdateCategory(categoryID uint, input model.Category)
 		}
 		return model.Category{}, fmt.Errorf("failed verify kategori: %w", err)
 	}
+	if err := uc.validateParent(categoryID, input.IDParent); err != nil {
+		return model.Category{}, err
+	}
+
+	// slug only change when asked or nama change, so old link keep working
+	if input.Slug != "" || existingCategory.Slug == "" || existingCategory.NamaCategory != input.NamaCategory {
+		slug, err := uc.categorySlug(input, categoryID)
+		if err != nil {
+			return model.Category{}, err
+		}
+		existingCategory.Slug = slug
+	}
 
+	renamed := existingCategory.NamaCategory != input.NamaCategory
 	existingCategory.NamaCategory = input.NamaCategory
+	existingCategory.IDParent = input.IDParent
+	existingCategory.Urutan = input.Urutan
 	existingCategory.UpdatedAtDate = time.Now()
 
 	updatedCategory, err := uc.categoryRepo.Update(existingCategory)
 	if err != nil {
 		return updatedCategory, fmt.Errorf("failed update kategori: %w", err)
 	}
+	// nama kategori is in search and suggest index of every produk
+	if renamed {
+		uc.indexer.ReindexCategory(categoryID)
+	}
 	return updatedCategory, nil
 }
 
-func (uc *categoryUsecase) DeleteCategory(categoryID uint) error {
+func (uc *categoryUsecase) DeleteCategory(categoryID, targetID uint) error {
 	existingCategory, err := uc.categoryRepo.FindByID(categoryID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -87,9 +251,329 @@ func (uc *categoryUsecase) DeleteCategory(categoryID uint) error {
 		return fmt.Errorf("failed verify kategori: %w", err)
 	}
 
+	if targetID != 0 {
+		_, err := uc.reassign(categoryID, targetID)
+		return err
+	}
+
+	// without target, produk and sub kategori must be moved first
+	totalProduk, err := uc.categoryRepo.CountProduk(categoryID)
+	if err != nil {
+		return fmt.Errorf("failed count produk kategori: %w", err)
+	}
+	totalChildren, err := uc.categoryRepo.CountChildren(categoryID)
+	if err != nil {
+		return fmt.Errorf("failed count sub kategori: %w", err)
+	}
+	if totalProduk > 0 || totalChildren > 0 {
+		return fmt.Errorf("%w: %d produk and %d sub kategori, give target_id to move them", ErrCategoryInUse, totalProduk, totalChildren)
+	}
+
 	err = uc.categoryRepo.Delete(existingCategory)
 	if err != nil {
 		return fmt.Errorf("failed delete kategori: %w", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// for cleaning duplicate kategori, category merged into target then deleted
+func (uc *categoryUsecase) MergeCategory(categoryID, targetID uint) (CategoryMergeResult, error) {
+	if _, err := uc.GetCategoryByID(categoryID); err != nil {
+		return CategoryMergeResult{}, err
+	}
+	if targetID == 0 {
+		return CategoryMergeResult{}, fmt.Errorf("%w: target_id is required", ErrCategoryNotValid)
+	}
+
+	moved, err := uc.reassign(categoryID, targetID)
+	if err != nil {
+		return CategoryMergeResult{}, err
+	}
+	target, err := uc.GetCategoryByID(targetID)
+	if err != nil {
+		return CategoryMergeResult{}, err
+	}
+	return CategoryMergeResult{Target: target, JumlahProduk: moved.Produk, JumlahSubKategori: moved.SubCategory}, nil
+}
+
+// target must exist and not inside category, otherwise moved sub kategori make a loop
+func (uc *categoryUsecase) reassign(categoryID, targetID uint) (repository.ReassignResult, error) {
+	if _, err := uc.categoryRepo.FindByID(targetID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return repository.ReassignResult{}, fmt.Errorf("%w: target kategori not found", ErrCategoryNotValid)
+		}
+		return repository.ReassignResult{}, fmt.Errorf("failed verify target kategori: %w", err)
+	}
+
+	categories, err := uc.categoryRepo.FindAll()
+	if err != nil {
+		return repository.ReassignResult{}, fmt.Errorf("failed get all kategori: %w", err)
+	}
+	sourceTree := model.CategoryWithDescendants(categories, categoryID)
+	for _, id := range sourceTree {
+		if id == targetID {
+			return repository.ReassignResult{}, fmt.Errorf("%w: target can't be the kategori itself or its sub kategori", ErrCategoryNotValid)
+		}
+	}
+
+	// after move, atribut of kategori and its sub kategori must still have unique kode in every branch
+	if err := uc.checkAtributClash(sourceTree, model.CategoryWithAncestors(categories, targetID)); err != nil {
+		return repository.ReassignResult{}, err
+	}
+	if err := uc.checkAtributClash([]uint{categoryID}, model.CategoryWithDescendants(categories, targetID)); err != nil {
+		return repository.ReassignResult{}, err
+	}
+
+	moved, err := uc.categoryRepo.ReassignAndDelete(categoryID, targetID, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return moved, errors.New("kategori not found")
+		}
+		return moved, fmt.Errorf("failed move produk kategori: %w", err)
+	}
+	// moved produk now indexed with target kategori, old one drop out of suggestion
+	if moved.Produk > 0 {
+		uc.indexer.ReindexCategory(targetID)
+	}
+	return moved, nil
+}
+
+func (uc *categoryUsecase) GetCategoryTree() ([]*CategoryNode, error) {
+	categories, err := uc.categoryRepo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed get all kategori: %w", err)
+	}
+	return BuildCategoryTree(categories), nil
+}
+
+// one category with all sub category below it
+func (uc *categoryUsecase) GetCategoryNode(idOrSlug string) (*CategoryNode, error) {
+	var category model.Category
+	var err error
+	if categoryID, parseErr := strconv.ParseUint(idOrSlug, 10, 64); parseErr == nil {
+		category, err = uc.categoryRepo.FindByID(uint(categoryID))
+	} else {
+		category, err = uc.categoryRepo.FindBySlug(idOrSlug)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("kategori not found")
+		}
+		return nil, fmt.Errorf("failed get kategori: %w", err)
+	}
+
+	categories, err := uc.categoryRepo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed get all kategori: %w", err)
+	}
+
+	// search the node in the tree, so children already filled
+	queue := BuildCategoryTree(categories)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = append(queue[1:], node.Children...)
+		if node.ID == category.ID {
+			return node, nil
+		}
+	}
+	return nil, errors.New("kategori not found")
+}
+
+// kode used by atribut in both group of kategori
+func (uc *categoryUsecase) checkAtributClash(categoryIDs, otherIDs []uint) error {
+	atribut, err := uc.categoryRepo.FindAtributByCategoryIDs(categoryIDs)
+	if err != nil || len(atribut) == 0 {
+		return err
+	}
+	other, err := uc.categoryRepo.FindAtributByCategoryIDs(otherIDs)
+	if err != nil {
+		return err
+	}
+	kode := map[string]bool{}
+	for _, a := range other {
+		kode[a.Kode] = true
+	}
+	for _, a := range atribut {
+		if kode[a.Kode] {
+			return fmt.Errorf("%w: atribut %s exist in both kategori, delete one of them first", ErrCategoryNotValid, a.Kode)
+		}
+	}
+	return nil
+}
+
+func (uc *categoryUsecase) GetAtribut(categoryID uint) ([]model.CategoryAtribut, error) {
+	if _, err := uc.GetCategoryByID(categoryID); err != nil {
+		return nil, err
+	}
+	categories, err := uc.categoryRepo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed get all kategori: %w", err)
+	}
+
+	atribut, err := uc.categoryRepo.FindAtributByCategoryIDs(model.CategoryWithAncestors(categories, categoryID))
+	if err != nil {
+		return nil, fmt.Errorf("failed get atribut kategori: %w", err)
+	}
+	return atribut, nil
+}
+
+// kode format, tipe known and opsi only for enum
+func validateAtributSchema(input model.CategoryAtribut) (model.CategoryAtribut, error) {
+	input.Kode = strings.TrimSpace(input.Kode)
+	input.Nama = strings.TrimSpace(input.Nama)
+	if !atributKodePattern.MatchString(input.Kode) {
+		return input, fmt.Errorf("%w: kode must be lowercase letter, digit or _", ErrCategoryNotValid)
+	}
+	if input.Nama == "" {
+		input.Nama = input.Kode
+	}
+
+	switch input.Tipe {
+	case model.AtributTipeString, model.AtributTipeNumber:
+		if len(input.Opsi) > 0 {
+			return input, fmt.Errorf("%w: opsi only for enum atribut", ErrCategoryNotValid)
+		}
+		input.Opsi = nil
+	case model.AtributTipeEnum:
+		seen := map[string]bool{}
+		opsi := model.StringList{}
+		for _, o := range input.Opsi {
+			o = strings.TrimSpace(o)
+			if o == "" || len(o) > 255 || seen[strings.ToLower(o)] {
+				return input, fmt.Errorf("%w: opsi must be filled, unique and max 255 char", ErrCategoryNotValid)
+			}
+			seen[strings.ToLower(o)] = true
+			opsi = append(opsi, o)
+		}
+		if len(opsi) == 0 {
+			return input, fmt.Errorf("%w: enum atribut need at least one opsi", ErrCategoryNotValid)
+		}
+		input.Opsi = opsi
+	default:
+		return input, fmt.Errorf("%w: tipe must be string, number or enum", ErrCategoryNotValid)
+	}
+	return input, nil
+}
+
+// kode must be unique in parent kategori and sub kategori, because produk use all of them
+func (uc *categoryUsecase) checkAtributKode(categoryID, exceptAtributID uint, kode string) error {
+	categories, err := uc.categoryRepo.FindAll()
+	if err != nil {
+		return fmt.Errorf("failed get all kategori: %w", err)
+	}
+	lineage := append(model.CategoryWithAncestors(categories, categoryID), model.CategoryWithDescendants(categories, categoryID)[1:]...)
+
+	atribut, err := uc.categoryRepo.FindAtributByCategoryIDs(lineage)
+	if err != nil {
+		return fmt.Errorf("failed get atribut kategori: %w", err)
+	}
+	for _, a := range atribut {
+		if a.Kode == kode && a.ID != exceptAtributID {
+			return fmt.Errorf("%w: atribut %s already exist in kategori %d", ErrCategoryNotValid, kode, a.IDCategory)
+		}
+	}
+	return nil
+}
+
+func (uc *categoryUsecase) CreateAtribut(categoryID uint, input model.CategoryAtribut) (model.CategoryAtribut, error) {
+	if _, err := uc.GetCategoryByID(categoryID); err != nil {
+		return model.CategoryAtribut{}, err
+	}
+	input, err := validateAtributSchema(input)
+	if err != nil {
+		return model.CategoryAtribut{}, err
+	}
+	if err := uc.checkAtributKode(categoryID, 0, input.Kode); err != nil {
+		return model.CategoryAtribut{}, err
+	}
+
+	now := time.Now()
+	input.IDCategory = categoryID
+	input.CreatedAtDate = now
+	input.UpdatedAtDate = now
+
+	savedAtribut, err := uc.categoryRepo.SaveAtribut(input)
+	if err != nil {
+		return savedAtribut, fmt.Errorf("failed save atribut: %w", err)
+	}
+	return savedAtribut, nil
+}
+
+func (uc *categoryUsecase) findAtribut(categoryID, atributID uint) (model.CategoryAtribut, error) {
+	atribut, err := uc.categoryRepo.FindAtributByID(categoryID, atributID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return atribut, errors.New("atribut not found")
+		}
+		return atribut, fmt.Errorf("failed get atribut: %w", err)
+	}
+	return atribut, nil
+}
+
+// kode and tipe can't change, produk already saved nilai for them
+func (uc *categoryUsecase) UpdateAtribut(categoryID, atributID uint, input model.CategoryAtribut) (model.CategoryAtribut, error) {
+	existingAtribut, err := uc.findAtribut(categoryID, atributID)
+	if err != nil {
+		return existingAtribut, err
+	}
+	input.Kode = existingAtribut.Kode
+	input.Tipe = existingAtribut.Tipe
+	input, err = validateAtributSchema(input)
+	if err != nil {
+		return model.CategoryAtribut{}, err
+	}
+
+	if input.Tipe == model.AtributTipeEnum {
+		used, err := uc.categoryRepo.CountNilaiNotIn(atributID, input.Opsi)
+		if err != nil {
+			return model.CategoryAtribut{}, fmt.Errorf("failed check nilai atribut: %w", err)
+		}
+		if used > 0 {
+			return model.CategoryAtribut{}, fmt.Errorf("%w: %d produk still use removed opsi", ErrCategoryInUse, used)
+		}
+	}
+
+	existingAtribut.Nama = input.Nama
+	existingAtribut.Opsi = input.Opsi
+	existingAtribut.Satuan = strings.TrimSpace(input.Satuan)
+	existingAtribut.Wajib = input.Wajib
+	existingAtribut.Urutan = input.Urutan
+	existingAtribut.UpdatedAtDate = time.Now()
+
+	updatedAtribut, err := uc.categoryRepo.UpdateAtribut(existingAtribut)
+	if err != nil {
+		return updatedAtribut, fmt.Errorf("failed update atribut: %w", err)
+	}
+	return updatedAtribut, nil
+}
+
+func (uc *categoryUsecase) DeleteAtribut(categoryID, atributID uint) error {
+	existingAtribut, err := uc.findAtribut(categoryID, atributID)
+	if err != nil {
+		return err
+	}
+	if err := uc.categoryRepo.DeleteAtribut(existingAtribut); err != nil {
+		return fmt.Errorf("failed delete atribut: %w", err)
+	}
+	return nil
+}
+
+// run on startup, give slug to category created before slug exist
+func (uc *categoryUsecase) BackfillSlugs() error {
+	categories, err := uc.categoryRepo.FindAllWithoutSlug()
+	if err != nil {
+		return fmt.Errorf("failed to get kategori without slug: %w", err)
+	}
+
+	for _, category := range categories {
+		slug, err := uniqueCategorySlug(uc.categoryRepo, category.NamaCategory, category.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create slug kategori %d: %w", category.ID, err)
+		}
+		category.Slug = slug
+		if _, err := uc.categoryRepo.Update(category); err != nil {
+			return fmt.Errorf("failed to save slug kategori %d: %w", category.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func testCategories() []model.Category {
+	// already ordered by urutan like repository FindAll
+	return []model.Category{
+		{ID: 1, NamaCategory: "Elektronik"},
+		{ID: 4, NamaCategory: "Laptop", IDParent: uintPtr(1)},
+		{ID: 2, NamaCategory: "Handphone", IDParent: uintPtr(1)},
+		{ID: 3, NamaCategory: "Android", IDParent: uintPtr(2)},
+		{ID: 5, NamaCategory: "Fashion"},
+		{ID: 6, NamaCategory: "Orphan", IDParent: uintPtr(99)},
+	}
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	tree := BuildCategoryTree(testCategories())
+
+	var roots []uint
+	for _, node := range tree {
+		roots = append(roots, node.ID)
+	}
+	if !reflect.DeepEqual(roots, []uint{1, 5, 6}) {
+		t.Fatalf("expected roots [1 5 6], got %v", roots)
+	}
+
+	elektronik := tree[0]
+	if len(elektronik.Children) != 2 || elektronik.Children[0].ID != 4 || elektronik.Children[1].ID != 2 {
+		t.Fatalf("children must keep urutan order, got %+v", elektronik.Children)
+	}
+	if len(elektronik.Children[1].Children) != 1 || elektronik.Children[1].Children[0].ID != 3 {
+		t.Fatalf("expected android under handphone")
+	}
+	if tree[1].Children == nil {
+		t.Fatalf("leaf children must be empty list, not null")
+	}
+}
+
+func TestCategoryWithDescendants(t *testing.T) {
+	categories := testCategories()
+
+	if got := model.CategoryWithDescendants(categories, 1); !reflect.DeepEqual(got, []uint{1, 4, 2, 3}) {
+		t.Errorf("elektronik: got %v", got)
+	}
+	if got := model.CategoryWithDescendants(categories, 3); !reflect.DeepEqual(got, []uint{3}) {
+		t.Errorf("leaf: got %v", got)
+	}
+
+	// loop in data must not hang
+	categories = append(categories, model.Category{ID: 7, IDParent: uintPtr(8)}, model.Category{ID: 8, IDParent: uintPtr(7)})
+	if got := model.CategoryWithDescendants(categories, 7); !reflect.DeepEqual(got, []uint{7, 8}) {
+		t.Errorf("loop: got %v", got)
+	}
+}
+
+type fakeCategoryRepo struct {
+	repository.CategoryRepository
+	categories []model.Category
+	produk     map[uint]int64 // produk count per kategori
+	atribut    []model.CategoryAtribut
+	reassigned [][2]uint
+	deleted    []uint
+}
+
+func (r *fakeCategoryRepo) FindByID(categoryID uint) (model.Category, error) {
+	for _, category := range r.categories {
+		if category.ID == categoryID {
+			return category, nil
+		}
+	}
+	return model.Category{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeCategoryRepo) FindAll() ([]model.Category, error) {
+	return r.categories, nil
+}
+
+func (r *fakeCategoryRepo) FindAtributByCategoryIDs(categoryIDs []uint) ([]model.CategoryAtribut, error) {
+	var result []model.CategoryAtribut
+	for _, atribut := range r.atribut {
+		for _, id := range categoryIDs {
+			if atribut.IDCategory == id {
+				result = append(result, atribut)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeCategoryRepo) CountProduk(categoryID uint) (int64, error) {
+	return r.produk[categoryID], nil
+}
+
+func (r *fakeCategoryRepo) CountChildren(categoryID uint) (int64, error) {
+	var total int64
+	for _, category := range r.categories {
+		if category.IDParent != nil && *category.IDParent == categoryID {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (r *fakeCategoryRepo) Delete(category model.Category) error {
+	r.deleted = append(r.deleted, category.ID)
+	return nil
+}
+
+func (r *fakeCategoryRepo) ReassignAndDelete(categoryID, targetID uint, now time.Time) (repository.ReassignResult, error) {
+	r.reassigned = append(r.reassigned, [2]uint{categoryID, targetID})
+	return repository.ReassignResult{Produk: r.produk[categoryID]}, nil
+}
+
+func TestDeleteCategory(t *testing.T) {
+	repo := &fakeCategoryRepo{categories: testCategories(), produk: map[uint]int64{4: 3}}
+	uc := NewCategoryUsecase(repo, &fakeProdukIndexer{})
+
+	// used by produk or sub kategori, refused without target
+	if err := uc.DeleteCategory(4, 0); !errors.Is(err, ErrCategoryInUse) {
+		t.Errorf("laptop with produk: expected ErrCategoryInUse, got %v", err)
+	}
+	if err := uc.DeleteCategory(2, 0); !errors.Is(err, ErrCategoryInUse) {
+		t.Errorf("handphone with sub kategori: expected ErrCategoryInUse, got %v", err)
+	}
+
+	// target inside deleted kategori or not exist
+	for _, targetID := range []uint{2, 3, 100} {
+		if err := uc.DeleteCategory(2, targetID); !errors.Is(err, ErrCategoryNotValid) {
+			t.Errorf("target %d: expected ErrCategoryNotValid, got %v", targetID, err)
+		}
+	}
+	if len(repo.reassigned) != 0 || len(repo.deleted) != 0 {
+		t.Fatalf("refused delete must not change data")
+	}
+
+	if err := uc.DeleteCategory(4, 5); err != nil {
+		t.Fatalf("delete with target: %v", err)
+	}
+	if err := uc.DeleteCategory(5, 0); err != nil {
+		t.Fatalf("delete unused: %v", err)
+	}
+	if len(repo.reassigned) != 1 || repo.reassigned[0] != [2]uint{4, 5} || len(repo.deleted) != 1 || repo.deleted[0] != 5 {
+		t.Errorf("unexpected repository call, reassigned %v deleted %v", repo.reassigned, repo.deleted)
+	}
+
+	result, err := uc.MergeCategory(4, 1)
+	if err != nil || result.Target.ID != 1 || result.JumlahProduk != 3 {
+		t.Errorf("merge: got %+v, %v", result, err)
+	}
+}
+
+func TestMergeCategoryAtributClash(t *testing.T) {
+	repo := &fakeCategoryRepo{
+		categories: testCategories(),
+		atribut: []model.CategoryAtribut{
+			{ID: 1, IDCategory: 1, Kode: "brand"},
+			{ID: 2, IDCategory: 3, Kode: "os"},
+			{ID: 3, IDCategory: 5, Kode: "os"},
+		},
+	}
+	uc := NewCategoryUsecase(repo, &fakeProdukIndexer{})
+
+	// sub kategori of handphone has "os", fashion also has "os"
+	if _, err := uc.MergeCategory(2, 5); !errors.Is(err, ErrCategoryNotValid) {
+		t.Errorf("expected clash error, got %v", err)
+	}
+	// laptop has no atribut, android "os" not affected
+	if _, err := uc.MergeCategory(4, 5); err != nil {
+		t.Errorf("expected merge ok, got %v", err)
+	}
+}
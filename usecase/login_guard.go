@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+// LoginLockedError returned when account or IP still in backoff / lockout
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %d seconds", int(e.RetryAfter.Seconds())+1)
+}
+
+// setting for brute force protection
+// after FreeAttempts failed, next try is delayed BaseDelay, then doubled every failed until MaxDelay (lockout)
+type LoginGuardConfig struct {
+	AccountFreeAttempts int
+	IPFreeAttempts      int
+	BaseDelay           time.Duration
+	MaxDelay            time.Duration
+	ResetAfter          time.Duration // failed counter forgotten after no failed this long
+
+	Now func() time.Time // can be replaced in test
+}
+
+func DefaultLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		AccountFreeAttempts: 5,
+		IPFreeAttempts:      20,
+		BaseDelay:           30 * time.Second,
+		MaxDelay:            15 * time.Minute,
+		ResetAfter:          time.Hour,
+		Now:                 time.Now,
+	}
+}
+
+type LoginGuard interface {
+	Check(email, ipAddress string) error
+	// count attempt as failed before credential checked, locked check and count done under same row lock
+	// so parallel guesses can't all pass. call Release when credential correct
+	Reserve(email, ipAddress string) (LoginReservation, error)
+	Release(reservation LoginReservation) error
+	RecordFailure(email, ipAddress string) error
+	RecordSuccess(email string) error
+	Unlock(email, ipAddress string) error
+}
+
+type loginGuard struct {
+	loginAttemptRepo repository.LoginAttemptRepository
+	config           LoginGuardConfig
+}
+
+func NewLoginGuard(loginAttemptRepo repository.LoginAttemptRepository, config LoginGuardConfig) LoginGuard {
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &loginGuard{loginAttemptRepo, config}
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
+
+// return LoginLockedError if account or ip still locked
+func (g *loginGuard) Check(email, ipAddress string) error {
+	now := g.config.Now()
+
+	var retryAfter time.Duration
+	for _, key := range []string{accountKey(email), ipKey(ipAddress)} {
+		throttle, err := g.loginAttemptRepo.FindThrottle(key)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return fmt.Errorf("failed check login throttle: %w", err)
+		}
+
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+			if wait := throttle.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// what Reserve counted on one key, lock set by it only undone when no other failure changed it
+type reservedAttempt struct {
+	key                 string
+	previousLockedUntil *time.Time
+	lockedUntil         *time.Time
+}
+
+type LoginReservation struct {
+	attempts []reservedAttempt
+}
+
+func (g *loginGuard) Reserve(email, ipAddress string) (LoginReservation, error) {
+	var reservation LoginReservation
+	keys := []struct {
+		key          string
+		freeAttempts int
+	}{
+		{accountKey(email), g.config.AccountFreeAttempts},
+		{ipKey(ipAddress), g.config.IPFreeAttempts},
+	}
+	for _, k := range keys {
+		attempt, retryAfter, err := g.reserve(k.key, k.freeAttempts)
+		if err == nil && retryAfter > 0 {
+			err = &LoginLockedError{RetryAfter: retryAfter}
+		}
+		if err != nil {
+			// locked attempt is not counted
+			if releaseErr := g.Release(reservation); releaseErr != nil {
+				return LoginReservation{}, releaseErr
+			}
+			return LoginReservation{}, err
+		}
+		reservation.attempts = append(reservation.attempts, attempt)
+	}
+	return reservation, nil
+}
+
+// return how long still locked, nothing counted then
+func (g *loginGuard) reserve(key string, freeAttempts int) (reservedAttempt, time.Duration, error) {
+	now := g.config.Now()
+	attempt := reservedAttempt{key: key}
+	var retryAfter time.Duration
+
+	_, err := g.loginAttemptRepo.UpdateThrottle(key, now, func(throttle *model.LoginThrottle) {
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+			retryAfter = throttle.LockedUntil.Sub(now)
+			return
+		}
+		attempt.previousLockedUntil = throttle.LockedUntil
+		g.fail(throttle, now, freeAttempts)
+		attempt.lockedUntil = throttle.LockedUntil
+	})
+	if err != nil {
+		return attempt, 0, fmt.Errorf("failed save login throttle: %w", err)
+	}
+	return attempt, retryAfter, nil
+}
+
+func (g *loginGuard) Release(reservation LoginReservation) error {
+	now := g.config.Now()
+	for _, attempt := range reservation.attempts {
+		_, err := g.loginAttemptRepo.UpdateThrottle(attempt.key, now, func(throttle *model.LoginThrottle) {
+			if throttle.FailedCount > 0 {
+				throttle.FailedCount--
+			}
+			if sameTime(throttle.LockedUntil, attempt.lockedUntil) {
+				throttle.LockedUntil = attempt.previousLockedUntil
+			}
+			throttle.UpdatedAtDate = now
+		})
+		if err != nil {
+			return fmt.Errorf("failed release login throttle: %w", err)
+		}
+	}
+	return nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func (g *loginGuard) RecordFailure(email, ipAddress string) error {
+	if err := g.increment(accountKey(email), g.config.AccountFreeAttempts); err != nil {
+		return err
+	}
+	return g.increment(ipKey(ipAddress), g.config.IPFreeAttempts)
+}
+
+// only account counter reset, ip counter keep running so attacker cant reset it with own account
+func (g *loginGuard) RecordSuccess(email string) error {
+	if err := g.loginAttemptRepo.DeleteThrottle(accountKey(email)); err != nil {
+		return fmt.Errorf("failed reset login throttle: %w", err)
+	}
+	return nil
+}
+
+// used by admin, ip is optional
+func (g *loginGuard) Unlock(email, ipAddress string) error {
+	if email != "" {
+		if err := g.loginAttemptRepo.DeleteThrottle(accountKey(email)); err != nil {
+			return fmt.Errorf("failed unlock account: %w", err)
+		}
+	}
+	if ipAddress != "" {
+		if err := g.loginAttemptRepo.DeleteThrottle(ipKey(ipAddress)); err != nil {
+			return fmt.Errorf("failed unlock ip: %w", err)
+		}
+	}
+	return nil
+}
+
+func (g *loginGuard) increment(key string, freeAttempts int) error {
+	now := g.config.Now()
+
+	_, err := g.loginAttemptRepo.UpdateThrottle(key, now, func(throttle *model.LoginThrottle) {
+		g.fail(throttle, now, freeAttempts)
+	})
+	if err != nil {
+		return fmt.Errorf("failed save login throttle: %w", err)
+	}
+	return nil
+}
+
+// add one failed to throttle, must run under row lock
+func (g *loginGuard) fail(throttle *model.LoginThrottle, now time.Time, freeAttempts int) {
+	// old failed attempt is forgotten
+	if throttle.FailedCount > 0 && now.Sub(throttle.LastFailedAt) > g.config.ResetAfter {
+		throttle.FailedCount = 0
+		throttle.LockedUntil = nil
+	}
+
+	throttle.FailedCount++
+	throttle.LastFailedAt = now
+	throttle.UpdatedAtDate = now
+
+	if delay := g.backoff(throttle.FailedCount, freeAttempts); delay > 0 {
+		lockedUntil := now.Add(delay)
+		throttle.LockedUntil = &lockedUntil
+	}
+}
+
+// exponential backoff: BaseDelay * 2^(failed - free), max MaxDelay
+func (g *loginGuard) backoff(failedCount, freeAttempts int) time.Duration {
+	if failedCount < freeAttempts {
+		return 0
+	}
+
+	delay := g.config.BaseDelay
+	for i := freeAttempts; i < failedCount; i++ {
+		delay *= 2
+		if delay >= g.config.MaxDelay {
+			return g.config.MaxDelay
+		}
+	}
+	if delay > g.config.MaxDelay {
+		return g.config.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"rakamin-evermos/repository"
+)
+
+func newTestLoginGuard(now *time.Time) LoginGuard {
+	config := LoginGuardConfig{
+		AccountFreeAttempts: 3,
+		IPFreeAttempts:      5,
+		BaseDelay:           time.Minute,
+		MaxDelay:            10 * time.Minute,
+		ResetAfter:          time.Hour,
+		Now:                 func() time.Time { return *now },
+	}
+	return NewLoginGuard(repository.NewMemoryLoginAttemptRepository(), config)
+}
+
+func TestLoginGuardLocksAfterFreeAttempts(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	guard := newTestLoginGuard(&now)
+
+	for i := 0; i < 2; i++ {
+		if err := guard.RecordFailure("a@mail.com", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := guard.Check("a@mail.com", "10.0.0.1"); err != nil {
+			t.Fatalf("attempt %d should not be locked: %v", i+1, err)
+		}
+	}
+
+	if err := guard.RecordFailure("a@mail.com", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	err := guard.Check("A@mail.com ", "10.0.0.2")
+	var lockedErr *LoginLockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("expected LoginLockedError, got %v", err)
+	}
+	if lockedErr.RetryAfter != time.Minute {
+		t.Fatalf("expected retry after 1m, got %s", lockedErr.RetryAfter)
+	}
+
+	now = now.Add(time.Minute + time.Second)
+	if err := guard.Check("a@mail.com", "10.0.0.1"); err != nil {
+		t.Fatalf("lock should be expired: %v", err)
+	}
+}
+
+func TestLoginGuardBackoffIsExponentialAndCapped(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	guard := newTestLoginGuard(&now)
+
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for i := 0; i < 2; i++ {
+		guard.RecordFailure("b@mail.com", "10.0.0.9")
+	}
+	for _, want := range expected {
+		guard.RecordFailure("b@mail.com", "")
+		var lockedErr *LoginLockedError
+		if !errors.As(guard.Check("b@mail.com", ""), &lockedErr) || lockedErr.RetryAfter != want {
+			t.Fatalf("expected lock %s, got %v", want, lockedErr)
+		}
+	}
+}
+
+func TestLoginGuardIPLockAndUnlock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	guard := newTestLoginGuard(&now)
+
+	// spread over many accounts so only ip counter reach the limit
+	for _, email := range []string{"1@mail.com", "2@mail.com", "3@mail.com", "4@mail.com", "5@mail.com"} {
+		guard.RecordFailure(email, "10.0.0.3")
+	}
+
+	if err := guard.Check("new@mail.com", "10.0.0.3"); err == nil {
+		t.Fatal("ip should be locked")
+	}
+	if err := guard.Check("new@mail.com", "10.0.0.4"); err != nil {
+		t.Fatalf("other ip should not be locked: %v", err)
+	}
+
+	if err := guard.Unlock("", "10.0.0.3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := guard.Check("new@mail.com", "10.0.0.3"); err != nil {
+		t.Fatalf("ip should be unlocked: %v", err)
+	}
+}
+
+func TestLoginGuardSuccessResetsAccountOnly(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	guard := newTestLoginGuard(&now)
+
+	for i := 0; i < 2; i++ {
+		guard.RecordFailure("c@mail.com", "10.0.0.5")
+	}
+	guard.RecordSuccess("c@mail.com")
+
+	// account counter start again from zero
+	for i := 0; i < 2; i++ {
+		guard.RecordFailure("c@mail.com", "10.0.0.6")
+	}
+	if err := guard.Check("c@mail.com", "10.0.0.6"); err != nil {
+		t.Fatalf("account should not be locked after success reset: %v", err)
+	}
+
+	// old failed attempts expire after ResetAfter
+	now = now.Add(2 * time.Hour)
+	guard.RecordFailure("c@mail.com", "10.0.0.6")
+	if err := guard.Check("c@mail.com", "10.0.0.6"); err != nil {
+		t.Fatalf("counter should be reset after ResetAfter: %v", err)
+	}
+}
+
+func TestLoginGuardCountsParallelFailures(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	repo := repository.NewMemoryLoginAttemptRepository()
+	guard := NewLoginGuard(repo, LoginGuardConfig{
+		AccountFreeAttempts: 3,
+		IPFreeAttempts:      5,
+		BaseDelay:           time.Minute,
+		MaxDelay:            10 * time.Minute,
+		ResetAfter:          time.Hour,
+		Now:                 func() time.Time { return now },
+	})
+
+	// guesses sent at same time must all be counted, none slip past the lock
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := guard.RecordFailure("d@mail.com", "10.0.0.7"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, key := range []string{accountKey("d@mail.com"), ipKey("10.0.0.7")} {
+		throttle, err := repo.FindThrottle(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if throttle.FailedCount != 50 {
+			t.Fatalf("expected 50 failed for %s, got %d", key, throttle.FailedCount)
+		}
+	}
+	var lockedErr *LoginLockedError
+	if !errors.As(guard.Check("d@mail.com", "10.0.0.7"), &lockedErr) || lockedErr.RetryAfter != 10*time.Minute {
+		t.Fatalf("expected max lock, got %v", lockedErr)
+	}
+}
+
+func TestLoginGuardReserveParallelGuesses(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	repo := repository.NewMemoryLoginAttemptRepository()
+	guard := NewLoginGuard(repo, LoginGuardConfig{
+		AccountFreeAttempts: 3,
+		IPFreeAttempts:      5,
+		BaseDelay:           time.Minute,
+		MaxDelay:            10 * time.Minute,
+		ResetAfter:          time.Hour,
+		Now:                 func() time.Time { return now },
+	})
+
+	// every guess reserved before its password compared, only free attempts get through
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	passed, locked := 0, 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := guard.Reserve("e@mail.com", "10.0.0.8")
+			mu.Lock()
+			defer mu.Unlock()
+			var lockedErr *LoginLockedError
+			switch {
+			case err == nil:
+				passed++
+			case errors.As(err, &lockedErr):
+				locked++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if passed != 3 || locked != 47 {
+		t.Fatalf("expected 3 passed and 47 locked, got %d and %d", passed, locked)
+	}
+	for _, key := range []string{accountKey("e@mail.com"), ipKey("10.0.0.8")} {
+		throttle, err := repo.FindThrottle(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if throttle.FailedCount != 3 {
+			t.Fatalf("expected locked attempt not counted for %s, got %d", key, throttle.FailedCount)
+		}
+	}
+}
+
+func TestLoginGuardReleaseUndoReservation(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	guard := newTestLoginGuard(&now)
+
+	for i := 0; i < 2; i++ {
+		guard.RecordFailure("f@mail.com", "10.0.0.9")
+	}
+	// third attempt would lock the account, correct password undo it
+	reservation, err := guard.Reserve("f@mail.com", "10.0.0.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := guard.Check("f@mail.com", "10.0.0.9"); err == nil {
+		t.Fatal("expected locked while reservation pending")
+	}
+	if err := guard.Release(reservation); err != nil {
+		t.Fatal(err)
+	}
+	if err := guard.Check("f@mail.com", "10.0.0.9"); err != nil {
+		t.Fatalf("expected not locked after release: %v", err)
+	}
+
+	// ip locked, account not counted either
+	for _, email := range []string{"1@mail.com", "2@mail.com", "3@mail.com"} {
+		guard.RecordFailure(email, "10.0.0.9")
+	}
+	if _, err := guard.Reserve("g@mail.com", "10.0.0.9"); err == nil {
+		t.Fatal("expected ip locked")
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := guard.Reserve("g@mail.com", "10.0.0.10"); err != nil {
+			t.Fatalf("expected account not counted while ip locked: %v", err)
+		}
+	}
+}
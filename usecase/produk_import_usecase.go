@@ -0,0 +1,417 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProdukImportUsecase create / update many produk of toko from csv or xlsx.
+// file processed by background worker, seller poll the job for the row report
+type ProdukImportUsecase interface {
+	ImportProduk(userID, tokoID uint, format, namaFile, filePath string) (model.ProdukImport, error)
+	GetImport(userID, tokoID, importID uint) (model.ProdukImport, error)
+	ExportProduk(userID, tokoID uint, writer utils.SpreadsheetWriter) error // header first, same column as import
+
+	// background job
+	ProcessPendingImports() (int, error)
+	FailInterruptedImports() error
+}
+
+const (
+	MaxImportFileSize   = 10 << 20 // byte
+	maxImportRows       = 5000
+	maxImportErrors     = 1000 // row report saved, gagal still counted after this
+	importProgressEvery = 100  // row
+	importBatchSize     = 5    // job per worker run
+	exportBatchSize     = 500
+)
+
+// atribut column is atribut.<kode>, nama_category only for reading and ignored on import
+const importAtributPrefix = "atribut."
+
+var produkImportColumns = []string{
+	"id", "nama_produk", "slug", "harga_reseller", "harga_konsumen", "stok",
+	"deskripsi", "id_category", "nama_category", "status",
+}
+
+var requiredImportColumns = []string{
+	"nama_produk", "harga_reseller", "harga_konsumen", "stok", "deskripsi", "id_category",
+}
+
+type produkImportUsecase struct {
+	importRepo        repository.ProdukImportRepository
+	produkRepo        repository.ProdukRepository
+	produkUsecase     ProdukUsecase
+	tokoMemberUsecase TokoMemberUsecase
+}
+
+func NewProdukImportUsecase(
+	importRepo repository.ProdukImportRepository,
+	produkRepo repository.ProdukRepository,
+	produkUsecase ProdukUsecase,
+	tokoMemberUsecase TokoMemberUsecase,
+) ProdukImportUsecase {
+	return &produkImportUsecase{importRepo, produkRepo, produkUsecase, tokoMemberUsecase}
+}
+
+// uploaded file removed when job not created
+func (uc *produkImportUsecase) ImportProduk(userID, tokoID uint, format, namaFile, filePath string) (model.ProdukImport, error) {
+	if format != utils.SpreadsheetCSV && format != utils.SpreadsheetXLSX {
+		removeUploadedFile(filePath)
+		return model.ProdukImport{}, fmt.Errorf("%w: file must be .csv or .xlsx", ErrProdukNotValid)
+	}
+	toko, err := resolveWritableToko(uc.tokoMemberUsecase, userID, tokoID)
+	if err != nil {
+		removeUploadedFile(filePath)
+		return model.ProdukImport{}, err
+	}
+
+	now := time.Now()
+	job := model.ProdukImport{
+		IDToko:        toko.ID,
+		IDUser:        userID,
+		Format:        format,
+		NamaFile:      namaFile,
+		FilePath:      filePath,
+		Status:        model.ImportStatusPending,
+		Errors:        model.ImportRowErrors{},
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	}
+	savedJob, err := uc.importRepo.Save(job)
+	if err != nil {
+		removeUploadedFile(filePath)
+		return savedJob, fmt.Errorf("failed save import: %w", err)
+	}
+	return savedJob, nil
+}
+
+func (uc *produkImportUsecase) GetImport(userID, tokoID, importID uint) (model.ProdukImport, error) {
+	toko, err := uc.tokoMemberUsecase.ResolveActingToko(userID, tokoID, model.TokoPermProdukRead)
+	if err != nil {
+		return model.ProdukImport{}, err
+	}
+
+	job, err := uc.importRepo.FindByTokoIDAndID(toko.ID, importID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return job, errors.New("import not found or you don't have access")
+		}
+		return job, fmt.Errorf("failed get import: %w", err)
+	}
+	return job, nil
+}
+
+// draft and archived produk included, atribut column is union of all kategori used.
+// row written per batch, nothing written when access refused
+func (uc *produkImportUsecase) ExportProduk(userID, tokoID uint, writer utils.SpreadsheetWriter) error {
+	toko, err := uc.tokoMemberUsecase.ResolveActingToko(userID, tokoID, model.TokoPermProdukRead)
+	if err != nil {
+		return err
+	}
+
+	kodes, err := uc.produkRepo.FindAtributKodeByTokoID(toko.ID)
+	if err != nil {
+		return fmt.Errorf("failed get atribut to export: %w", err)
+	}
+	header := append([]string{}, produkImportColumns...)
+	for _, kode := range kodes {
+		header = append(header, importAtributPrefix+kode)
+	}
+	if err := writer.WriteRow(header); err != nil {
+		return err
+	}
+
+	err = uc.produkRepo.FindAllByTokoIDInBatches(toko.ID, exportBatchSize, func(batch []model.Produk) error {
+		for _, produk := range batch {
+			if err := writer.WriteRow(exportRow(produk, kodes)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed export produk: %w", err)
+	}
+	return writer.Flush()
+}
+
+func exportRow(produk model.Produk, kodes []string) []string {
+	nilai := map[string]string{}
+	for _, atribut := range produk.Atribut {
+		if atribut.Atribut != nil {
+			nilai[atribut.Atribut.Kode] = atribut.Nilai
+		}
+	}
+	row := []string{
+		strconv.FormatUint(uint64(produk.ID), 10),
+		produk.NamaProduk,
+		produk.Slug,
+		produk.HargaReseller,
+		produk.HargaKonsumen,
+		strconv.Itoa(produk.Stok),
+		produk.Deskripsi,
+		strconv.FormatUint(uint64(produk.IDCategory), 10),
+		produk.Category.NamaCategory,
+		produk.Status,
+	}
+	for _, kode := range kodes {
+		row = append(row, nilai[kode])
+	}
+	return row
+}
+
+// pending job processed one by one, claim keep job from processed twice
+func (uc *produkImportUsecase) ProcessPendingImports() (int, error) {
+	jobs, err := uc.importRepo.FindByStatus(model.ImportStatusPending, importBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed get pending import: %w", err)
+	}
+
+	processed := 0
+	for _, job := range jobs {
+		now := time.Now()
+		claimed, err := uc.importRepo.Claim(job.ID, now)
+		if err != nil {
+			fmt.Printf("failed claim import %d: %v\n", job.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		job.Status = model.ImportStatusProcessing
+		job.StartedAt = &now
+		uc.runImport(job)
+		processed++
+	}
+	return processed, nil
+}
+
+// job stopped by restart not run again, row without id would be created twice
+func (uc *produkImportUsecase) FailInterruptedImports() error {
+	for {
+		jobs, err := uc.importRepo.FindByStatus(model.ImportStatusProcessing, importBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed get interrupted import: %w", err)
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+		for _, job := range jobs {
+			removeUploadedFile(job.FilePath)
+			if _, err := uc.finishImport(job, model.ImportStatusFailed, "interrupted by server restart, check produk before import again"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// StartProdukImportWorker process pending import every interval, call returned func to stop
+func StartProdukImportWorker(uc ProdukImportUsecase, interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			processed, err := uc.ProcessPendingImports()
+			if err != nil {
+				fmt.Printf("produk import worker: %v\n", err)
+			} else if processed > 0 {
+				fmt.Printf("produk import worker: %d import processed\n", processed)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// every row saved on its own, failed row reported and the rest still imported
+func (uc *produkImportUsecase) runImport(job model.ProdukImport) model.ProdukImport {
+	defer removeUploadedFile(job.FilePath)
+
+	// header is not produk
+	rows, err := utils.ReadSpreadsheet(job.FilePath, job.Format, maxImportRows+1)
+	if errors.Is(err, utils.ErrSpreadsheetTooManyRows) {
+		return uc.finishImportLogged(job, model.ImportStatusFailed, fmt.Sprintf("file has more than %d produk", maxImportRows))
+	}
+	if err != nil {
+		return uc.finishImportLogged(job, model.ImportStatusFailed, "file can't be read as "+job.Format)
+	}
+	columns, err := parseImportHeader(rows)
+	if err != nil {
+		return uc.finishImportLogged(job, model.ImportStatusFailed, err.Error())
+	}
+
+	job.TotalBaris = 0
+	for _, row := range rows[1:] {
+		if !isBlankRow(row) {
+			job.TotalBaris++
+		}
+	}
+
+	job.Errors = model.ImportRowErrors{}
+	for i, row := range rows[1:] {
+		if isBlankRow(row) {
+			continue
+		}
+		if err := uc.importRow(job, columns, row); err != nil {
+			job.Gagal++
+			if len(job.Errors) < maxImportErrors {
+				// header is row 1
+				job.Errors = append(job.Errors, model.ImportRowError{Baris: i + 2, Pesan: err.Error()})
+			}
+		} else {
+			job.Berhasil++
+		}
+
+		if (job.Berhasil+job.Gagal)%importProgressEvery == 0 {
+			job.UpdatedAtDate = time.Now()
+			if saved, err := uc.importRepo.Update(job); err != nil {
+				fmt.Printf("failed save progress of import %d: %v\n", job.ID, err)
+			} else {
+				job = saved
+			}
+		}
+	}
+	return uc.finishImportLogged(job, model.ImportStatusDone, "")
+}
+
+func (uc *produkImportUsecase) finishImport(job model.ProdukImport, status, pesan string) (model.ProdukImport, error) {
+	now := time.Now()
+	job.Status = status
+	job.Pesan = pesan
+	job.FinishedAt = &now
+	job.UpdatedAtDate = now
+	savedJob, err := uc.importRepo.Update(job)
+	if err != nil {
+		return job, fmt.Errorf("failed finish import %d: %w", job.ID, err)
+	}
+	return savedJob, nil
+}
+
+func (uc *produkImportUsecase) finishImportLogged(job model.ProdukImport, status, pesan string) model.ProdukImport {
+	savedJob, err := uc.finishImport(job, status, pesan)
+	if err != nil {
+		fmt.Printf("failed finish import %d: %v\n", job.ID, err)
+	}
+	return savedJob
+}
+
+// column name -> index, unknown column rejected so typo in header not silently ignored
+func parseImportHeader(rows [][]string) (map[string]int, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	known := map[string]bool{}
+	for _, name := range produkImportColumns {
+		known[name] = true
+	}
+	columns := map[string]int{}
+	for i, cell := range rows[0] {
+		name := strings.ToLower(strings.TrimSpace(cell))
+		if name == "" {
+			continue
+		}
+		if !known[name] && (!strings.HasPrefix(name, importAtributPrefix) || name == importAtributPrefix) {
+			return nil, fmt.Errorf("unknown column %s", name)
+		}
+		if _, exist := columns[name]; exist {
+			return nil, fmt.Errorf("column %s appear more than once", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, exist := columns[name]; !exist {
+			return nil, fmt.Errorf("column %s is required", name)
+		}
+	}
+	return columns, nil
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// same check as json input, row with id update produk of this toko, without id create new one
+func (uc *produkImportUsecase) importRow(job model.ProdukImport, columns map[string]int, row []string) error {
+	cell := func(name string) string {
+		i, exist := columns[name]
+		if !exist || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	for _, name := range requiredImportColumns {
+		if cell(name) == "" {
+			return fmt.Errorf("%w: %s is required", ErrProdukNotValid, name)
+		}
+	}
+	for _, name := range []string{"harga_reseller", "harga_konsumen"} {
+		if _, err := strconv.ParseUint(cell(name), 10, 64); err != nil {
+			return fmt.Errorf("%w: %s must be number", ErrProdukNotValid, name)
+		}
+	}
+	stok, err := strconv.Atoi(cell("stok"))
+	if err != nil || stok < 0 {
+		return fmt.Errorf("%w: stok must be number not negative", ErrProdukNotValid)
+	}
+	categoryID, err := strconv.ParseUint(cell("id_category"), 10, 32)
+	if err != nil || categoryID == 0 {
+		return fmt.Errorf("%w: id_category not valid", ErrProdukNotValid)
+	}
+
+	input := model.Produk{
+		NamaProduk:    cell("nama_produk"),
+		Slug:          cell("slug"),
+		HargaReseller: cell("harga_reseller"),
+		HargaKonsumen: cell("harga_konsumen"),
+		Stok:          stok,
+		Deskripsi:     cell("deskripsi"),
+		IDCategory:    uint(categoryID),
+		Status:        strings.ToLower(cell("status")),
+	}
+	if input.Slug == "" {
+		input.Slug = utils.Slugify(input.NamaProduk)
+	}
+
+	// empty cell = atribut not filled, column can come from other kategori
+	atribut := map[string]interface{}{}
+	for name := range columns {
+		if kode, isAtribut := strings.CutPrefix(name, importAtributPrefix); isAtribut && cell(name) != "" {
+			atribut[kode] = cell(name)
+		}
+	}
+
+	if cell("id") == "" {
+		_, err = uc.produkUsecase.CreateProduk(job.IDUser, job.IDToko, input, atribut)
+		return err
+	}
+	produkID, err := strconv.ParseUint(cell("id"), 10, 32)
+	if err != nil || produkID == 0 {
+		return fmt.Errorf("%w: id not valid", ErrProdukNotValid)
+	}
+	_, err = uc.produkUsecase.UpdateProduk(job.IDUser, job.IDToko, uint(produkID), input, atribut)
+	return err
+}
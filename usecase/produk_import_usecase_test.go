@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+	"testing"
+)
+
+type fakeImportRepo struct {
+	repository.ProdukImportRepository
+	updated []model.ProdukImport
+}
+
+func (r *fakeImportRepo) Update(job model.ProdukImport) (model.ProdukImport, error) {
+	r.updated = append(r.updated, job)
+	return job, nil
+}
+
+type fakeImportProdukUsecase struct {
+	ProdukUsecase
+	created []model.Produk
+	updated map[uint]model.Produk
+	atribut []map[string]interface{}
+}
+
+func (uc *fakeImportProdukUsecase) CreateProduk(userID, tokoID uint, input model.Produk, atribut map[string]interface{}) (model.Produk, error) {
+	if input.IDCategory == 99 {
+		return model.Produk{}, errors.New("produk not valid: kategori not found")
+	}
+	uc.created = append(uc.created, input)
+	uc.atribut = append(uc.atribut, atribut)
+	return input, nil
+}
+
+func (uc *fakeImportProdukUsecase) UpdateProduk(userID, tokoID, produkID uint, input model.Produk, atribut map[string]interface{}) (model.Produk, error) {
+	uc.updated[produkID] = input
+	return input, nil
+}
+
+func TestRunImportReportFailedRow(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "import.csv")
+	csv := "\ufeffnama_produk,harga_reseller,harga_konsumen,stok,deskripsi,id_category,ID,atribut.ram\n" +
+		"Laptop A,9000,10000,5,murah,1,,16\n" +
+		",9000,10000,5,tanpa nama,1,,\n" +
+		"\n" +
+		"Laptop B,9000,sepuluh ribu,5,mahal,1,,\n" +
+		"Laptop C,9000,10000,5,kategori salah,99,,\n" +
+		"Laptop D,9000,10000,2,lama,1,7,\n"
+	if err := os.WriteFile(file, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeImportRepo{}
+	produkUc := &fakeImportProdukUsecase{updated: map[uint]model.Produk{}}
+	uc := &produkImportUsecase{importRepo: repo, produkUsecase: produkUc}
+
+	job := uc.runImport(model.ProdukImport{ID: 1, IDUser: 2, IDToko: 3, Format: "csv", FilePath: file})
+	if job.Status != model.ImportStatusDone || job.TotalBaris != 5 || job.Berhasil != 2 || job.Gagal != 3 {
+		t.Fatalf("unexpected result %+v", job)
+	}
+	wantBaris := []int{3, 5, 6}
+	if len(job.Errors) != len(wantBaris) {
+		t.Fatalf("unexpected errors %+v", job.Errors)
+	}
+	for i, baris := range wantBaris {
+		if job.Errors[i].Baris != baris {
+			t.Fatalf("expected error %d at row %d, got %+v", i, baris, job.Errors[i])
+		}
+	}
+
+	if len(produkUc.created) != 1 || produkUc.created[0].Slug != "laptop-a" || produkUc.atribut[0]["ram"] != "16" {
+		t.Fatalf("unexpected created produk %+v %v", produkUc.created, produkUc.atribut)
+	}
+	if produkUc.updated[7].NamaProduk != "Laptop D" {
+		t.Fatalf("produk 7 must be updated, got %+v", produkUc.updated)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("import file must be removed, stat error %v", err)
+	}
+}
+
+func TestRunImportRejectUnknownColumn(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "import.csv")
+	if err := os.WriteFile(file, []byte("nama_produk,harga,stok\nLaptop,1,1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	uc := &produkImportUsecase{importRepo: &fakeImportRepo{}, produkUsecase: &fakeImportProdukUsecase{}}
+	job := uc.runImport(model.ProdukImport{ID: 1, Format: "csv", FilePath: file})
+	if job.Status != model.ImportStatusFailed || job.Pesan != "unknown column harga" || job.Berhasil != 0 {
+		t.Fatalf("unexpected result %+v", job)
+	}
+}
+
+type fakeActingTokoUsecase struct {
+	TokoMemberUsecase
+	status string
+}
+
+func (uc *fakeActingTokoUsecase) ResolveActingToko(userID, tokoID uint, permission string) (model.Toko, error) {
+	return model.Toko{ID: 3, Status: uc.status}, nil
+}
+
+type fakeExportProdukRepo struct {
+	repository.ProdukRepository
+	batches [][]model.Produk
+}
+
+func (r *fakeExportProdukRepo) FindAtributKodeByTokoID(tokoID uint) ([]string, error) {
+	return []string{"ram"}, nil
+}
+
+func (r *fakeExportProdukRepo) FindAllByTokoIDInBatches(tokoID uint, batchSize int, fn func(produks []model.Produk) error) error {
+	for _, batch := range r.batches {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportProdukWritePerBatch(t *testing.T) {
+	ram := &model.CategoryAtribut{Kode: "ram"}
+	repo := &fakeExportProdukRepo{batches: [][]model.Produk{
+		{{ID: 1, NamaProduk: "Laptop", Stok: 2, IDCategory: 1, Atribut: []model.ProdukAtribut{{Atribut: ram, Nilai: "16"}}}},
+		{{ID: 2, NamaProduk: "=Kaos", Stok: 5, IDCategory: 2}},
+	}}
+	uc := &produkImportUsecase{produkRepo: repo, tokoMemberUsecase: &fakeActingTokoUsecase{status: model.TokoStatusActive}}
+
+	var buffer bytes.Buffer
+	writer, err := utils.NewSpreadsheetWriter(&buffer, utils.SpreadsheetCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	if err := uc.ExportProduk(2, 0, writer); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id,nama_produk,slug,harga_reseller,harga_konsumen,stok,deskripsi,id_category,nama_category,status,atribut.ram\n" +
+		"1,Laptop,,,,2,,1,,,16\n" +
+		"2,'=Kaos,,,,5,,2,,,\n"
+	if buffer.String() != want {
+		t.Fatalf("expected %q, got %q", want, buffer.String())
+	}
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+// ProdukIndexer keep search and suggest index same as produk the public can see (model.Produk.IsPublic).
+// called by produk usecase on produk change and by toko, kategori and account usecase when
+// change of them show or hide produk, or rename what the index keep
+type ProdukIndexer interface {
+	IndexProduk(produkID uint)
+	UnindexProduk(produkID uint)
+	ReindexToko(tokoID uint)
+	ReindexUserToko(userID uint)
+	ReindexCategory(categoryID uint)
+
+	// toko with libur_sampai passed between from and to, their produk visible again
+	ReindexLiburEnded(from, to time.Time) (int, error)
+
+	// fill suggest index on startup, it is kept in memory only
+	BuildSuggestIndex() error
+}
+
+type produkIndexer struct {
+	produkRepo   repository.ProdukRepository
+	tokoRepo     repository.TokoRepository
+	searchIndex  repository.SearchIndex
+	suggestIndex repository.SuggestIndex
+}
+
+func NewProdukIndexer(
+	produkRepo repository.ProdukRepository,
+	tokoRepo repository.TokoRepository,
+	searchIndex repository.SearchIndex,
+	suggestIndex repository.SuggestIndex,
+) ProdukIndexer {
+	return &produkIndexer{produkRepo, tokoRepo, searchIndex, suggestIndex}
+}
+
+// failing to update search index must not cancel change that already saved
+func (ix *produkIndexer) IndexProduk(produkID uint) {
+	produk, err := ix.produkRepo.FindByID(produkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ix.UnindexProduk(produkID)
+			return
+		}
+		fmt.Printf("failed index produk %d: %v\n", produkID, err)
+		return
+	}
+	ix.index(produk, time.Now())
+}
+
+// produk must be loaded with Category and Toko
+func (ix *produkIndexer) index(produk model.Produk, now time.Time) {
+	if !produk.IsPublic(now) {
+		ix.UnindexProduk(produk.ID)
+		return
+	}
+
+	doc := repository.NewSearchDocument(produk)
+	if err := ix.searchIndex.Index(doc); err != nil {
+		fmt.Printf("failed index produk %d: %v\n", produk.ID, err)
+	}
+	if err := ix.suggestIndex.Index(doc); err != nil {
+		fmt.Printf("failed update suggestion of produk %d: %v\n", produk.ID, err)
+	}
+}
+
+func (ix *produkIndexer) UnindexProduk(produkID uint) {
+	if err := ix.searchIndex.Remove(produkID); err != nil {
+		fmt.Printf("failed remove produk %d from search index: %v\n", produkID, err)
+	}
+	if err := ix.suggestIndex.Remove(produkID); err != nil {
+		fmt.Printf("failed remove produk %d from suggestion: %v\n", produkID, err)
+	}
+}
+
+func (ix *produkIndexer) indexBatch(produks []model.Produk) error {
+	now := time.Now()
+	for _, produk := range produks {
+		ix.index(produk, now)
+	}
+	return nil
+}
+
+// after status, libur or nama toko change
+func (ix *produkIndexer) ReindexToko(tokoID uint) {
+	if err := ix.produkRepo.FindAllForIndexByTokoIDInBatches(tokoID, suggestBatchSize, ix.indexBatch); err != nil {
+		fmt.Printf("failed reindex produk of toko %d: %v\n", tokoID, err)
+	}
+}
+
+// toko owned by user, nothing when user has no toko
+func (ix *produkIndexer) ReindexUserToko(userID uint) {
+	toko, err := ix.tokoRepo.FindByUserID(userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			fmt.Printf("failed get toko of user %d for reindex: %v\n", userID, err)
+		}
+		return
+	}
+	ix.ReindexToko(toko.ID)
+}
+
+// after nama kategori change or produk moved into it
+func (ix *produkIndexer) ReindexCategory(categoryID uint) {
+	if err := ix.produkRepo.FindAllForIndexByCategoryIDInBatches(categoryID, suggestBatchSize, ix.indexBatch); err != nil {
+		fmt.Printf("failed reindex produk of kategori %d: %v\n", categoryID, err)
+	}
+}
+
+func (ix *produkIndexer) ReindexLiburEnded(from, to time.Time) (int, error) {
+	tokoIDs, err := ix.tokoRepo.FindLiburEndedBetween(from, to)
+	if err != nil {
+		return 0, fmt.Errorf("failed get toko with libur ended: %w", err)
+	}
+	for _, tokoID := range tokoIDs {
+		ix.ReindexToko(tokoID)
+	}
+	return len(tokoIDs), nil
+}
+
+// index all public produk, change after this come through IndexProduk and Reindex*
+func (ix *produkIndexer) BuildSuggestIndex() error {
+	return ix.produkRepo.FindAllPublicInBatches(suggestBatchSize, func(produks []model.Produk) error {
+		for _, produk := range produks {
+			if err := ix.suggestIndex.Index(repository.NewSearchDocument(produk)); err != nil {
+				return fmt.Errorf("failed index suggestion of produk %d: %w", produk.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// StartLiburIndexJob put back produk of toko whose libur_sampai passed, every interval. call returned func to stop
+func StartLiburIndexJob(ix ProdukIndexer, interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		// zero on first run, also cover libur ended while server was down
+		var from time.Time
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				ticker.Stop()
+				return
+			}
+
+			to := time.Now()
+			reindexed, err := ix.ReindexLiburEnded(from, to)
+			if err != nil {
+				fmt.Printf("libur index job: %v\n", err)
+				continue
+			}
+			if reindexed > 0 {
+				fmt.Printf("libur index job: produk of %d toko back in index\n", reindexed)
+			}
+			from = to
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+)
+
+type fakeProdukIndexer struct {
+	ProdukIndexer
+	tokoIDs     []uint
+	userIDs     []uint
+	categoryIDs []uint
+}
+
+func (ix *fakeProdukIndexer) ReindexToko(tokoID uint) {
+	ix.tokoIDs = append(ix.tokoIDs, tokoID)
+}
+
+func (ix *fakeProdukIndexer) ReindexUserToko(userID uint) {
+	ix.userIDs = append(ix.userIDs, userID)
+}
+
+func (ix *fakeProdukIndexer) ReindexCategory(categoryID uint) {
+	ix.categoryIDs = append(ix.categoryIDs, categoryID)
+}
+
+// produk share toko pointer, so change to toko show in every produk like a fresh preload
+type fakeIndexProdukRepo struct {
+	repository.ProdukRepository
+	produks []model.Produk
+}
+
+func (r *fakeIndexProdukRepo) FindAllForIndexByTokoIDInBatches(tokoID uint, batchSize int, fn func(produks []model.Produk) error) error {
+	var found []model.Produk
+	for _, produk := range r.produks {
+		if produk.IDToko == tokoID {
+			found = append(found, produk)
+		}
+	}
+	return fn(found)
+}
+
+func suggestTeks(t *testing.T, index repository.SuggestIndex, query string) []string {
+	suggestions, err := index.Suggest(query, 10)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	teks := []string{}
+	for _, suggestion := range suggestions {
+		teks = append(teks, suggestion.Teks)
+	}
+	return teks
+}
+
+func TestReindexTokoFollowVisibility(t *testing.T) {
+	toko := &model.Toko{ID: 1, NamaToko: "Toko Budi", Status: model.TokoStatusActive}
+	produkRepo := &fakeIndexProdukRepo{produks: []model.Produk{
+		{ID: 1, IDToko: 1, NamaProduk: "Laptop Asus", Status: model.ProdukStatusPublished, Toko: toko},
+		{ID: 2, IDToko: 1, NamaProduk: "Laptop Acer", Status: model.ProdukStatusDraft, Toko: toko},
+	}}
+	suggestIndex := repository.NewMemorySuggestIndex()
+	ix := NewProdukIndexer(produkRepo, &fakeTokoRepo{}, repository.NewMemorySearchIndex(), suggestIndex)
+
+	ix.ReindexToko(1)
+	if got := suggestTeks(t, suggestIndex, "laptop"); len(got) != 1 || got[0] != "Laptop Asus" {
+		t.Fatalf("expected only published produk suggested, got %v", got)
+	}
+
+	toko.NamaToko = "Toko Budi Jaya"
+	ix.ReindexToko(1)
+	if got := suggestTeks(t, suggestIndex, "toko budi j"); len(got) != 1 || got[0] != "Toko Budi Jaya" {
+		t.Fatalf("expected new nama toko suggested, got %v", got)
+	}
+
+	liburSampai := time.Now().Add(time.Hour)
+	toko.IsLibur, toko.LiburSampai = true, &liburSampai
+	ix.ReindexToko(1)
+	if got := suggestTeks(t, suggestIndex, "laptop"); len(got) != 0 {
+		t.Fatalf("expected produk of toko on libur removed, got %v", got)
+	}
+
+	toko.IsLibur, toko.LiburSampai = false, nil
+	toko.Status = model.TokoStatusSuspended
+	ix.ReindexToko(1)
+	if got := suggestTeks(t, suggestIndex, "toko"); len(got) != 0 {
+		t.Fatalf("expected produk of suspended toko removed, got %v", got)
+	}
+}
@@ -3,9 +3,12 @@ package usecase
 import (
 	"errors"
 	"fmt"
+	"os"
 	"rakamin-evermos/model"
 	"rakamin-evermos/repository"
 	"rakamin-evermos/utils"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,42 +16,338 @@ import (
 
 type ProdukUsecase interface {
 	// public accessible 
-	GetAllProduk(pagination utils.PaginationInput, filter repository.FilterInput) (utils.PaginationResult, error)
+	GetAllProduk(pagination utils.PaginationInput, filter repository.FilterInput) (ProdukListResult, error)
+	GetAllProdukCursor(cursor utils.CursorInput, filter repository.FilterInput) (ProdukCursorResult, error)
 	GetProdukByID(produkID uint) (model.Produk, error)
+	GetSuggestions(query string, limit int) ([]ProdukSuggestion, error)
 
-	// seller only
-	CreateProduk(userID uint, input model.Produk) (model.Produk, error)
-	GetMyProduk(userID uint, pagination utils.PaginationInput, filter repository.FilterInput) (utils.PaginationResult, error)
-	UpdateProduk(userID, produkID uint, input model.Produk) (model.Produk, error)
-	DeleteProduk(userID, produkID uint) error
-	UploadFotoProduk(userID, produkID uint, filePath string) (model.FotoProduk, error)
+	// seller only, tokoID is acting toko (0 = default toko of user)
+	// atribut is nilai by kode, checked with atribut schema of kategori
+	CreateProduk(userID, tokoID uint, input model.Produk, atribut map[string]interface{}) (model.Produk, error)
+	GetMyProduk(userID, tokoID uint, pagination utils.PaginationInput, filter repository.FilterInput) (utils.PaginationResult, error)
+	GetMyProdukCursor(userID, tokoID uint, cursor utils.CursorInput, filter repository.FilterInput) (utils.CursorResult, error)
+	UpdateProduk(userID, tokoID, produkID uint, input model.Produk, atribut map[string]interface{}) (model.Produk, error)
+	SetProdukStatus(userID, tokoID, produkID uint, status string) (model.Produk, error)
+	DeleteProduk(userID, tokoID, produkID uint) error // to trash, restorable until purged
+	GetDeletedProduk(userID, tokoID uint, pagination utils.PaginationInput) (utils.PaginationResult, error)
+	RestoreProduk(userID, tokoID, produkID uint) (model.Produk, error)
+	UploadFotoProduk(userID, tokoID, produkID uint, filePath string) (model.FotoProduk, error)
+
+
+	// remove foto of produk in trash longer than purge grace, return total purged
+	PurgeDeletedProduk(now time.Time) (int, error)
+}
+
+// kategori or atribut input not valid, handler return 400
+var ErrProdukNotValid = errors.New("produk not valid")
+
+// search hit used for one list request, result has search_truncated = true when index found more
+const maxSearchHits = 1000
+
+const (
+	defaultProdukPurgeGrace = 30 * 24 * time.Hour
+	produkPurgeBatchSize    = 200
+)
+
+// PRODUK_PURGE_DAYS, days produk stay in trash before purged (default 30)
+func ProdukPurgeGraceFromEnv() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("PRODUK_PURGE_DAYS"))
+	if err != nil || days < 0 {
+		return defaultProdukPurgeGrace
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 20
+	maxSuggestQuery     = 100 // char
+	suggestBatchSize    = 500
+)
+
+// tipe is produk, kategori or toko, koreksi true when q has typo
+type ProdukSuggestion struct {
+	Tipe    string `json:"tipe"`
+	ID      uint   `json:"id"`
+	Teks    string `json:"teks"`
+	Koreksi bool   `json:"koreksi"`
+}
+
+// upper bound (exclusive) of harga facet bucket, last bucket has no max
+var hargaFacetBounds = []int64{50000, 100000, 250000, 500000, 1000000}
+
+// page of produk with facet, pagination field stay on top level
+type ProdukListResult struct {
+	utils.PaginationResult
+	Facets          ProdukFacets `json:"facets"`
+	SearchTruncated bool         `json:"search_truncated"` // only best maxSearchHits match listed, refine the search to see others
+}
+
+// facet only on first page, next page use same filter
+type ProdukCursorResult struct {
+	utils.CursorResult
+	Facets          *ProdukFacets `json:"facets,omitempty"`
+	SearchTruncated bool          `json:"search_truncated"`
+}
+
+type ProdukFacets struct {
+	Category []CategoryFacet `json:"category"`
+	Harga    []HargaFacet    `json:"harga"`
+}
+
+type CategoryFacet struct {
+	IDCategory   uint   `json:"id_category"`
+	NamaCategory string `json:"nama_category"`
+	Jumlah       int64  `json:"jumlah"`
+}
+
+// Min inclusive, Max exclusive, nil Max = no upper bound
+type HargaFacet struct {
+	Min    int64  `json:"min"`
+	Max    *int64 `json:"max"`
+	Jumlah int64  `json:"jumlah"`
 }
 
 type produkUsecase struct {
 	produkRepo     repository.ProdukRepository
 	fotoProdukRepo repository.FotoProdukRepository
-	tokoRepo       repository.TokoRepository 
+	tokoMemberUsecase TokoMemberUsecase
+	categoryUsecase   CategoryUsecase
+	searchIndex       repository.SearchIndex
+	suggestIndex      repository.SuggestIndex
+	indexer           ProdukIndexer
+	purgeGrace        time.Duration
+}
+
+func NewProdukUsecase(
+	produkRepo repository.ProdukRepository,
+	fotoProdukRepo repository.FotoProdukRepository,
+	tokoMemberUsecase TokoMemberUsecase,
+	categoryUsecase CategoryUsecase,
+	searchIndex repository.SearchIndex,
+	suggestIndex repository.SuggestIndex,
+	indexer ProdukIndexer,
+	purgeGrace time.Duration,
+) ProdukUsecase {
+	return &produkUsecase{produkRepo, fotoProdukRepo, tokoMemberUsecase, categoryUsecase, searchIndex, suggestIndex, indexer, purgeGrace}
 }
 
-func NewProdukUsecase(produkRepo repository.ProdukRepository, fotoProdukRepo repository.FotoProdukRepository, tokoRepo repository.TokoRepository) ProdukUsecase {
-	return &produkUsecase{produkRepo, fotoProdukRepo, tokoRepo}
+// empty status = keep current, new produk published unless sent as draft
+func checkProdukStatus(status string, allowed ...string) error {
+	for _, value := range allowed {
+		if status == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: status must be one of %v", ErrProdukNotValid, allowed)
+}
+
+// ValidateProdukAtribut check input against schema and return nilai in schema order.
+// kode not in schema rejected, wajib atribut must be filled, empty nilai skipped
+func ValidateProdukAtribut(schema []model.CategoryAtribut, input map[string]interface{}) ([]model.ProdukAtribut, error) {
+	known := map[string]bool{}
+	for _, atribut := range schema {
+		known[atribut.Kode] = true
+	}
+	for kode := range input {
+		if !known[kode] {
+			return nil, fmt.Errorf("%w: atribut %s not exist in kategori", ErrProdukNotValid, kode)
+		}
+	}
+
+	nilai := []model.ProdukAtribut{}
+	for _, atribut := range schema {
+		value, filled := input[atribut.Kode]
+		if text, ok := value.(string); ok && strings.TrimSpace(text) == "" {
+			filled = false
+		}
+		if !filled || value == nil {
+			if atribut.Wajib {
+				return nil, fmt.Errorf("%w: atribut %s is required", ErrProdukNotValid, atribut.Kode)
+			}
+			continue
+		}
+
+		produkAtribut := model.ProdukAtribut{IDAtribut: atribut.ID}
+		switch atribut.Tipe {
+		case model.AtributTipeNumber:
+			var number float64
+			switch v := value.(type) {
+			case float64:
+				number = v
+			case string:
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: atribut %s must be number", ErrProdukNotValid, atribut.Kode)
+				}
+				number = parsed
+			default:
+				return nil, fmt.Errorf("%w: atribut %s must be number", ErrProdukNotValid, atribut.Kode)
+			}
+			produkAtribut.Nilai = strconv.FormatFloat(number, 'f', -1, 64)
+			produkAtribut.NilaiAngka = &number
+		case model.AtributTipeEnum:
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: atribut %s must be one of %v", ErrProdukNotValid, atribut.Kode, atribut.Opsi)
+			}
+			for _, opsi := range atribut.Opsi {
+				if strings.EqualFold(opsi, strings.TrimSpace(text)) {
+					produkAtribut.Nilai = opsi
+				}
+			}
+			if produkAtribut.Nilai == "" {
+				return nil, fmt.Errorf("%w: atribut %s must be one of %v", ErrProdukNotValid, atribut.Kode, atribut.Opsi)
+			}
+		default:
+			text, ok := value.(string)
+			if !ok || len(strings.TrimSpace(text)) > 255 {
+				return nil, fmt.Errorf("%w: atribut %s must be text max 255 char", ErrProdukNotValid, atribut.Kode)
+			}
+			produkAtribut.Nilai = strings.TrimSpace(text)
+		}
+		nilai = append(nilai, produkAtribut)
+	}
+	return nilai, nil
+}
+
+// kategori must exist, atribut checked against schema of kategori and its parent
+func (uc *produkUsecase) buildAtribut(categoryID uint, input map[string]interface{}, now time.Time) ([]model.ProdukAtribut, error) {
+	schema, err := uc.categoryUsecase.GetAtribut(categoryID)
+	if err != nil {
+		if errors.Is(err, ErrCategoryNotFound) {
+			return nil, fmt.Errorf("%w: kategori not found", ErrProdukNotValid)
+		}
+		return nil, err
+	}
+	nilai, err := ValidateProdukAtribut(schema, input)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nilai {
+		nilai[i].CreatedAtDate = now
+		nilai[i].UpdatedAtDate = now
+	}
+	return nilai, nil
 }
 
 
 // Public Accessible
 
 // get all produk with pagination & filtering
-func (uc *produkUsecase) GetAllProduk(pagination utils.PaginationInput, filter repository.FilterInput) (utils.PaginationResult, error) {
+func (uc *produkUsecase) GetAllProduk(pagination utils.PaginationInput, filter repository.FilterInput) (ProdukListResult, error) {
+	filter, truncated, err := uc.searchProduk(filter)
+	if err != nil {
+		return ProdukListResult{}, err
+	}
+
 	produks, totalData, err := uc.produkRepo.FindAll(pagination, filter)
 	if err != nil {
-		return utils.PaginationResult{}, fmt.Errorf("failed get produk: %w", err)
+		return ProdukListResult{}, fmt.Errorf("failed get produk: %w", err)
+	}
+
+	facets, err := uc.getFacets(filter)
+	if err != nil {
+		return ProdukListResult{}, err
 	}
 
 	// format result from utils
 	result := utils.GeneratePaginationResult(produks, totalData, pagination.Page, pagination.Limit)
+	return ProdukListResult{PaginationResult: result, Facets: facets, SearchTruncated: truncated}, nil
+}
+
+// same filter as GetAllProduk, page by cursor instead of page number
+func (uc *produkUsecase) GetAllProdukCursor(cursor utils.CursorInput, filter repository.FilterInput) (ProdukCursorResult, error) {
+	after, err := utils.DecodeCursor(cursor.Cursor)
+	if err != nil {
+		return ProdukCursorResult{}, err
+	}
+	filter, truncated, err := uc.searchProduk(filter)
+	if err != nil {
+		return ProdukCursorResult{}, err
+	}
+
+	produks, next, err := uc.produkRepo.FindAllAfter(after, cursor.Limit, filter)
+	if err != nil {
+		return ProdukCursorResult{}, fmt.Errorf("failed get produk: %w", err)
+	}
+	page, err := utils.GenerateCursorResult(produks, next, cursor.Limit)
+	if err != nil {
+		return ProdukCursorResult{}, err
+	}
+
+	result := ProdukCursorResult{CursorResult: page, SearchTruncated: truncated}
+	if after == nil {
+		facets, err := uc.getFacets(filter)
+		if err != nil {
+			return ProdukCursorResult{}, err
+		}
+		result.Facets = &facets
+	}
 	return result, nil
 }
 
+// search text ranked by search index, repository keep the order.
+// one extra hit asked to know if more than maxSearchHits match
+func (uc *produkUsecase) searchProduk(filter repository.FilterInput) (repository.FilterInput, bool, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	if filter.Search == "" {
+		return filter, false, nil
+	}
+	hits, err := uc.searchIndex.Search(filter.Search, maxSearchHits+1)
+	if err != nil {
+		return filter, false, fmt.Errorf("failed search produk: %w", err)
+	}
+	truncated := len(hits) > maxSearchHits
+	if truncated {
+		hits = hits[:maxSearchHits]
+	}
+	filter.SearchIDs = make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		filter.SearchIDs = append(filter.SearchIDs, hit.ProdukID)
+	}
+	return filter, truncated, nil
+}
+
+// each facet ignore its own filter, so other choice of it still counted
+func (uc *produkUsecase) getFacets(filter repository.FilterInput) (ProdukFacets, error) {
+	facets := ProdukFacets{Category: []CategoryFacet{}, Harga: []HargaFacet{}}
+
+	categoryFilter := filter
+	categoryFilter.CategoryID = 0
+	categoryCounts, err := uc.produkRepo.CountByCategory(categoryFilter)
+	if err != nil {
+		return facets, fmt.Errorf("failed count produk per kategori: %w", err)
+	}
+	for _, count := range categoryCounts {
+		facets.Category = append(facets.Category, CategoryFacet{
+			IDCategory:   count.IDCategory,
+			NamaCategory: count.NamaCategory,
+			Jumlah:       count.Jumlah,
+		})
+	}
+
+	hargaFilter := filter
+	hargaFilter.MinHarga = nil
+	hargaFilter.MaxHarga = nil
+	hargaCounts, err := uc.produkRepo.CountByHarga(hargaFilter, hargaFacetBounds)
+	if err != nil {
+		return facets, fmt.Errorf("failed count produk per harga: %w", err)
+	}
+	for i, jumlah := range hargaCounts {
+		bucket := HargaFacet{Jumlah: jumlah}
+		if i > 0 {
+			bucket.Min = hargaFacetBounds[i-1]
+		}
+		if i < len(hargaFacetBounds) {
+			max := hargaFacetBounds[i]
+			bucket.Max = &max
+		}
+		facets.Harga = append(facets.Harga, bucket)
+	}
+	return facets, nil
+}
+
 // get detail one produk
 func (uc *produkUsecase) GetProdukByID(produkID uint) (model.Produk, error) {
 	produk, err := uc.produkRepo.FindByID(produkID)
@@ -58,25 +357,56 @@ func (uc *produkUsecase) GetProdukByID(produkID uint) (model.Produk, error) {
 		}
 		return produk, fmt.Errorf("failed get produk: %w", err)
 	}
+	// same rule as public list: draft, archived, produk of toko not active or in mode libur is not public
+	if !produk.IsPublic(time.Now()) {
+		return model.Produk{}, errors.New("produk not found")
+	}
 	return produk, nil
 }
 
- // seller only
+// search as you type, q matched as prefix and typo corrected
+func (uc *produkUsecase) GetSuggestions(query string, limit int) ([]ProdukSuggestion, error) {
+	query = strings.TrimSpace(query)
+	if runes := []rune(query); len(runes) > maxSuggestQuery {
+		query = string(runes[:maxSuggestQuery])
+	}
+	if limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
 
-func (uc *produkUsecase) getTokoByUserID(userID uint) (model.Toko, error) {
-	toko, err := uc.tokoRepo.FindByUserID(userID)
+	hits, err := uc.suggestIndex.Suggest(query, limit)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return toko, errors.New("u dont have toko. go register as seller first")
-		}
-		return toko, fmt.Errorf("failed verify your toko: %w", err)
+		return nil, fmt.Errorf("failed get suggestion: %w", err)
+	}
+	suggestions := make([]ProdukSuggestion, 0, len(hits))
+	for _, hit := range hits {
+		suggestions = append(suggestions, ProdukSuggestion{
+			Tipe:    hit.Tipe,
+			ID:      hit.ID,
+			Teks:    hit.Teks,
+			Koreksi: hit.Koreksi,
+		})
 	}
-	return toko, nil
+	return suggestions, nil
+}
+
+ // seller only
+
+// toko where user is staff with permission, not only owned toko
+func (uc *produkUsecase) getActingToko(userID, tokoID uint, permission string) (model.Toko, error) {
+	return uc.tokoMemberUsecase.ResolveActingToko(userID, tokoID, permission)
+}
+
+func (uc *produkUsecase) getWritableToko(userID, tokoID uint) (model.Toko, error) {
+	return resolveWritableToko(uc.tokoMemberUsecase, userID, tokoID)
 }
 
-func (uc *produkUsecase) CreateProduk(userID uint, input model.Produk) (model.Produk, error) {
+func (uc *produkUsecase) CreateProduk(userID, tokoID uint, input model.Produk, atribut map[string]interface{}) (model.Produk, error) {
 	// get toko user first
-	toko, err := uc.getTokoByUserID(userID)
+	toko, err := uc.getWritableToko(userID, tokoID)
 	if err != nil {
 		return model.Produk{}, err
 	}
@@ -84,7 +414,18 @@ func (uc *produkUsecase) CreateProduk(userID uint, input model.Produk) (model.Pr
 	// Set IDToko base toko login
 	input.IDToko = toko.ID
 
+	if input.Status == "" {
+		input.Status = model.ProdukStatusPublished
+	}
+	if err := checkProdukStatus(input.Status, model.ProdukStatusDraft, model.ProdukStatusPublished); err != nil {
+		return model.Produk{}, err
+	}
+
 	now := time.Now()
+	input.Atribut, err = uc.buildAtribut(input.IDCategory, atribut, now)
+	if err != nil {
+		return model.Produk{}, err
+	}
 	input.CreatedAtDate = now
 	input.UpdatedAtDate = now
 
@@ -92,13 +433,14 @@ func (uc *produkUsecase) CreateProduk(userID uint, input model.Produk) (model.Pr
 	if err != nil {
 		return savedProduk, fmt.Errorf("failed save produk: %w", err)
 	}
+	uc.indexer.IndexProduk(savedProduk.ID)
 	return savedProduk, nil
 }
 
 // get produk owned by toko user with pagination & filtering
-func (uc *produkUsecase) GetMyProduk(userID uint, pagination utils.PaginationInput, filter repository.FilterInput) (utils.PaginationResult, error) {
+func (uc *produkUsecase) GetMyProduk(userID, tokoID uint, pagination utils.PaginationInput, filter repository.FilterInput) (utils.PaginationResult, error) {
 	// get toko user first
-	toko, err := uc.getTokoByUserID(userID)
+	toko, err := uc.getActingToko(userID, tokoID, model.TokoPermProdukRead)
 	if err != nil {
 		return utils.PaginationResult{}, err
 	}
@@ -113,8 +455,25 @@ func (uc *produkUsecase) GetMyProduk(userID uint, pagination utils.PaginationInp
 	return result, nil
 }
 
-func (uc *produkUsecase) UpdateProduk(userID, produkID uint, input model.Produk) (model.Produk, error) {
-	toko, err := uc.getTokoByUserID(userID)
+func (uc *produkUsecase) GetMyProdukCursor(userID, tokoID uint, cursor utils.CursorInput, filter repository.FilterInput) (utils.CursorResult, error) {
+	after, err := utils.DecodeCursor(cursor.Cursor)
+	if err != nil {
+		return utils.CursorResult{}, err
+	}
+	toko, err := uc.getActingToko(userID, tokoID, model.TokoPermProdukRead)
+	if err != nil {
+		return utils.CursorResult{}, err
+	}
+
+	produks, next, err := uc.produkRepo.FindAllByTokoIDAfter(toko.ID, after, cursor.Limit, filter)
+	if err != nil {
+		return utils.CursorResult{}, fmt.Errorf("failed get your produk: %w", err)
+	}
+	return utils.GenerateCursorResult(produks, next, cursor.Limit)
+}
+
+func (uc *produkUsecase) UpdateProduk(userID, tokoID, produkID uint, input model.Produk, atribut map[string]interface{}) (model.Produk, error) {
+	toko, err := uc.getWritableToko(userID, tokoID)
 	if err != nil {
 		return model.Produk{}, err
 	}
@@ -135,16 +494,57 @@ func (uc *produkUsecase) UpdateProduk(userID, produkID uint, input model.Produk)
 	existingProduk.Deskripsi = input.Deskripsi
 	existingProduk.IDCategory = input.IDCategory
 	existingProduk.UpdatedAtDate = time.Now()
+	if input.Status != "" {
+		if err := checkProdukStatus(input.Status, model.ProdukStatusDraft, model.ProdukStatusPublished, model.ProdukStatusArchived); err != nil {
+			return model.Produk{}, err
+		}
+		existingProduk.Status = input.Status
+	}
+
+	// atribut always replaced, also when kategori changed
+	existingProduk.Atribut, err = uc.buildAtribut(input.IDCategory, atribut, existingProduk.UpdatedAtDate)
+	if err != nil {
+		return model.Produk{}, err
+	}
 
 	updatedProduk, err := uc.produkRepo.Update(existingProduk)
 	if err != nil {
 		return updatedProduk, fmt.Errorf("failed update produk: %w", err)
 	}
+	uc.indexer.IndexProduk(updatedProduk.ID)
 	return updatedProduk, nil
 }
 
-func (uc *produkUsecase) DeleteProduk(userID, produkID uint) error {
-	toko, err := uc.getTokoByUserID(userID)
+// archive hide produk from public, publish again to show it
+func (uc *produkUsecase) SetProdukStatus(userID, tokoID, produkID uint, status string) (model.Produk, error) {
+	if err := checkProdukStatus(status, model.ProdukStatusDraft, model.ProdukStatusPublished, model.ProdukStatusArchived); err != nil {
+		return model.Produk{}, err
+	}
+	toko, err := uc.getWritableToko(userID, tokoID)
+	if err != nil {
+		return model.Produk{}, err
+	}
+
+	existingProduk, err := uc.produkRepo.FindByTokoIDAndProdukID(toko.ID, produkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Produk{}, errors.New("produk not found atau u dont have access")
+		}
+		return model.Produk{}, fmt.Errorf("failed verify produk: %w", err)
+	}
+
+	existingProduk.Status = status
+	existingProduk.UpdatedAtDate = time.Now()
+	updatedProduk, err := uc.produkRepo.Update(existingProduk)
+	if err != nil {
+		return updatedProduk, fmt.Errorf("failed update status produk: %w", err)
+	}
+	uc.indexer.IndexProduk(updatedProduk.ID)
+	return updatedProduk, nil
+}
+
+func (uc *produkUsecase) DeleteProduk(userID, tokoID, produkID uint) error {
+	toko, err := uc.getWritableToko(userID, tokoID)
 	if err != nil {
 		return err
 	}
@@ -160,11 +560,100 @@ func (uc *produkUsecase) DeleteProduk(userID, produkID uint) error {
 	if err := uc.produkRepo.Delete(existingProduk); err != nil {
 		return fmt.Errorf("failed delete produk: %w", err)
 	}
+	uc.indexer.UnindexProduk(existingProduk.ID)
 	return nil
 }
 
-func (uc *produkUsecase) UploadFotoProduk(userID, produkID uint, filePath string) (model.FotoProduk, error) {
-	toko, err := uc.getTokoByUserID(userID)
+// produk in trash of toko, newest deleted first
+func (uc *produkUsecase) GetDeletedProduk(userID, tokoID uint, pagination utils.PaginationInput) (utils.PaginationResult, error) {
+	toko, err := uc.getActingToko(userID, tokoID, model.TokoPermProdukRead)
+	if err != nil {
+		return utils.PaginationResult{}, err
+	}
+
+	produks, totalData, err := uc.produkRepo.FindDeletedByTokoID(toko.ID, pagination)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("failed get deleted produk: %w", err)
+	}
+	return utils.GeneratePaginationResult(produks, totalData, pagination.Page, pagination.Limit), nil
+}
+
+// back from trash with same status, foto already purged can't come back
+func (uc *produkUsecase) RestoreProduk(userID, tokoID, produkID uint) (model.Produk, error) {
+	toko, err := uc.getWritableToko(userID, tokoID)
+	if err != nil {
+		return model.Produk{}, err
+	}
+
+	deletedProduk, err := uc.produkRepo.FindDeletedByTokoIDAndProdukID(toko.ID, produkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Produk{}, errors.New("produk not found in trash")
+		}
+		return model.Produk{}, fmt.Errorf("failed verify produk: %w", err)
+	}
+	if !deletedProduk.DeletedAt.Time.After(time.Now().Add(-uc.purgeGrace)) {
+		return model.Produk{}, fmt.Errorf("%w: produk already purged, can't be restored", ErrProdukNotValid)
+	}
+
+	restoredProduk, err := uc.produkRepo.Restore(deletedProduk)
+	if err != nil {
+		return restoredProduk, fmt.Errorf("failed restore produk: %w", err)
+	}
+	uc.indexer.IndexProduk(restoredProduk.ID)
+	return restoredProduk, nil
+}
+
+func (uc *produkUsecase) PurgeDeletedProduk(now time.Time) (int, error) {
+	produks, err := uc.produkRepo.FindPurgeable(now.Add(-uc.purgeGrace), produkPurgeBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed get produk to purge: %w", err)
+	}
+
+	purged := 0
+	for _, produk := range produks {
+		removedFiles, err := uc.produkRepo.Purge(produk.ID)
+		if err != nil {
+			fmt.Printf("failed purge produk %d: %v\n", produk.ID, err)
+			continue
+		}
+		// file removed after commit, leftover file is better than missing file for live data
+		for _, path := range removedFiles {
+			removeUploadedFile(path)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartProdukPurgeJob purge produk past trash grace period every interval, call returned func to stop
+func StartProdukPurgeJob(uc ProdukUsecase, interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			purged, err := uc.PurgeDeletedProduk(time.Now())
+			if err != nil {
+				fmt.Printf("produk purge job: %v\n", err)
+			} else if purged > 0 {
+				fmt.Printf("produk purge job: %d produk purged\n", purged)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func (uc *produkUsecase) UploadFotoProduk(userID, tokoID, produkID uint, filePath string) (model.FotoProduk, error) {
+	toko, err := uc.getWritableToko(userID, tokoID)
 	if err != nil {
 		return model.FotoProduk{}, err
 	}
@@ -189,4 +678,5 @@ func (uc *produkUsecase) UploadFotoProduk(userID, produkID uint, filePath string
 		return savedFoto, fmt.Errorf("failed save foto produk: %w", err)
 	}
 	return savedFoto, nil
-}
\ No newline at end of file
+}
+
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+func TestValidateProdukAtribut(t *testing.T) {
+	schema := []model.CategoryAtribut{
+		{ID: 1, Kode: "brand", Tipe: model.AtributTipeString, Wajib: true},
+		{ID: 2, Kode: "ram", Tipe: model.AtributTipeNumber},
+		{ID: 3, Kode: "warna", Tipe: model.AtributTipeEnum, Opsi: model.StringList{"Hitam", "Putih"}},
+	}
+
+	nilai, err := ValidateProdukAtribut(schema, map[string]interface{}{"brand": " Asus ", "ram": "16", "warna": "hitam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nilai) != 3 || nilai[0].Nilai != "Asus" || nilai[1].Nilai != "16" || *nilai[1].NilaiAngka != 16 || nilai[2].Nilai != "Hitam" {
+		t.Fatalf("unexpected nilai %+v", nilai)
+	}
+
+	// json number and empty optional atribut
+	nilai, err = ValidateProdukAtribut(schema, map[string]interface{}{"brand": "Asus", "ram": 8.5, "warna": ""})
+	if err != nil || len(nilai) != 2 || nilai[1].Nilai != "8.5" {
+		t.Fatalf("unexpected result %+v, %v", nilai, err)
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"missing wajib":  {"ram": 8},
+		"empty wajib":    {"brand": "  "},
+		"unknown kode":   {"brand": "Asus", "cpu": "i7"},
+		"not number":     {"brand": "Asus", "ram": "banyak"},
+		"bool as number": {"brand": "Asus", "ram": true},
+		"not in opsi":    {"brand": "Asus", "warna": "Merah"},
+		"number as text": {"brand": 10},
+	}
+	for name, input := range invalid {
+		if _, err := ValidateProdukAtribut(schema, input); !errors.Is(err, ErrProdukNotValid) {
+			t.Errorf("%s: expected ErrProdukNotValid, got %v", name, err)
+		}
+	}
+}
+
+type fakeProdukRepo struct {
+	repository.ProdukRepository
+	produks        []model.Produk
+	categoryFilter repository.FilterInput
+	hargaFilter    repository.FilterInput
+}
+
+func (r *fakeProdukRepo) FindByID(produkID uint) (model.Produk, error) {
+	for _, produk := range r.produks {
+		if produk.ID == produkID {
+			return produk, nil
+		}
+	}
+	return model.Produk{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeProdukRepo) CountByCategory(filter repository.FilterInput) ([]repository.CategoryCount, error) {
+	r.categoryFilter = filter
+	return []repository.CategoryCount{{IDCategory: 2, NamaCategory: "Laptop", Jumlah: 5}}, nil
+}
+
+func (r *fakeProdukRepo) CountByHarga(filter repository.FilterInput, bounds []int64) ([]int64, error) {
+	r.hargaFilter = filter
+	counts := make([]int64, len(bounds)+1)
+	counts[0] = 3
+	counts[len(bounds)] = 1
+	return counts, nil
+}
+
+func TestGetFacetsIgnoreOwnFilter(t *testing.T) {
+	repo := &fakeProdukRepo{}
+	uc := &produkUsecase{produkRepo: repo}
+	min, max := int64(1000), int64(90000)
+	filter := repository.FilterInput{CategoryID: 2, MinHarga: &min, MaxHarga: &max, InStock: true}
+
+	facets, err := uc.getFacets(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.categoryFilter.CategoryID != 0 || repo.categoryFilter.MinHarga == nil || !repo.categoryFilter.InStock {
+		t.Fatalf("unexpected kategori facet filter %+v", repo.categoryFilter)
+	}
+	if repo.hargaFilter.CategoryID != 2 || repo.hargaFilter.MinHarga != nil || repo.hargaFilter.MaxHarga != nil {
+		t.Fatalf("unexpected harga facet filter %+v", repo.hargaFilter)
+	}
+
+	if len(facets.Category) != 1 || facets.Category[0].Jumlah != 5 {
+		t.Fatalf("unexpected kategori facet %+v", facets.Category)
+	}
+	if len(facets.Harga) != len(hargaFacetBounds)+1 {
+		t.Fatalf("expected %d harga bucket, got %d", len(hargaFacetBounds)+1, len(facets.Harga))
+	}
+	first, last := facets.Harga[0], facets.Harga[len(facets.Harga)-1]
+	if first.Min != 0 || *first.Max != hargaFacetBounds[0] || first.Jumlah != 3 {
+		t.Fatalf("unexpected first bucket %+v", first)
+	}
+	if last.Min != hargaFacetBounds[len(hargaFacetBounds)-1] || last.Max != nil || last.Jumlah != 1 {
+		t.Fatalf("unexpected last bucket %+v", last)
+	}
+}
+
+type fakePurgeProdukRepo struct {
+	repository.ProdukRepository
+	deletedBefore time.Time
+	fotos         map[uint][]string
+	purged        []uint
+}
+
+func (r *fakePurgeProdukRepo) FindPurgeable(deletedBefore time.Time, limit int) ([]model.Produk, error) {
+	r.deletedBefore = deletedBefore
+	return []model.Produk{{ID: 1}, {ID: 2}}, nil
+}
+
+func (r *fakePurgeProdukRepo) Purge(produkID uint) ([]string, error) {
+	if produkID == 2 {
+		return nil, errors.New("locked")
+	}
+	r.purged = append(r.purged, produkID)
+	return r.fotos[produkID], nil
+}
+
+func TestPurgeDeletedProdukRemovesFoto(t *testing.T) {
+	dir := t.TempDir()
+	foto := filepath.Join(dir, "produk-1.jpg")
+	if err := os.WriteFile(foto, []byte("jpg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakePurgeProdukRepo{fotos: map[uint][]string{1: {foto, filepath.Join(dir, "already-gone.jpg")}}}
+	uc := &produkUsecase{produkRepo: repo, purgeGrace: 30 * 24 * time.Hour}
+	now := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	purged, err := uc.PurgeDeletedProduk(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 || len(repo.purged) != 1 || repo.purged[0] != 1 {
+		t.Fatalf("expected only produk 1 purged, got %d %v", purged, repo.purged)
+	}
+	if !repo.deletedBefore.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected cutoff %v", repo.deletedBefore)
+	}
+	if _, err := os.Stat(foto); !os.IsNotExist(err) {
+		t.Fatalf("foto file must be removed, stat error %v", err)
+	}
+}
+
+func TestSearchProdukFlagTruncated(t *testing.T) {
+	index := repository.NewMemorySearchIndex()
+	for id := uint(1); id <= maxSearchHits; id++ {
+		index.Index(repository.SearchDocument{ProdukID: id, NamaProduk: "kaos polos"})
+	}
+	uc := &produkUsecase{searchIndex: index}
+
+	filter, truncated, err := uc.searchProduk(repository.FilterInput{Search: "kaos"})
+	if err != nil || truncated || len(filter.SearchIDs) != maxSearchHits {
+		t.Fatalf("exactly %d hit must not be truncated, got %d %v %v", maxSearchHits, len(filter.SearchIDs), truncated, err)
+	}
+
+	index.Index(repository.SearchDocument{ProdukID: maxSearchHits + 1, NamaProduk: "kaos polos"})
+	filter, truncated, err = uc.searchProduk(repository.FilterInput{Search: "kaos"})
+	if err != nil || !truncated || len(filter.SearchIDs) != maxSearchHits {
+		t.Fatalf("expected %d hit and truncated, got %d %v %v", maxSearchHits, len(filter.SearchIDs), truncated, err)
+	}
+}
+
+func TestGetProdukByIDHideTokoOnLibur(t *testing.T) {
+	liburSampai := time.Now().Add(time.Hour)
+	toko := &model.Toko{ID: 1, Status: model.TokoStatusActive, IsLibur: true, LiburSampai: &liburSampai}
+	repo := &fakeProdukRepo{produks: []model.Produk{{ID: 1, IDToko: 1, Status: model.ProdukStatusPublished, Toko: toko}}}
+	uc := &produkUsecase{produkRepo: repo}
+
+	if _, err := uc.GetProdukByID(1); err == nil {
+		t.Fatalf("expected produk of toko on libur not found")
+	}
+
+	toko.IsLibur, toko.LiburSampai = false, nil
+	if produk, err := uc.GetProdukByID(1); err != nil || produk.ID != 1 {
+		t.Fatalf("expected produk after libur end, got %v %v", produk.ID, err)
+	}
+}
+
+func TestProdukWriteRejectTokoNotActive(t *testing.T) {
+	for _, status := range []string{model.TokoStatusDraft, model.TokoStatusPendingReview, model.TokoStatusSuspended} {
+		tokoMember := &fakeActingTokoUsecase{status: status}
+		uc := &produkUsecase{produkRepo: &fakeProdukRepo{}, tokoMemberUsecase: tokoMember}
+		if _, err := uc.CreateProduk(1, 3, model.Produk{NamaProduk: "Laptop"}, nil); !errors.Is(err, ErrTokoNotActive) {
+			t.Fatalf("%s: expected ErrTokoNotActive on create, got %v", status, err)
+		}
+
+		file := filepath.Join(t.TempDir(), "produk.csv")
+		os.WriteFile(file, []byte("nama_produk\n"), 0o644)
+		importUc := &produkImportUsecase{tokoMemberUsecase: tokoMember}
+		if _, err := importUc.ImportProduk(1, 3, utils.SpreadsheetCSV, "produk.csv", file); !errors.Is(err, ErrTokoNotActive) {
+			t.Fatalf("%s: expected ErrTokoNotActive on import, got %v", status, err)
+		}
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Fatalf("%s: uploaded file must be removed, stat error %v", status, err)
+		}
+	}
+}
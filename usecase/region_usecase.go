@@ -0,0 +1,361 @@
+package usecase
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+var kodePosPattern = regexp.MustCompile(`^[0-9]{5}$`)
+
+// returned (wrapped) by ValidateRegion when input wrong, handler answer 400
+var ErrRegionNotValid = errors.New("region not valid")
+
+func regionNotValid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrRegionNotValid, fmt.Sprintf(format, args...))
+}
+
+// region id of profile or address, 0 / empty mean not filled
+type RegionInput struct {
+	IDProvinsi  uint
+	IDKota      uint
+	IDKecamatan uint
+	IDKelurahan uint64
+	KodePos     string
+}
+
+// all row from provinsi.csv, kota.csv, kecamatan.csv and kelurahan.csv
+type RegionDataset struct {
+	Provinsi  []model.Provinsi
+	Kota      []model.Kota
+	Kecamatan []model.Kecamatan
+	Kelurahan []model.Kelurahan
+}
+
+type RegionUsecase interface {
+	SeedRegions(fsys fs.FS) error
+
+	GetProvinsi(search string) ([]model.Provinsi, error)
+	GetKota(provinsiID uint, search string) ([]model.Kota, error)
+	GetKecamatan(kotaID uint, search string) ([]model.Kecamatan, error)
+	GetKelurahan(kecamatanID uint, search string) ([]model.Kelurahan, error)
+	GetKelurahanByKodePos(kodePos string) ([]model.Kelurahan, error)
+
+	// used by profile and address write
+	ValidateRegion(input RegionInput) error
+}
+
+type regionUsecase struct {
+	regionRepo repository.RegionRepository
+}
+
+func NewRegionUsecase(regionRepo repository.RegionRepository) RegionUsecase {
+	return &regionUsecase{regionRepo}
+}
+
+// read csv with header, every row passed to parse with column by header name
+func readRegionCSV(fsys fs.FS, name string, columns []string, parse func(row map[string]string) error) error {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%s: failed read header: %w", name, err)
+	}
+	index := map[string]int{}
+	for i, column := range header {
+		index[strings.TrimSpace(strings.ToLower(column))] = i
+	}
+	for _, column := range columns {
+		if _, ok := index[column]; !ok {
+			return fmt.Errorf("%s: missing column %s", name, column)
+		}
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+
+		row := map[string]string{}
+		for _, column := range columns {
+			row[column] = strings.TrimSpace(record[index[column]])
+		}
+		if err := parse(row); err != nil {
+			return fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+	}
+}
+
+func parseRegionID(value string) (uint, error) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	return uint(id), err
+}
+
+// LoadRegionDataset parse dataset and check every row point to existing parent
+func LoadRegionDataset(fsys fs.FS) (RegionDataset, error) {
+	var dataset RegionDataset
+	provinsiIDs, kotaIDs, kecamatanIDs := map[uint]bool{}, map[uint]bool{}, map[uint]bool{}
+
+	err := readRegionCSV(fsys, "provinsi.csv", []string{"id", "nama"}, func(row map[string]string) error {
+		id, err := parseRegionID(row["id"])
+		if err != nil {
+			return err
+		}
+		provinsiIDs[id] = true
+		dataset.Provinsi = append(dataset.Provinsi, model.Provinsi{ID: id, Nama: row["nama"]})
+		return nil
+	})
+	if err != nil {
+		return dataset, err
+	}
+
+	err = readRegionCSV(fsys, "kota.csv", []string{"id", "id_provinsi", "nama"}, func(row map[string]string) error {
+		id, err := parseRegionID(row["id"])
+		if err != nil {
+			return err
+		}
+		parentID, err := parseRegionID(row["id_provinsi"])
+		if err != nil || !provinsiIDs[parentID] {
+			return fmt.Errorf("provinsi %s not found", row["id_provinsi"])
+		}
+		kotaIDs[id] = true
+		dataset.Kota = append(dataset.Kota, model.Kota{ID: id, IDProvinsi: parentID, Nama: row["nama"]})
+		return nil
+	})
+	if err != nil {
+		return dataset, err
+	}
+
+	err = readRegionCSV(fsys, "kecamatan.csv", []string{"id", "id_kota", "nama"}, func(row map[string]string) error {
+		id, err := parseRegionID(row["id"])
+		if err != nil {
+			return err
+		}
+		parentID, err := parseRegionID(row["id_kota"])
+		if err != nil || !kotaIDs[parentID] {
+			return fmt.Errorf("kota %s not found", row["id_kota"])
+		}
+		kecamatanIDs[id] = true
+		dataset.Kecamatan = append(dataset.Kecamatan, model.Kecamatan{ID: id, IDKota: parentID, Nama: row["nama"]})
+		return nil
+	})
+	if err != nil {
+		return dataset, err
+	}
+
+	err = readRegionCSV(fsys, "kelurahan.csv", []string{"id", "id_kecamatan", "nama", "kode_pos"}, func(row map[string]string) error {
+		id, err := strconv.ParseUint(row["id"], 10, 64)
+		if err != nil {
+			return err
+		}
+		parentID, err := parseRegionID(row["id_kecamatan"])
+		if err != nil || !kecamatanIDs[parentID] {
+			return fmt.Errorf("kecamatan %s not found", row["id_kecamatan"])
+		}
+		if row["kode_pos"] != "" && !kodePosPattern.MatchString(row["kode_pos"]) {
+			return fmt.Errorf("kode pos %q not valid", row["kode_pos"])
+		}
+		dataset.Kelurahan = append(dataset.Kelurahan, model.Kelurahan{ID: id, IDKecamatan: parentID, Nama: row["nama"], KodePos: row["kode_pos"]})
+		return nil
+	})
+	return dataset, err
+}
+
+// every provinsi, kota and kecamatan must have a row below it, a subset would make
+// ValidateRegion reject real address
+func CheckRegionDatasetComplete(dataset RegionDataset) error {
+	hasKota, hasKecamatan, hasKelurahan := map[uint]bool{}, map[uint]bool{}, map[uint]bool{}
+	for _, kota := range dataset.Kota {
+		hasKota[kota.IDProvinsi] = true
+	}
+	for _, kecamatan := range dataset.Kecamatan {
+		hasKecamatan[kecamatan.IDKota] = true
+	}
+	for _, kelurahan := range dataset.Kelurahan {
+		hasKelurahan[kelurahan.IDKecamatan] = true
+	}
+
+	for _, provinsi := range dataset.Provinsi {
+		if !hasKota[provinsi.ID] {
+			return fmt.Errorf("provinsi %d has no kota, dataset not complete", provinsi.ID)
+		}
+	}
+	for _, kota := range dataset.Kota {
+		if !hasKecamatan[kota.ID] {
+			return fmt.Errorf("kota %d has no kecamatan, dataset not complete", kota.ID)
+		}
+	}
+	for _, kecamatan := range dataset.Kecamatan {
+		if !hasKelurahan[kecamatan.ID] {
+			return fmt.Errorf("kecamatan %d has no kelurahan, dataset not complete", kecamatan.ID)
+		}
+	}
+	return nil
+}
+
+// run on startup, level skipped when row count already same as dataset
+func (uc *regionUsecase) SeedRegions(fsys fs.FS) error {
+	dataset, err := LoadRegionDataset(fsys)
+	if err != nil {
+		return fmt.Errorf("failed read region dataset: %w", err)
+	}
+	if err := CheckRegionDatasetComplete(dataset); err != nil {
+		return err
+	}
+
+	levels := []struct {
+		name  string
+		total int
+		count func() (int64, error)
+		save  func() error
+	}{
+		{"provinsi", len(dataset.Provinsi), uc.regionRepo.CountProvinsi, func() error { return uc.regionRepo.SaveProvinsi(dataset.Provinsi) }},
+		{"kota", len(dataset.Kota), uc.regionRepo.CountKota, func() error { return uc.regionRepo.SaveKota(dataset.Kota) }},
+		{"kecamatan", len(dataset.Kecamatan), uc.regionRepo.CountKecamatan, func() error { return uc.regionRepo.SaveKecamatan(dataset.Kecamatan) }},
+		{"kelurahan", len(dataset.Kelurahan), uc.regionRepo.CountKelurahan, func() error { return uc.regionRepo.SaveKelurahan(dataset.Kelurahan) }},
+	}
+	for _, level := range levels {
+		count, err := level.count()
+		if err != nil {
+			return fmt.Errorf("failed count %s: %w", level.name, err)
+		}
+		if count == int64(level.total) {
+			continue
+		}
+		if err := level.save(); err != nil {
+			return fmt.Errorf("failed seed %s: %w", level.name, err)
+		}
+	}
+	return nil
+}
+
+func (uc *regionUsecase) GetProvinsi(search string) ([]model.Provinsi, error) {
+	provinsi, err := uc.regionRepo.FindAllProvinsi(strings.TrimSpace(search))
+	if err != nil {
+		return provinsi, fmt.Errorf("failed get provinsi: %w", err)
+	}
+	return provinsi, nil
+}
+
+func (uc *regionUsecase) GetKota(provinsiID uint, search string) ([]model.Kota, error) {
+	if _, err := uc.regionRepo.FindProvinsiByID(provinsiID); err != nil {
+		return nil, errors.New("provinsi not found")
+	}
+	kota, err := uc.regionRepo.FindKotaByProvinsiID(provinsiID, strings.TrimSpace(search))
+	if err != nil {
+		return kota, fmt.Errorf("failed get kota: %w", err)
+	}
+	return kota, nil
+}
+
+func (uc *regionUsecase) GetKecamatan(kotaID uint, search string) ([]model.Kecamatan, error) {
+	if _, err := uc.regionRepo.FindKotaByID(kotaID); err != nil {
+		return nil, errors.New("kota not found")
+	}
+	kecamatan, err := uc.regionRepo.FindKecamatanByKotaID(kotaID, strings.TrimSpace(search))
+	if err != nil {
+		return kecamatan, fmt.Errorf("failed get kecamatan: %w", err)
+	}
+	return kecamatan, nil
+}
+
+func (uc *regionUsecase) GetKelurahan(kecamatanID uint, search string) ([]model.Kelurahan, error) {
+	if _, err := uc.regionRepo.FindKecamatanByID(kecamatanID); err != nil {
+		return nil, errors.New("kecamatan not found")
+	}
+	kelurahan, err := uc.regionRepo.FindKelurahanByKecamatanID(kecamatanID, strings.TrimSpace(search))
+	if err != nil {
+		return kelurahan, fmt.Errorf("failed get kelurahan: %w", err)
+	}
+	return kelurahan, nil
+}
+
+func (uc *regionUsecase) GetKelurahanByKodePos(kodePos string) ([]model.Kelurahan, error) {
+	if !kodePosPattern.MatchString(kodePos) {
+		return nil, errors.New("kode pos must be 5 digit")
+	}
+	kelurahan, err := uc.regionRepo.FindKelurahanByKodePos(kodePos)
+	if err != nil {
+		return kelurahan, fmt.Errorf("failed get kelurahan: %w", err)
+	}
+	return kelurahan, nil
+}
+
+func regionLookupError(name string, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return regionNotValid("id_%s not found", name)
+	}
+	return fmt.Errorf("failed check %s: %w", name, err)
+}
+
+// every filled level must exist and belong to the level above it
+func (uc *regionUsecase) ValidateRegion(input RegionInput) error {
+	if input.IDKota != 0 && input.IDProvinsi == 0 {
+		return regionNotValid("id_provinsi is required when id_kota is filled")
+	}
+	if input.IDKecamatan != 0 && input.IDKota == 0 {
+		return regionNotValid("id_kota is required when id_kecamatan is filled")
+	}
+	if input.IDKelurahan != 0 && input.IDKecamatan == 0 {
+		return regionNotValid("id_kecamatan is required when id_kelurahan is filled")
+	}
+	if input.KodePos != "" && !kodePosPattern.MatchString(input.KodePos) {
+		return regionNotValid("kode pos must be 5 digit")
+	}
+
+	if input.IDProvinsi != 0 {
+		if _, err := uc.regionRepo.FindProvinsiByID(input.IDProvinsi); err != nil {
+			return regionLookupError("provinsi", err)
+		}
+	}
+	if input.IDKota != 0 {
+		kota, err := uc.regionRepo.FindKotaByID(input.IDKota)
+		if err != nil {
+			return regionLookupError("kota", err)
+		}
+		if kota.IDProvinsi != input.IDProvinsi {
+			return regionNotValid("id_kota not in id_provinsi")
+		}
+	}
+	if input.IDKecamatan != 0 {
+		kecamatan, err := uc.regionRepo.FindKecamatanByID(input.IDKecamatan)
+		if err != nil {
+			return regionLookupError("kecamatan", err)
+		}
+		if kecamatan.IDKota != input.IDKota {
+			return regionNotValid("id_kecamatan not in id_kota")
+		}
+	}
+	if input.IDKelurahan != 0 {
+		kelurahan, err := uc.regionRepo.FindKelurahanByID(input.IDKelurahan)
+		if err != nil {
+			return regionLookupError("kelurahan", err)
+		}
+		if kelurahan.IDKecamatan != input.IDKecamatan {
+			return regionNotValid("id_kelurahan not in id_kecamatan")
+		}
+		if input.KodePos != "" && kelurahan.KodePos != "" && input.KodePos != kelurahan.KodePos {
+			return regionNotValid("kode pos %s not match kelurahan (%s)", input.KodePos, kelurahan.KodePos)
+		}
+	}
+	return nil
+}
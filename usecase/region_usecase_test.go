@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"rakamin-evermos/data"
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+func TestBundledRegionDatasetIsConsistent(t *testing.T) {
+	regionFS, err := fs.Sub(data.Region, "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataset, err := LoadRegionDataset(regionFS)
+	if err != nil {
+		t.Fatalf("bundled dataset not valid: %v", err)
+	}
+	if len(dataset.Provinsi) != 38 {
+		t.Errorf("expected 38 provinsi, got %d", len(dataset.Provinsi))
+	}
+	if len(dataset.Kota) == 0 || len(dataset.Kecamatan) == 0 || len(dataset.Kelurahan) == 0 {
+		t.Fatalf("expected kota, kecamatan and kelurahan rows, got %d/%d/%d",
+			len(dataset.Kota), len(dataset.Kecamatan), len(dataset.Kelurahan))
+	}
+
+	seen := map[uint64]bool{}
+	for _, kelurahan := range dataset.Kelurahan {
+		if seen[kelurahan.ID] {
+			t.Errorf("duplicate kelurahan %d", kelurahan.ID)
+		}
+		seen[kelurahan.ID] = true
+	}
+}
+
+func TestLoadRegionDatasetRejectsOrphanRow(t *testing.T) {
+	fsys := fstest.MapFS{
+		"provinsi.csv":  {Data: []byte("id,nama\n31,DKI JAKARTA\n")},
+		"kota.csv":      {Data: []byte("id,id_provinsi,nama\n3171,31,KOTA JAKARTA SELATAN\n3471,34,KOTA YOGYAKARTA\n")},
+		"kecamatan.csv": {Data: []byte("id,id_kota,nama\n")},
+		"kelurahan.csv": {Data: []byte("id,id_kecamatan,nama,kode_pos\n")},
+	}
+
+	if _, err := LoadRegionDataset(fsys); err == nil {
+		t.Fatal("expected error for kota with unknown provinsi")
+	}
+}
+
+type fakeRegionRepo struct {
+	repository.RegionRepository
+	dataset RegionDataset
+}
+
+func (r *fakeRegionRepo) FindProvinsiByID(provinsiID uint) (model.Provinsi, error) {
+	for _, provinsi := range r.dataset.Provinsi {
+		if provinsi.ID == provinsiID {
+			return provinsi, nil
+		}
+	}
+	return model.Provinsi{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRegionRepo) FindKotaByID(kotaID uint) (model.Kota, error) {
+	for _, kota := range r.dataset.Kota {
+		if kota.ID == kotaID {
+			return kota, nil
+		}
+	}
+	return model.Kota{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRegionRepo) FindKecamatanByID(kecamatanID uint) (model.Kecamatan, error) {
+	for _, kecamatan := range r.dataset.Kecamatan {
+		if kecamatan.ID == kecamatanID {
+			return kecamatan, nil
+		}
+	}
+	return model.Kecamatan{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRegionRepo) FindKelurahanByID(kelurahanID uint64) (model.Kelurahan, error) {
+	for _, kelurahan := range r.dataset.Kelurahan {
+		if kelurahan.ID == kelurahanID {
+			return kelurahan, nil
+		}
+	}
+	return model.Kelurahan{}, gorm.ErrRecordNotFound
+}
+
+func TestValidateRegionRejectIDNotInDataset(t *testing.T) {
+	regionFS, err := fs.Sub(data.Region, "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset, err := LoadRegionDataset(regionFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := NewRegionUsecase(&fakeRegionRepo{dataset: dataset})
+
+	cases := []struct {
+		name  string
+		input RegionInput
+		valid bool
+	}{
+		{"kelurahan in dataset", RegionInput{IDProvinsi: 31, IDKota: 3171, IDKecamatan: 317109, IDKelurahan: 3171091001, KodePos: "12810"}, true},
+		{"unknown provinsi", RegionInput{IDProvinsi: 99}, false},
+		// code prefix match the parent but id not in dataset
+		{"kota not in dataset", RegionInput{IDProvinsi: 35, IDKota: 3509}, false},
+		{"kecamatan not in dataset", RegionInput{IDProvinsi: 32, IDKota: 3273, IDKecamatan: 327301}, false},
+		{"kota in other provinsi", RegionInput{IDProvinsi: 32, IDKota: 3171}, false},
+		{"kelurahan wrong kode pos", RegionInput{IDProvinsi: 31, IDKota: 3171, IDKecamatan: 317109, IDKelurahan: 3171091001, KodePos: "12820"}, false},
+	}
+	for _, tc := range cases {
+		err := uc.ValidateRegion(tc.input)
+		if tc.valid && err != nil {
+			t.Errorf("%s: expected valid, got %v", tc.name, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrRegionNotValid) {
+			t.Errorf("%s: expected ErrRegionNotValid, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestSeedRegionsRefuseSubset(t *testing.T) {
+	regionFS, err := fs.Sub(data.Region, "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRegionUsecase(&fakeRegionRepo{}).SeedRegions(regionFS); err == nil {
+		t.Fatal("expected bundled sample refused")
+	}
+
+	complete := fstest.MapFS{
+		"provinsi.csv":  {Data: []byte("id,nama\n31,DKI JAKARTA\n")},
+		"kota.csv":      {Data: []byte("id,id_provinsi,nama\n3171,31,KOTA JAKARTA SELATAN\n")},
+		"kecamatan.csv": {Data: []byte("id,id_kota,nama\n317109,3171,MAMPANG PRAPATAN\n")},
+		"kelurahan.csv": {Data: []byte("id,id_kecamatan,nama,kode_pos\n3171091001,317109,KUNINGAN BARAT,12710\n")},
+	}
+	dataset, err := LoadRegionDataset(complete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckRegionDatasetComplete(dataset); err != nil {
+		t.Fatalf("expected complete dataset accepted, got %v", err)
+	}
+}
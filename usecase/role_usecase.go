@@ -0,0 +1,292 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+// permission of every default role, admin get all permission
+var defaultRolePermissions = map[string][]string{
+	model.RoleCatalogManager: {model.PermissionCategoryRead, model.PermissionCategoryWrite},
+	model.RoleSupport:        {model.PermissionUserRead, model.PermissionUserWrite, model.PermissionLoginAudit, model.PermissionLoginUnlock},
+	model.RoleFinance:        {model.PermissionTrxRead},
+}
+
+var defaultPermissions = []model.Permission{
+	{Code: model.PermissionCategoryRead, Description: "See category in admin panel"},
+	{Code: model.PermissionCategoryWrite, Description: "Create, update and delete category"},
+	{Code: model.PermissionUserRead, Description: "See user data"},
+	{Code: model.PermissionUserWrite, Description: "Change user data"},
+	{Code: model.PermissionRoleManage, Description: "Create role and assign role to user"},
+	{Code: model.PermissionLoginAudit, Description: "See login attempts"},
+	{Code: model.PermissionLoginUnlock, Description: "Unlock locked account or IP"},
+	{Code: model.PermissionTrxRead, Description: "See all transaksi"},
+	{Code: model.PermissionAuditRead, Description: "See admin audit log"},
+	{Code: model.PermissionTokoReview, Description: "Review, approve and suspend toko"},
+}
+
+type RoleUsecase interface {
+	SeedDefaultRoles() error
+	GetUserPermissions(userID uint) ([]string, error)
+
+	// admin only
+	GetRoles() ([]model.Role, error)
+	GetPermissions() ([]model.Permission, error)
+	CreateRole(input model.Role, permissionCodes []string) (model.Role, error)
+	UpdateRole(roleID uint, input model.Role, permissionCodes []string) (model.Role, error)
+	GetUserRoles(userID uint) ([]model.Role, error)
+	AssignUserRoles(actorID, userID uint, roleNames []string, ipAddress string) ([]model.Role, error)
+}
+
+type roleUsecase struct {
+	roleRepo     repository.RoleRepository
+	userRepo     repository.UserRepository
+	auditLogRepo repository.AuditLogRepository
+}
+
+func NewRoleUsecase(roleRepo repository.RoleRepository, userRepo repository.UserRepository, auditLogRepo repository.AuditLogRepository) RoleUsecase {
+	return &roleUsecase{roleRepo, userRepo, auditLogRepo}
+}
+
+// run on startup, only create missing data so role changed by admin is kept
+func (uc *roleUsecase) SeedDefaultRoles() error {
+	now := time.Now()
+	permissions := make([]model.Permission, len(defaultPermissions))
+	for i, permission := range defaultPermissions {
+		permission.CreatedAtDate = now
+		permission.UpdatedAtDate = now
+		permissions[i] = permission
+	}
+	if err := uc.roleRepo.SavePermissions(permissions); err != nil {
+		return fmt.Errorf("failed seed permission: %w", err)
+	}
+
+	allCodes := make([]string, 0, len(defaultPermissions))
+	for _, permission := range defaultPermissions {
+		allCodes = append(allCodes, permission.Code)
+	}
+
+	roles := map[string][]string{model.RoleAdmin: allCodes}
+	for name, codes := range defaultRolePermissions {
+		roles[name] = codes
+	}
+
+	for name, codes := range roles {
+		existingRole, err := uc.roleRepo.FindByName(name)
+		if err == nil {
+			// default permission added in new version given to old database too, extra permission from admin kept
+			if codes := missingPermissionCodes(existingRole, codes); len(codes) > 0 {
+				for _, permission := range existingRole.Permissions {
+					codes = append(codes, permission.Code)
+				}
+				if _, err := uc.UpdateRole(existingRole.ID, existingRole, codes); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed check role %s: %w", name, err)
+		}
+		if _, err := uc.CreateRole(model.Role{Name: name, Description: "default role"}, codes); err != nil {
+			return err
+		}
+	}
+
+	// user with old is_admin flag become admin role
+	adminRole, err := uc.roleRepo.FindByName(model.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("failed get admin role: %w", err)
+	}
+	if err := uc.roleRepo.AssignRoleToAdminFlagUsers(adminRole.ID); err != nil {
+		return fmt.Errorf("failed migrate admin user: %w", err)
+	}
+	return nil
+}
+
+func missingPermissionCodes(role model.Role, codes []string) []string {
+	owned := map[string]bool{}
+	for _, permission := range role.Permissions {
+		owned[permission.Code] = true
+	}
+	missing := []string{}
+	for _, code := range codes {
+		if !owned[code] {
+			missing = append(missing, code)
+		}
+	}
+	return missing
+}
+
+func (uc *roleUsecase) GetUserPermissions(userID uint) ([]string, error) {
+	codes, err := uc.roleRepo.FindPermissionCodesByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed get user permission: %w", err)
+	}
+	return codes, nil
+}
+
+func (uc *roleUsecase) GetRoles() ([]model.Role, error) {
+	roles, err := uc.roleRepo.FindAll()
+	if err != nil {
+		return roles, fmt.Errorf("failed get roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (uc *roleUsecase) GetPermissions() ([]model.Permission, error) {
+	permissions, err := uc.roleRepo.FindAllPermissions()
+	if err != nil {
+		return permissions, fmt.Errorf("failed get permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+func (uc *roleUsecase) getPermissions(codes []string) ([]model.Permission, error) {
+	if len(codes) == 0 {
+		return []model.Permission{}, nil
+	}
+
+	permissions, err := uc.roleRepo.FindPermissionsByCodes(codes)
+	if err != nil {
+		return nil, fmt.Errorf("failed get permissions: %w", err)
+	}
+	if len(permissions) != len(uniqueStrings(codes)) {
+		return nil, errors.New("some permission code not valid")
+	}
+	return permissions, nil
+}
+
+func (uc *roleUsecase) CreateRole(input model.Role, permissionCodes []string) (model.Role, error) {
+	if _, err := uc.roleRepo.FindByName(input.Name); err == nil {
+		return model.Role{}, errors.New("role name already exist")
+	}
+
+	permissions, err := uc.getPermissions(permissionCodes)
+	if err != nil {
+		return model.Role{}, err
+	}
+
+	now := time.Now()
+	input.Permissions = permissions
+	input.CreatedAtDate = now
+	input.UpdatedAtDate = now
+
+	savedRole, err := uc.roleRepo.Save(input)
+	if err != nil {
+		return savedRole, fmt.Errorf("failed save role: %w", err)
+	}
+	return savedRole, nil
+}
+
+// name cant be changed, default role name used in code
+func (uc *roleUsecase) UpdateRole(roleID uint, input model.Role, permissionCodes []string) (model.Role, error) {
+	existingRole, err := uc.roleRepo.FindByID(roleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Role{}, errors.New("role not found")
+		}
+		return model.Role{}, fmt.Errorf("failed verify role: %w", err)
+	}
+
+	permissions, err := uc.getPermissions(permissionCodes)
+	if err != nil {
+		return model.Role{}, err
+	}
+
+	existingRole.Description = input.Description
+	existingRole.Permissions = permissions
+	existingRole.UpdatedAtDate = time.Now()
+
+	updatedRole, err := uc.roleRepo.Save(existingRole)
+	if err != nil {
+		return updatedRole, fmt.Errorf("failed update role: %w", err)
+	}
+	return updatedRole, nil
+}
+
+func (uc *roleUsecase) GetUserRoles(userID uint) ([]model.Role, error) {
+	if _, err := uc.userRepo.FindByID(userID); err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	roles, err := uc.roleRepo.FindRolesByUserID(userID)
+	if err != nil {
+		return roles, fmt.Errorf("failed get user roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (uc *roleUsecase) AssignUserRoles(actorID, userID uint, roleNames []string, ipAddress string) ([]model.Role, error) {
+	if _, err := uc.userRepo.FindByID(userID); err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	roleNames = uniqueStrings(roleNames)
+	previousRoles, err := uc.roleRepo.FindRolesByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed get user roles: %w", err)
+	}
+
+	// admin cant lock himself out
+	if actorID == userID && containsString(roleNamesOf(previousRoles), model.RoleAdmin) && !containsString(roleNames, model.RoleAdmin) {
+		return nil, errors.New("cant remove admin role from your own account")
+	}
+
+	roles := []model.Role{}
+	if len(roleNames) > 0 {
+		found, err := uc.roleRepo.FindByNames(roleNames)
+		if err != nil {
+			return nil, fmt.Errorf("failed get roles: %w", err)
+		}
+		if len(found) != len(roleNames) {
+			return nil, errors.New("some role name not valid")
+		}
+		roles = found
+	}
+
+	if err := uc.roleRepo.ReplaceUserRoles(userID, roles); err != nil {
+		return nil, fmt.Errorf("failed assign roles: %w", err)
+	}
+
+	recordAudit(uc.auditLogRepo, actorID, model.AuditUserRoles, "user", userID, map[string]interface{}{
+		"previous": roleNamesOf(previousRoles),
+		"current":  roleNamesOf(roles),
+	}, ipAddress)
+	return uc.roleRepo.FindRolesByUserID(userID)
+}
+
+func roleNamesOf(roles []model.Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
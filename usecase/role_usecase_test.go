@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"sort"
+	"testing"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+type fakeRoleRepo struct {
+	repository.RoleRepository
+	roles       map[string]model.Role
+	permissions map[string]model.Permission
+	userCodes   map[uint][]string
+}
+
+func (r *fakeRoleRepo) Save(role model.Role) (model.Role, error) {
+	if role.ID == 0 {
+		role.ID = uint(len(r.roles) + 1)
+	}
+	r.roles[role.Name] = role
+	return role, nil
+}
+
+func (r *fakeRoleRepo) FindByName(name string) (model.Role, error) {
+	role, ok := r.roles[name]
+	if !ok {
+		return role, gorm.ErrRecordNotFound
+	}
+	return role, nil
+}
+
+func (r *fakeRoleRepo) FindByID(roleID uint) (model.Role, error) {
+	for _, role := range r.roles {
+		if role.ID == roleID {
+			return role, nil
+		}
+	}
+	return model.Role{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRoleRepo) SavePermissions(permissions []model.Permission) error {
+	for _, permission := range permissions {
+		if _, exist := r.permissions[permission.Code]; !exist {
+			r.permissions[permission.Code] = permission
+		}
+	}
+	return nil
+}
+
+func (r *fakeRoleRepo) FindPermissionsByCodes(codes []string) ([]model.Permission, error) {
+	permissions := []model.Permission{}
+	for _, code := range uniqueStrings(codes) {
+		if permission, ok := r.permissions[code]; ok {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
+
+func (r *fakeRoleRepo) FindPermissionCodesByUserID(userID uint) ([]string, error) {
+	return r.userCodes[userID], nil
+}
+
+func (r *fakeRoleRepo) AssignRoleToAdminFlagUsers(roleID uint) error {
+	return nil
+}
+
+func roleCodes(role model.Role) []string {
+	codes := []string{}
+	for _, permission := range role.Permissions {
+		codes = append(codes, permission.Code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func TestSeedDefaultRolesTopUpExistingRole(t *testing.T) {
+	// old database: support created before user:write existed, admin added extra permission to it
+	repo := &fakeRoleRepo{roles: map[string]model.Role{
+		model.RoleSupport: {ID: 1, Name: model.RoleSupport, Permissions: []model.Permission{
+			{Code: model.PermissionUserRead}, {Code: model.PermissionLoginAudit}, {Code: model.PermissionLoginUnlock}, {Code: model.PermissionTrxRead},
+		}},
+	}, permissions: map[string]model.Permission{}}
+	uc := &roleUsecase{roleRepo: repo}
+
+	if err := uc.SeedDefaultRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	support := roleCodes(repo.roles[model.RoleSupport])
+	expected := []string{model.PermissionLoginAudit, model.PermissionLoginUnlock, model.PermissionTrxRead, model.PermissionUserRead, model.PermissionUserWrite}
+	sort.Strings(expected)
+	if len(support) != len(expected) {
+		t.Fatalf("expected support %v, got %v", expected, support)
+	}
+	for i := range expected {
+		if support[i] != expected[i] {
+			t.Fatalf("expected support %v, got %v", expected, support)
+		}
+	}
+	if admin := repo.roles[model.RoleAdmin]; len(admin.Permissions) != len(defaultPermissions) {
+		t.Fatalf("admin must get all permission, got %v", roleCodes(admin))
+	}
+}
@@ -0,0 +1,381 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+const tokoInvitationTTL = 7 * 24 * time.Hour
+
+// user is not staff of toko or role not allowed, handler return 403
+var ErrTokoForbidden = errors.New("you don't have access to this toko")
+
+// toko not verified yet or suspended, produk can't be changed (same as checkout refuse toko not active)
+var ErrTokoNotActive = errors.New("toko is not active, produk can't be changed until toko is active")
+
+// user is staff of many toko and didn't choose one
+var ErrActingTokoRequired = errors.New("you are staff of more than one toko, choose toko with X-Toko-ID header")
+
+// toko where user is staff, for choose acting toko
+type MyToko struct {
+	IDToko   uint   `json:"id_toko"`
+	NamaToko string `json:"nama_toko"`
+	Slug     string `json:"slug"`
+	Role     string `json:"role"`
+}
+
+type TokoStaff struct {
+	IDUser        uint      `json:"id_user"`
+	Nama          string    `json:"nama"`
+	Email         string    `json:"email"`
+	Role          string    `json:"role"`
+	CreatedAtDate time.Time `json:"created_at_date"`
+}
+
+// Token only returned here, share it with invited staff
+type CreatedTokoInvitation struct {
+	Invitation model.TokoInvitation `json:"invitation"`
+	Token      string               `json:"token"`
+}
+
+type TokoMemberUsecase interface {
+	// tokoID 0 = owned toko, or the only toko where user is staff
+	ResolveActingToko(userID, tokoID uint, permission string) (model.Toko, error)
+	GetMyTokoList(userID uint) ([]MyToko, error)
+
+	GetStaff(userID, tokoID uint) ([]TokoStaff, error)
+	UpdateStaffRole(userID, tokoID, staffUserID uint, role string) (TokoStaff, error)
+	RemoveStaff(userID, tokoID, staffUserID uint) error
+
+	InviteStaff(userID, tokoID uint, email, role string) (CreatedTokoInvitation, error)
+	GetInvitations(userID, tokoID uint) ([]model.TokoInvitation, error)
+	RevokeInvitation(userID, tokoID, invitationID uint) error
+	AcceptInvitation(userID uint, token string) (MyToko, error)
+}
+
+type tokoMemberUsecase struct {
+	memberRepo repository.TokoMemberRepository
+	userRepo   repository.UserRepository
+}
+
+func NewTokoMemberUsecase(memberRepo repository.TokoMemberRepository, userRepo repository.UserRepository) TokoMemberUsecase {
+	return &tokoMemberUsecase{memberRepo, userRepo}
+}
+
+// owner role only come from create toko, staff get manager or packer
+func validateStaffRole(role string) error {
+	if role != model.TokoRoleManager && role != model.TokoRolePacker {
+		return fmt.Errorf("role must be %s or %s", model.TokoRoleManager, model.TokoRolePacker)
+	}
+	return nil
+}
+
+func (uc *tokoMemberUsecase) findActingMember(userID, tokoID uint) (model.TokoMember, error) {
+	if tokoID != 0 {
+		member, err := uc.memberRepo.FindMember(tokoID, userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return member, ErrTokoForbidden
+			}
+			return member, fmt.Errorf("failed verify your toko: %w", err)
+		}
+		return member, nil
+	}
+
+	members, err := uc.memberRepo.FindAllByUserID(userID)
+	if err != nil {
+		return model.TokoMember{}, fmt.Errorf("failed verify your toko: %w", err)
+	}
+	switch {
+	case len(members) == 0:
+		return model.TokoMember{}, errors.New("u dont have toko. go register as seller first")
+	case members[0].Role == model.TokoRoleOwner || len(members) == 1:
+		return members[0], nil
+	}
+	return model.TokoMember{}, ErrActingTokoRequired
+}
+
+func (uc *tokoMemberUsecase) ResolveActingToko(userID, tokoID uint, permission string) (model.Toko, error) {
+	member, err := uc.findActingMember(userID, tokoID)
+	if err != nil {
+		return model.Toko{}, err
+	}
+	if !member.HasPermission(permission) {
+		return model.Toko{}, fmt.Errorf("%w: role %s can't do %s", ErrTokoForbidden, member.Role, permission)
+	}
+	if member.Toko == nil {
+		return model.Toko{}, errors.New("toko not found")
+	}
+	return *member.Toko, nil
+}
+
+// acting toko for write produk, toko must be active
+func resolveWritableToko(tokoMemberUsecase TokoMemberUsecase, userID, tokoID uint) (model.Toko, error) {
+	toko, err := tokoMemberUsecase.ResolveActingToko(userID, tokoID, model.TokoPermProdukWrite)
+	if err != nil {
+		return toko, err
+	}
+	if !toko.IsActive() {
+		return model.Toko{}, fmt.Errorf("%w: status toko %s", ErrTokoNotActive, toko.Status)
+	}
+	return toko, nil
+}
+
+func (uc *tokoMemberUsecase) GetMyTokoList(userID uint) ([]MyToko, error) {
+	members, err := uc.memberRepo.FindAllByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed get your toko: %w", err)
+	}
+
+	tokos := []MyToko{}
+	for _, member := range members {
+		if member.Toko == nil {
+			continue
+		}
+		tokos = append(tokos, MyToko{
+			IDToko:   member.IDToko,
+			NamaToko: member.Toko.NamaToko,
+			Slug:     member.Toko.SlugValue(),
+			Role:     member.Role,
+		})
+	}
+	return tokos, nil
+}
+
+func toTokoStaff(member model.TokoMember) TokoStaff {
+	staff := TokoStaff{IDUser: member.IDUser, Role: member.Role, CreatedAtDate: member.CreatedAtDate}
+	if member.User != nil {
+		staff.Nama = member.User.Nama
+		staff.Email = member.User.Email
+	}
+	return staff
+}
+
+// every staff can see other staff of same toko
+func (uc *tokoMemberUsecase) GetStaff(userID, tokoID uint) ([]TokoStaff, error) {
+	toko, err := uc.ResolveActingToko(userID, tokoID, model.TokoPermTokoRead)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := uc.memberRepo.FindAllByTokoID(toko.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed get staff toko: %w", err)
+	}
+
+	staff := []TokoStaff{}
+	for _, member := range members {
+		staff = append(staff, toTokoStaff(member))
+	}
+	return staff, nil
+}
+
+func (uc *tokoMemberUsecase) findStaff(tokoID, staffUserID uint) (model.TokoMember, error) {
+	member, err := uc.memberRepo.FindMember(tokoID, staffUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return member, errors.New("staff not found")
+		}
+		return member, fmt.Errorf("failed get staff: %w", err)
+	}
+	return member, nil
+}
+
+func (uc *tokoMemberUsecase) UpdateStaffRole(userID, tokoID, staffUserID uint, role string) (TokoStaff, error) {
+	if err := validateStaffRole(role); err != nil {
+		return TokoStaff{}, err
+	}
+
+	toko, err := uc.ResolveActingToko(userID, tokoID, model.TokoPermStaffManage)
+	if err != nil {
+		return TokoStaff{}, err
+	}
+
+	member, err := uc.findStaff(toko.ID, staffUserID)
+	if err != nil {
+		return TokoStaff{}, err
+	}
+	if member.Role == model.TokoRoleOwner {
+		return TokoStaff{}, errors.New("role of owner can't be changed")
+	}
+
+	member.Role = role
+	member.UpdatedAtDate = time.Now()
+	if _, err := uc.memberRepo.Update(member); err != nil {
+		return TokoStaff{}, fmt.Errorf("failed update role staff: %w", err)
+	}
+	return toTokoStaff(member), nil
+}
+
+// staff can remove himself (leave toko) without staff:manage
+func (uc *tokoMemberUsecase) RemoveStaff(userID, tokoID, staffUserID uint) error {
+	permission := model.TokoPermStaffManage
+	if staffUserID == userID {
+		permission = model.TokoPermTokoRead
+	}
+
+	toko, err := uc.ResolveActingToko(userID, tokoID, permission)
+	if err != nil {
+		return err
+	}
+
+	member, err := uc.findStaff(toko.ID, staffUserID)
+	if err != nil {
+		return err
+	}
+	if member.Role == model.TokoRoleOwner {
+		return errors.New("owner can't be removed from toko")
+	}
+
+	if err := uc.memberRepo.Delete(member); err != nil {
+		return fmt.Errorf("failed remove staff: %w", err)
+	}
+	return nil
+}
+
+func (uc *tokoMemberUsecase) InviteStaff(userID, tokoID uint, email, role string) (CreatedTokoInvitation, error) {
+	if err := validateStaffRole(role); err != nil {
+		return CreatedTokoInvitation{}, err
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	toko, err := uc.ResolveActingToko(userID, tokoID, model.TokoPermStaffManage)
+	if err != nil {
+		return CreatedTokoInvitation{}, err
+	}
+
+	// already staff
+	if invited, err := uc.userRepo.FindByEmail(email); err == nil {
+		if _, err := uc.memberRepo.FindMember(toko.ID, invited.ID); err == nil {
+			return CreatedTokoInvitation{}, errors.New("user already staff of this toko")
+		}
+	}
+
+	now := time.Now()
+	invitations, err := uc.memberRepo.FindInvitationsByTokoID(toko.ID)
+	if err != nil {
+		return CreatedTokoInvitation{}, fmt.Errorf("failed check invitation: %w", err)
+	}
+	for _, invitation := range invitations {
+		if invitation.Email == email && invitation.AcceptedAt == nil && invitation.RevokedAt == nil && now.Before(invitation.ExpiresAt) {
+			return CreatedTokoInvitation{}, errors.New("email already invited, revoke old invitation first")
+		}
+	}
+
+	token, err := utils.RandomURLString(32)
+	if err != nil {
+		return CreatedTokoInvitation{}, fmt.Errorf("failed generate invitation token: %w", err)
+	}
+
+	invitation := model.TokoInvitation{
+		IDToko:        toko.ID,
+		IDInviter:     userID,
+		Email:         email,
+		Role:          role,
+		TokenHash:     utils.HashAPIKey(token),
+		ExpiresAt:     now.Add(tokoInvitationTTL),
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	}
+	savedInvitation, err := uc.memberRepo.SaveInvitation(invitation)
+	if err != nil {
+		return CreatedTokoInvitation{}, fmt.Errorf("failed save invitation: %w", err)
+	}
+	return CreatedTokoInvitation{Invitation: savedInvitation, Token: token}, nil
+}
+
+func (uc *tokoMemberUsecase) GetInvitations(userID, tokoID uint) ([]model.TokoInvitation, error) {
+	toko, err := uc.ResolveActingToko(userID, tokoID, model.TokoPermStaffManage)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := uc.memberRepo.FindInvitationsByTokoID(toko.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed get invitation: %w", err)
+	}
+	return invitations, nil
+}
+
+func (uc *tokoMemberUsecase) RevokeInvitation(userID, tokoID, invitationID uint) error {
+	toko, err := uc.ResolveActingToko(userID, tokoID, model.TokoPermStaffManage)
+	if err != nil {
+		return err
+	}
+
+	invitation, err := uc.memberRepo.FindInvitationByID(toko.ID, invitationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invitation not found")
+		}
+		return fmt.Errorf("failed get invitation: %w", err)
+	}
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return repository.ErrInvitationNotPending
+	}
+
+	now := time.Now()
+	invitation.RevokedAt = &now
+	invitation.UpdatedAtDate = now
+	if _, err := uc.memberRepo.UpdateInvitation(invitation); err != nil {
+		return fmt.Errorf("failed revoke invitation: %w", err)
+	}
+	return nil
+}
+
+// only user login with invited email can accept
+func (uc *tokoMemberUsecase) AcceptInvitation(userID uint, token string) (MyToko, error) {
+	invalidErr := errors.New("invitation not valid or expired")
+
+	invitation, err := uc.memberRepo.FindInvitationByTokenHash(utils.HashAPIKey(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return MyToko{}, invalidErr
+		}
+		return MyToko{}, fmt.Errorf("failed get invitation: %w", err)
+	}
+
+	now := time.Now()
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || !now.Before(invitation.ExpiresAt) {
+		return MyToko{}, invalidErr
+	}
+
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil {
+		return MyToko{}, fmt.Errorf("failed get user: %w", err)
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return MyToko{}, errors.New("invitation is for other email")
+	}
+	if _, err := uc.memberRepo.FindMember(invitation.IDToko, userID); err == nil {
+		return MyToko{}, errors.New("you already staff of this toko")
+	}
+
+	member := model.TokoMember{
+		IDToko:        invitation.IDToko,
+		IDUser:        userID,
+		Role:          invitation.Role,
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	}
+	if _, err := uc.memberRepo.AcceptInvitation(invitation, member, now); err != nil {
+		if errors.Is(err, repository.ErrInvitationNotPending) {
+			return MyToko{}, invalidErr
+		}
+		return MyToko{}, fmt.Errorf("failed accept invitation: %w", err)
+	}
+
+	joined, err := uc.memberRepo.FindMember(invitation.IDToko, userID)
+	if err != nil || joined.Toko == nil {
+		return MyToko{IDToko: invitation.IDToko, Role: invitation.Role}, nil
+	}
+	return MyToko{IDToko: joined.IDToko, NamaToko: joined.Toko.NamaToko, Slug: joined.Toko.SlugValue(), Role: joined.Role}, nil
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+type fakeTokoMemberRepo struct {
+	repository.TokoMemberRepository
+	members []model.TokoMember
+}
+
+func (r *fakeTokoMemberRepo) FindMember(tokoID, userID uint) (model.TokoMember, error) {
+	for _, member := range r.members {
+		if member.IDToko == tokoID && member.IDUser == userID {
+			return member, nil
+		}
+	}
+	return model.TokoMember{}, gorm.ErrRecordNotFound
+}
+
+// owner first like real repository
+func (r *fakeTokoMemberRepo) FindAllByUserID(userID uint) ([]model.TokoMember, error) {
+	var owned, staff []model.TokoMember
+	for _, member := range r.members {
+		if member.IDUser != userID {
+			continue
+		}
+		if member.Role == model.TokoRoleOwner {
+			owned = append(owned, member)
+		} else {
+			staff = append(staff, member)
+		}
+	}
+	return append(owned, staff...), nil
+}
+
+func newTestMember(tokoID, userID uint, role string) model.TokoMember {
+	return model.TokoMember{IDToko: tokoID, IDUser: userID, Role: role, Toko: &model.Toko{ID: tokoID}}
+}
+
+func TestResolveActingToko(t *testing.T) {
+	repo := &fakeTokoMemberRepo{members: []model.TokoMember{
+		newTestMember(1, 10, model.TokoRoleOwner),
+		newTestMember(2, 10, model.TokoRoleManager),
+		newTestMember(2, 20, model.TokoRolePacker),
+		newTestMember(3, 30, model.TokoRoleManager),
+		newTestMember(4, 30, model.TokoRolePacker),
+	}}
+	uc := NewTokoMemberUsecase(repo, nil)
+
+	cases := []struct {
+		name       string
+		userID     uint
+		tokoID     uint
+		permission string
+		wantToko   uint
+		wantErr    error
+	}{
+		{"owner default to own toko", 10, 0, model.TokoPermStaffManage, 1, nil},
+		{"owner act as manager of other toko", 10, 2, model.TokoPermProdukWrite, 2, nil},
+		{"manager can't manage staff", 10, 2, model.TokoPermStaffManage, 0, ErrTokoForbidden},
+		{"only membership become default", 20, 0, model.TokoPermProdukRead, 2, nil},
+		{"packer can't write produk", 20, 0, model.TokoPermProdukWrite, 0, ErrTokoForbidden},
+		{"not staff of toko", 20, 1, model.TokoPermTokoRead, 0, ErrTokoForbidden},
+		{"many toko without owned must choose", 30, 0, model.TokoPermTokoRead, 0, ErrActingTokoRequired},
+		{"many toko with choice", 30, 4, model.TokoPermProdukRead, 4, nil},
+	}
+	for _, tc := range cases {
+		toko, err := uc.ResolveActingToko(tc.userID, tc.tokoID, tc.permission)
+		if tc.wantErr != nil {
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if toko.ID != tc.wantToko {
+			t.Errorf("%s: expected toko %d, got %d", tc.name, tc.wantToko, toko.ID)
+		}
+	}
+
+	if _, err := uc.ResolveActingToko(99, 0, model.TokoPermTokoRead); err == nil {
+		t.Error("user without toko: expected error")
+	}
+}
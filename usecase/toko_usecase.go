@@ -3,31 +3,176 @@ package usecase
 import (
 	"errors"
 	"fmt"
+	"math"
+	"os"
+	"strconv"
 	"time"
 
 	"rakamin-evermos/model"
 	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
 
 	"gorm.io/gorm"
 )
 
 type TokoUsecase interface {
-	GetMyToko(userID uint) (model.Toko, error)
-	UpdateMyToko(userID uint, input model.Toko) (model.Toko, error)
-	UploadTokoPhoto(userID uint, filePath string) (model.Toko, error)
+	// tokoID is acting toko (0 = default toko of user)
+	GetMyToko(userID, tokoID uint) (model.Toko, error)
+	UpdateMyToko(userID, tokoID uint, input model.Toko) (model.Toko, error) // input.JamOperasional nil = keep current jam
+	UploadTokoPhoto(userID, tokoID uint, filePath string) (model.Toko, error)
+	SetLibur(userID, tokoID uint, isLibur bool, liburSampai *time.Time, pesanLibur string) (model.Toko, error)
+
+	// verifikasi toko, only owner and only while draft
+	UploadDokumen(userID, tokoID uint, jenis, namaFile, filePath string) (model.TokoDokumen, error)
+	GetDokumen(userID, tokoID uint) ([]model.TokoDokumen, error)
+	DeleteDokumen(userID, tokoID, dokumenID uint) error
+	SubmitVerifikasi(userID, tokoID uint) (model.Toko, error)
+
+	// public storefront, idOrSlug is ID toko or slug
+	GetTokoProfile(idOrSlug string) (TokoProfile, error)
+	GetTokoProduk(idOrSlug string, pagination utils.PaginationInput, filter repository.FilterInput) (utils.PaginationResult, error)
+	GetTokoProdukCursor(idOrSlug string, cursor utils.CursorInput, filter repository.FilterInput) (utils.CursorResult, error)
+
+	BackfillSlugs() error
+	ActivateLegacyToko() error
+}
+
+// from ulasan of all produk of toko, Average 0 when no ulasan yet
+type RatingSummary struct {
+	Average float64 `json:"average"`
+	Count   int64   `json:"count"`
+}
+
+// public data of toko, without id_user and detail alamat
+type TokoProfile struct {
+	ID             uint                   `json:"id"`
+	NamaToko       string                 `json:"nama_toko"`
+	Slug           string                 `json:"slug"`
+	UrlFoto        string                 `json:"url_foto"`
+	Deskripsi      string                 `json:"deskripsi"`
+	NoTelp         string                 `json:"no_telp"`
+	EmailKontak    string                 `json:"email_kontak"`
+	IDProvinsi     uint                   `json:"id_provinsi"`
+	IDKota         uint                   `json:"id_kota"`
+	JamOperasional []model.JamOperasional `json:"jam_operasional"`
+	SedangLibur    bool                   `json:"sedang_libur"`
+	LiburSampai    *time.Time             `json:"libur_sampai,omitempty"`
+	PesanLibur     string                 `json:"pesan_libur,omitempty"`
+	JumlahProduk   int64                  `json:"jumlah_produk"`
+	Rating         RatingSummary          `json:"rating"`
+	CreatedAtDate  time.Time              `json:"created_at_date"`
+}
+
+// input toko not valid, handler return 400
+var ErrTokoNotValid = errors.New("toko not valid")
+
+// dokumen verifikasi can only be changed before submit or after rejected
+func tokoDraftOnly(toko model.Toko) error {
+	if toko.Status != model.TokoStatusDraft {
+		return fmt.Errorf("%w: toko with status %s can't change dokumen verifikasi", ErrTokoNotValid, toko.Status)
+	}
+	return nil
+}
+
+func removeUploadedFile(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("failed remove file %s: %v\n", path, err)
+	}
 }
 
 type tokoUsecase struct {
-	tokoRepo repository.TokoRepository
+	tokoRepo          repository.TokoRepository
+	produkRepo        repository.ProdukRepository
+	regionUsecase     RegionUsecase
+	tokoMemberUsecase TokoMemberUsecase
+	indexer           ProdukIndexer
+	ulasanRepo        repository.UlasanRepository
+}
+
+func NewTokoUsecase(tokoRepo repository.TokoRepository, produkRepo repository.ProdukRepository, regionUsecase RegionUsecase, tokoMemberUsecase TokoMemberUsecase, indexer ProdukIndexer, ulasanRepo repository.UlasanRepository) TokoUsecase {
+	return &tokoUsecase{tokoRepo, produkRepo, regionUsecase, tokoMemberUsecase, indexer, ulasanRepo}
+}
+
+// one row per hari at most, jam "HH:MM" and buka before tutup
+func validateJamOperasional(jamOperasional []model.JamOperasional) error {
+	seen := map[int]bool{}
+	for _, jam := range jamOperasional {
+		if jam.Hari < 0 || jam.Hari > 6 {
+			return fmt.Errorf("%w: hari must be 0 (minggu) until 6 (sabtu)", ErrTokoNotValid)
+		}
+		if seen[jam.Hari] {
+			return fmt.Errorf("%w: hari %d filled more than once", ErrTokoNotValid, jam.Hari)
+		}
+		seen[jam.Hari] = true
+
+		if jam.Tutup {
+			continue
+		}
+		buka, err := time.Parse("15:04", jam.JamBuka)
+		if err != nil {
+			return fmt.Errorf("%w: jam buka %q must be HH:MM", ErrTokoNotValid, jam.JamBuka)
+		}
+		tutup, err := time.Parse("15:04", jam.JamTutup)
+		if err != nil {
+			return fmt.Errorf("%w: jam tutup %q must be HH:MM", ErrTokoNotValid, jam.JamTutup)
+		}
+		if !buka.Before(tutup) {
+			return fmt.Errorf("%w: jam buka must be before jam tutup on hari %d", ErrTokoNotValid, jam.Hari)
+		}
+	}
+	return nil
+}
+
+// slug from nama toko, "-2", "-3" added when already used by other toko.
+// never only digit or "me", so /toko/:id can tell ID, slug and /toko/me apart
+func uniqueTokoSlug(tokoRepo repository.TokoRepository, namaToko string, exceptTokoID uint) (string, error) {
+	base := utils.Slugify(namaToko)
+	if base == "" {
+		base = "toko"
+	} else if _, err := strconv.ParseUint(base, 10, 64); err == nil || base == "me" {
+		base = "toko-" + base
+	}
+
+	slug := base
+	for i := 2; ; i++ {
+		exists, err := tokoRepo.SlugExists(slug, exceptTokoID)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
 }
 
-func NewTokoUsecase(tokoRepo repository.TokoRepository) TokoUsecase {
-	return &tokoUsecase{tokoRepo}
+// slug save attempt before giving up with ErrTokoSlugTaken (409)
+const tokoSlugAttempts = 3
+
+// slug checked before save but other toko can take it in between, then next free slug is picked and saved again
+func saveTokoWithSlug(tokoRepo repository.TokoRepository, toko model.Toko, save func(model.Toko) (model.Toko, error)) (model.Toko, error) {
+	savedToko, err := save(toko)
+	for attempt := 1; errors.Is(err, repository.ErrTokoSlugTaken) && attempt < tokoSlugAttempts; attempt++ {
+		var slug string
+		slug, err = uniqueTokoSlug(tokoRepo, toko.NamaToko, toko.ID)
+		if err != nil {
+			return toko, err
+		}
+		toko.Slug = &slug
+		savedToko, err = save(toko)
+	}
+	return savedToko, err
 }
 
 
-func (uc *tokoUsecase) GetMyToko(userID uint) (model.Toko, error) {
-	toko, err := uc.tokoRepo.FindByUserID(userID)
+// toko where user is staff with permission, loaded with jam operasional
+func (uc *tokoUsecase) getActingToko(userID, tokoID uint, permission string) (model.Toko, error) {
+	actingToko, err := uc.tokoMemberUsecase.ResolveActingToko(userID, tokoID, permission)
+	if err != nil {
+		return actingToko, err
+	}
+
+	toko, err := uc.tokoRepo.FindByID(actingToko.ID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return toko, errors.New("toko not found")
@@ -37,32 +182,106 @@ func (uc *tokoUsecase) GetMyToko(userID uint) (model.Toko, error) {
 	return toko, nil
 }
 
-func (uc *tokoUsecase) UpdateMyToko(userID uint, input model.Toko) (model.Toko, error) {
-	existingToko, err := uc.tokoRepo.FindByUserID(userID)
+func (uc *tokoUsecase) GetMyToko(userID, tokoID uint) (model.Toko, error) {
+	return uc.getActingToko(userID, tokoID, model.TokoPermTokoRead)
+}
+
+func (uc *tokoUsecase) UpdateMyToko(userID, tokoID uint, input model.Toko) (model.Toko, error) {
+	if err := validateJamOperasional(input.JamOperasional); err != nil {
+		return model.Toko{}, err
+	}
+	err := uc.regionUsecase.ValidateRegion(RegionInput{
+		IDProvinsi:  input.IDProvinsi,
+		IDKota:      input.IDKota,
+		IDKecamatan: input.IDKecamatan,
+		IDKelurahan: input.IDKelurahan,
+		KodePos:     input.KodePos,
+	})
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return model.Toko{}, errors.New("toko not found or you do not have access")
+		return model.Toko{}, err
+	}
+
+	existingToko, err := uc.getActingToko(userID, tokoID, model.TokoPermTokoWrite)
+	if err != nil {
+		return model.Toko{}, err
+	}
+	renamed := existingToko.NamaToko != input.NamaToko
+
+	if existingToko.SlugValue() == "" || existingToko.NamaToko != input.NamaToko {
+		slug, err := uniqueTokoSlug(uc.tokoRepo, input.NamaToko, existingToko.ID)
+		if err != nil {
+			return model.Toko{}, fmt.Errorf("failed to create slug toko: %w", err)
 		}
-		return model.Toko{}, fmt.Errorf("failed to verify toko: %w", err)
+		existingToko.Slug = &slug
 	}
 
+	now := time.Now()
 	existingToko.NamaToko = input.NamaToko
-	existingToko.UpdatedAtDate = time.Now()
+	existingToko.Deskripsi = input.Deskripsi
+	existingToko.NoTelp = input.NoTelp
+	existingToko.EmailKontak = input.EmailKontak
+	existingToko.DetailAlamat = input.DetailAlamat
+	existingToko.IDProvinsi = input.IDProvinsi
+	existingToko.IDKota = input.IDKota
+	existingToko.IDKecamatan = input.IDKecamatan
+	existingToko.IDKelurahan = input.IDKelurahan
+	existingToko.KodePos = input.KodePos
+	existingToko.UpdatedAtDate = now
 
-	updatedToko, err := uc.tokoRepo.Update(existingToko)
+	updatedToko, err := saveTokoWithSlug(uc.tokoRepo, existingToko, uc.tokoRepo.Update)
 	if err != nil {
 		return updatedToko, fmt.Errorf("failed to update toko: %w", err)
 	}
+
+	if input.JamOperasional != nil {
+		for i := range input.JamOperasional {
+			input.JamOperasional[i].CreatedAtDate = now
+			input.JamOperasional[i].UpdatedAtDate = now
+		}
+		if err := uc.tokoRepo.ReplaceJamOperasional(updatedToko.ID, input.JamOperasional); err != nil {
+			return updatedToko, fmt.Errorf("failed to update jam operasional: %w", err)
+		}
+	}
+	// nama toko is in search and suggest index of every produk
+	if renamed {
+		uc.indexer.ReindexToko(updatedToko.ID)
+	}
+	return uc.tokoRepo.FindByID(updatedToko.ID)
+}
+
+// while libur, produk hidden from GET /produk and can't be checkout
+func (uc *tokoUsecase) SetLibur(userID, tokoID uint, isLibur bool, liburSampai *time.Time, pesanLibur string) (model.Toko, error) {
+	now := time.Now()
+	if isLibur && liburSampai != nil && !liburSampai.After(now) {
+		return model.Toko{}, fmt.Errorf("%w: libur_sampai must be in the future", ErrTokoNotValid)
+	}
+
+	existingToko, err := uc.getActingToko(userID, tokoID, model.TokoPermTokoWrite)
+	if err != nil {
+		return model.Toko{}, err
+	}
+
+	existingToko.IsLibur = isLibur
+	existingToko.LiburSampai = nil
+	existingToko.PesanLibur = ""
+	if isLibur {
+		existingToko.LiburSampai = liburSampai
+		existingToko.PesanLibur = pesanLibur
+	}
+	existingToko.UpdatedAtDate = now
+
+	updatedToko, err := uc.tokoRepo.Update(existingToko)
+	if err != nil {
+		return updatedToko, fmt.Errorf("failed to update mode libur: %w", err)
+	}
+	uc.indexer.ReindexToko(updatedToko.ID)
 	return updatedToko, nil
 }
 
-func (uc *tokoUsecase) UploadTokoPhoto(userID uint, filePath string) (model.Toko, error) {
-	existingToko, err := uc.tokoRepo.FindByUserID(userID)
+func (uc *tokoUsecase) UploadTokoPhoto(userID, tokoID uint, filePath string) (model.Toko, error) {
+	existingToko, err := uc.getActingToko(userID, tokoID, model.TokoPermTokoWrite)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return model.Toko{}, errors.New("toko not found or you do not have access")
-		}
-		return model.Toko{}, fmt.Errorf("failed to verify toko: %w", err)
+		return model.Toko{}, err
 	}
 
 	existingToko.UrlFoto = filePath
@@ -73,4 +292,250 @@ func (uc *tokoUsecase) UploadTokoPhoto(userID uint, filePath string) (model.Toko
 		return updatedToko, fmt.Errorf("failed to upload toko photo: %w", err)
 	}
 	return updatedToko, nil
-}
\ No newline at end of file
+}
+
+// one dokumen per jenis, upload same jenis again replace the old file
+func (uc *tokoUsecase) UploadDokumen(userID, tokoID uint, jenis, namaFile, filePath string) (model.TokoDokumen, error) {
+	validJenis := false
+	for _, allowed := range model.TokoDokumenJenis {
+		if jenis == allowed {
+			validJenis = true
+		}
+	}
+	if !validJenis {
+		return model.TokoDokumen{}, fmt.Errorf("%w: jenis dokumen must be one of %v", ErrTokoNotValid, model.TokoDokumenJenis)
+	}
+
+	toko, err := uc.getActingToko(userID, tokoID, model.TokoPermVerifikasi)
+	if err != nil {
+		return model.TokoDokumen{}, err
+	}
+	if err := tokoDraftOnly(toko); err != nil {
+		return model.TokoDokumen{}, err
+	}
+
+	existing, err := uc.tokoRepo.FindDokumenByTokoID(toko.ID)
+	if err != nil {
+		return model.TokoDokumen{}, fmt.Errorf("failed get dokumen toko: %w", err)
+	}
+
+	now := time.Now()
+	dokumen := model.TokoDokumen{IDToko: toko.ID, Jenis: jenis, CreatedAtDate: now}
+	oldFile := ""
+	for _, old := range existing {
+		if old.Jenis == jenis {
+			dokumen = old
+			oldFile = old.Url
+		}
+	}
+	dokumen.Url = filePath
+	dokumen.NamaFile = namaFile
+	dokumen.UpdatedAtDate = now
+
+	savedDokumen, err := uc.tokoRepo.SaveDokumen(dokumen)
+	if err != nil {
+		return savedDokumen, fmt.Errorf("failed save dokumen toko: %w", err)
+	}
+	if oldFile != "" && oldFile != filePath {
+		removeUploadedFile(oldFile)
+	}
+	return savedDokumen, nil
+}
+
+func (uc *tokoUsecase) GetDokumen(userID, tokoID uint) ([]model.TokoDokumen, error) {
+	toko, err := uc.getActingToko(userID, tokoID, model.TokoPermVerifikasi)
+	if err != nil {
+		return nil, err
+	}
+	dokumen, err := uc.tokoRepo.FindDokumenByTokoID(toko.ID)
+	if err != nil {
+		return dokumen, fmt.Errorf("failed get dokumen toko: %w", err)
+	}
+	return dokumen, nil
+}
+
+func (uc *tokoUsecase) DeleteDokumen(userID, tokoID, dokumenID uint) error {
+	toko, err := uc.getActingToko(userID, tokoID, model.TokoPermVerifikasi)
+	if err != nil {
+		return err
+	}
+	if err := tokoDraftOnly(toko); err != nil {
+		return err
+	}
+
+	dokumen, err := uc.tokoRepo.FindDokumenByID(toko.ID, dokumenID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("dokumen not found")
+		}
+		return fmt.Errorf("failed get dokumen toko: %w", err)
+	}
+	if err := uc.tokoRepo.DeleteDokumen(dokumen); err != nil {
+		return fmt.Errorf("failed delete dokumen toko: %w", err)
+	}
+	removeUploadedFile(dokumen.Url)
+	return nil
+}
+
+// draft -> pending_review, need required dokumen and alamat asal pengiriman
+func (uc *tokoUsecase) SubmitVerifikasi(userID, tokoID uint) (model.Toko, error) {
+	toko, err := uc.getActingToko(userID, tokoID, model.TokoPermVerifikasi)
+	if err != nil {
+		return model.Toko{}, err
+	}
+	if toko.Status != model.TokoStatusDraft {
+		return model.Toko{}, fmt.Errorf("%w: only draft toko can be submitted, current status %s", ErrTokoNotValid, toko.Status)
+	}
+	if toko.DetailAlamat == "" || toko.IDKota == 0 {
+		return model.Toko{}, fmt.Errorf("%w: fill detail_alamat and id_kota of toko before submit", ErrTokoNotValid)
+	}
+
+	dokumen, err := uc.tokoRepo.FindDokumenByTokoID(toko.ID)
+	if err != nil {
+		return model.Toko{}, fmt.Errorf("failed get dokumen toko: %w", err)
+	}
+	uploaded := map[string]bool{}
+	for _, d := range dokumen {
+		uploaded[d.Jenis] = true
+	}
+	for _, jenis := range model.RequiredTokoDokumen {
+		if !uploaded[jenis] {
+			return model.Toko{}, fmt.Errorf("%w: dokumen %s is required", ErrTokoNotValid, jenis)
+		}
+	}
+
+	now := time.Now()
+	toko.Status = model.TokoStatusPendingReview
+	toko.StatusReason = ""
+	toko.SubmittedAt = &now
+	toko.UpdatedAtDate = now
+
+	updatedToko, err := uc.tokoRepo.Update(toko)
+	if err != nil {
+		return updatedToko, fmt.Errorf("failed submit toko: %w", err)
+	}
+	return updatedToko, nil
+}
+
+// not active toko (draft, in review, suspended) is hidden from buyer
+func (uc *tokoUsecase) findToko(idOrSlug string) (model.Toko, error) {
+	var toko model.Toko
+	var err error
+	if tokoID, parseErr := strconv.ParseUint(idOrSlug, 10, 64); parseErr == nil {
+		toko, err = uc.tokoRepo.FindByID(uint(tokoID))
+	} else {
+		toko, err = uc.tokoRepo.FindBySlug(idOrSlug)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return toko, errors.New("toko not found")
+		}
+		return toko, fmt.Errorf("failed to retrieve toko data: %w", err)
+	}
+	if !toko.IsActive() {
+		return model.Toko{}, errors.New("toko not found")
+	}
+	return toko, nil
+}
+
+func (uc *tokoUsecase) GetTokoProfile(idOrSlug string) (TokoProfile, error) {
+	toko, err := uc.findToko(idOrSlug)
+	if err != nil {
+		return TokoProfile{}, err
+	}
+
+	jumlahProduk, err := uc.tokoRepo.CountProduk(toko.ID)
+	if err != nil {
+		return TokoProfile{}, fmt.Errorf("failed to count produk toko: %w", err)
+	}
+	rating, err := uc.ulasanRepo.SummaryByTokoID(toko.ID)
+	if err != nil {
+		return TokoProfile{}, fmt.Errorf("failed to get rating toko: %w", err)
+	}
+
+	profile := TokoProfile{
+		ID:             toko.ID,
+		NamaToko:       toko.NamaToko,
+		Slug:           toko.SlugValue(),
+		UrlFoto:        toko.UrlFoto,
+		Deskripsi:      toko.Deskripsi,
+		NoTelp:         toko.NoTelp,
+		EmailKontak:    toko.EmailKontak,
+		IDProvinsi:     toko.IDProvinsi,
+		IDKota:         toko.IDKota,
+		JamOperasional: toko.JamOperasional,
+		JumlahProduk:   jumlahProduk,
+		Rating:         RatingSummary{Average: math.Round(rating.Rata*10) / 10, Count: rating.Jumlah},
+		CreatedAtDate:  toko.CreatedAtDate,
+	}
+	if toko.SedangLibur(time.Now()) {
+		profile.SedangLibur = true
+		profile.LiburSampai = toko.LiburSampai
+		profile.PesanLibur = toko.PesanLibur
+	}
+	return profile, nil
+}
+
+func (uc *tokoUsecase) GetTokoProduk(idOrSlug string, pagination utils.PaginationInput, filter repository.FilterInput) (utils.PaginationResult, error) {
+	toko, err := uc.findToko(idOrSlug)
+	if err != nil {
+		return utils.PaginationResult{}, err
+	}
+
+	// draft and archived produk only in seller list
+	filter.Status = model.ProdukStatusPublished
+	produks, totalData, err := uc.produkRepo.FindAllByTokoID(toko.ID, pagination, filter)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("failed get produk toko: %w", err)
+	}
+	return utils.GeneratePaginationResult(produks, totalData, pagination.Page, pagination.Limit), nil
+}
+
+func (uc *tokoUsecase) GetTokoProdukCursor(idOrSlug string, cursor utils.CursorInput, filter repository.FilterInput) (utils.CursorResult, error) {
+	after, err := utils.DecodeCursor(cursor.Cursor)
+	if err != nil {
+		return utils.CursorResult{}, err
+	}
+	toko, err := uc.findToko(idOrSlug)
+	if err != nil {
+		return utils.CursorResult{}, err
+	}
+
+	filter.Status = model.ProdukStatusPublished
+	produks, next, err := uc.produkRepo.FindAllByTokoIDAfter(toko.ID, after, cursor.Limit, filter)
+	if err != nil {
+		return utils.CursorResult{}, fmt.Errorf("failed get produk toko: %w", err)
+	}
+	return utils.GenerateCursorResult(produks, next, cursor.Limit)
+}
+
+// run on startup, give slug to toko created before slug exist
+func (uc *tokoUsecase) BackfillSlugs() error {
+	tokos, err := uc.tokoRepo.FindAllWithoutSlug()
+	if err != nil {
+		return fmt.Errorf("failed to get toko without slug: %w", err)
+	}
+
+	for _, toko := range tokos {
+		slug, err := uniqueTokoSlug(uc.tokoRepo, toko.NamaToko, toko.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create slug toko %d: %w", toko.ID, err)
+		}
+		toko.Slug = &slug
+		if _, err := uc.tokoRepo.Update(toko); err != nil {
+			return fmt.Errorf("failed to save slug toko %d: %w", toko.ID, err)
+		}
+	}
+	if err := uc.tokoRepo.EnsureSlugUniqueIndex(); err != nil {
+		return fmt.Errorf("failed to create unique index slug toko: %w", err)
+	}
+	return nil
+}
+
+// run on startup, toko created before verifikasi exist keep selling
+func (uc *tokoUsecase) ActivateLegacyToko() error {
+	if _, err := uc.tokoRepo.ActivateLegacyToko(time.Now()); err != nil {
+		return fmt.Errorf("failed to activate legacy toko: %w", err)
+	}
+	return nil
+}
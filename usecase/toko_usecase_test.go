@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+)
+
+func TestSaveTokoWithSlugRetryWhenTaken(t *testing.T) {
+	repo := &fakeTokoRepo{}
+	uniqueSave := func(toko model.Toko) (model.Toko, error) {
+		if taken, _ := repo.SlugExists(toko.SlugValue(), toko.ID); taken {
+			return toko, repository.ErrTokoSlugTaken
+		}
+		return repo.Save(toko)
+	}
+
+	// other toko saved "toko-budi" after slug was picked
+	slug := "toko-budi"
+	toko := model.Toko{NamaToko: "Toko Budi", Slug: &slug}
+	repo.Save(toko)
+	saved, err := saveTokoWithSlug(repo, toko, uniqueSave)
+	if err != nil || saved.SlugValue() != "toko-budi-2" {
+		t.Fatalf("expected toko-budi-2, got %q %v", saved.SlugValue(), err)
+	}
+
+	alwaysTaken := func(toko model.Toko) (model.Toko, error) { return toko, repository.ErrTokoSlugTaken }
+	if _, err := saveTokoWithSlug(repo, toko, alwaysTaken); !errors.Is(err, repository.ErrTokoSlugTaken) {
+		t.Fatalf("expected ErrTokoSlugTaken after retry, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"rakamin-evermos/model"
 	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
 	"strconv"
 	"time"
 
@@ -21,6 +22,7 @@ type TransaksiUsecase interface {
 	CreateTransaksi(userID, alamatID uint, methodBayar string, items []CartItemInput) (model.Trx, error)
 
 	GetMyTransaksi(userID uint) ([]model.Trx, error)
+	GetMyTransaksiCursor(userID uint, cursor utils.CursorInput) (utils.CursorResult, error)
 	GetMyTransaksiByID(userID, trxID uint) (model.Trx, error)
 }
 
@@ -30,7 +32,9 @@ type transaksiUsecase struct {
 	transaksiRepo repository.TransaksiRepository
 	detailTrxRepo repository.DetailTrxRepository
 	logProdukRepo repository.LogProdukRepository
+	logAlamatRepo repository.LogAlamatRepository
 	produkRepo    repository.ProdukRepository
+	tokoRepo      repository.TokoRepository
 	addressRepo   repository.AddressRepository
 }
 
@@ -39,7 +43,9 @@ func NewTransaksiUsecase(
 	transaksiRepo repository.TransaksiRepository,
 	detailTrxRepo repository.DetailTrxRepository,
 	logProdukRepo repository.LogProdukRepository,
+	logAlamatRepo repository.LogAlamatRepository,
 	produkRepo repository.ProdukRepository,
+	tokoRepo repository.TokoRepository,
 	addressRepo repository.AddressRepository,
 ) TransaksiUsecase {
 	return &transaksiUsecase{
@@ -47,7 +53,9 @@ func NewTransaksiUsecase(
 		transaksiRepo,
 		detailTrxRepo,
 		logProdukRepo,
+		logAlamatRepo,
 		produkRepo,
+		tokoRepo,
 		addressRepo,
 	}
 }
@@ -55,8 +63,18 @@ func NewTransaksiUsecase(
 
 func (uc *transaksiUsecase) CreateTransaksi(userID, alamatID uint, methodBayar string, items []CartItemInput) (model.Trx, error) {
 
-	// verify userid and alamat
-	_, err := uc.addressRepo.FindByIDAndUserID(alamatID, userID)
+	// verify userid and alamat, without alamatID use default alamat of user
+	if alamatID == 0 {
+		alamat, err := uc.addressRepo.FindDefaultByUserID(userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return model.Trx{}, errors.New("alamat_pengiriman_id is required, user has no default alamat")
+			}
+			return model.Trx{}, err
+		}
+		alamatID = alamat.ID
+	}
+	alamat, err := uc.addressRepo.FindByIDAndUserID(alamatID, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return model.Trx{}, errors.New("alamat not found or access denied")
@@ -77,6 +95,7 @@ func (uc *transaksiUsecase) CreateTransaksi(userID, alamatID uint, methodBayar s
 
 	var grandTotal int = 0
 	var createdDetails []model.DetailTrx
+	checkedToko := map[uint]bool{}
 
 	// Loop every item in cart
 	for _, item := range items {
@@ -87,6 +106,30 @@ func (uc *transaksiUsecase) CreateTransaksi(userID, alamatID uint, methodBayar s
 			return model.Trx{}, errors.New("produk not found")
 		}
 
+		// draft and archived produk can't be ordered
+		if produk.Status != model.ProdukStatusPublished {
+			tx.Rollback()
+			return model.Trx{}, fmt.Errorf("produk '%s' is not available", produk.NamaProduk)
+		}
+
+		// toko not active or in mode libur can't get new order
+		if !checkedToko[produk.IDToko] {
+			toko, err := uc.tokoRepo.FindByIDWithTx(tx, produk.IDToko)
+			if err != nil {
+				tx.Rollback()
+				return model.Trx{}, errors.New("toko not found")
+			}
+			if !toko.IsActive() {
+				tx.Rollback()
+				return model.Trx{}, fmt.Errorf("produk '%s' is not available, toko is not active", produk.NamaProduk)
+			}
+			if toko.SedangLibur(time.Now()) {
+				tx.Rollback()
+				return model.Trx{}, fmt.Errorf("toko '%s' is on vacation, produk '%s' can't be ordered yet", toko.NamaToko, produk.NamaProduk)
+			}
+			checkedToko[produk.IDToko] = true
+		}
+
 		// check stok
 		if produk.Stok < item.Kuantitas {
 			tx.Rollback()
@@ -156,6 +199,16 @@ func (uc *transaksiUsecase) CreateTransaksi(userID, alamatID uint, methodBayar s
 		return model.Trx{}, fmt.Errorf("fail save header transaksi: %w", err)
 	}
 
+	// snapshot alamat, later edit or delete of alamat not change this order
+	logAlamat := model.NewLogAlamat(alamat, time.Now())
+	logAlamat.IDTrx = savedTrx.ID
+	savedLogAlamat, err := uc.logAlamatRepo.Save(tx, logAlamat)
+	if err != nil {
+		tx.Rollback()
+		return model.Trx{}, fmt.Errorf("fail save alamat pengiriman: %w", err)
+	}
+	savedTrx.LogAlamat = &savedLogAlamat
+
 	// 5. Update IDTrx in all DetailTrx then save
 	for _, detail := range createdDetails {
 		detail.IDTrx = savedTrx.ID
@@ -185,6 +238,20 @@ func (uc *transaksiUsecase) GetMyTransaksi(userID uint) ([]model.Trx, error) {
 	return trxs, nil
 }
 
+// newest first, page by cursor
+func (uc *transaksiUsecase) GetMyTransaksiCursor(userID uint, cursor utils.CursorInput) (utils.CursorResult, error) {
+	after, err := utils.DecodeCursor(cursor.Cursor)
+	if err != nil {
+		return utils.CursorResult{}, err
+	}
+
+	trxs, next, err := uc.transaksiRepo.FindAllByUserIDAfter(userID, after, cursor.Limit)
+	if err != nil {
+		return utils.CursorResult{}, fmt.Errorf("fail get history transaksi: %w", err)
+	}
+	return utils.GenerateCursorResult(trxs, next, cursor.Limit)
+}
+
 // get detail transaksi by user
 func (uc *transaksiUsecase) GetMyTransaksiByID(userID, trxID uint) (model.Trx, error) {
 	trx, err := uc.transaksiRepo.FindByUserAndTrxID(userID, trxID)
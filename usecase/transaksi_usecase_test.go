@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"testing"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils/testdb"
+)
+
+func TestCreateTransaksiSnapshotAlamat(t *testing.T) {
+	db := testdb.Open(t, &model.User{}, &model.Alamat{}, &model.Toko{}, &model.Produk{}, &model.LogProduk{},
+		&model.Trx{}, &model.DetailTrx{}, &model.LogAlamat{})
+	db.Create(&model.User{ID: 1, Email: "budi@example.com", NoTelp: "0811"})
+	db.Create(&model.Toko{ID: 1, IDUser: 2, NamaToko: "Toko Ani", Status: model.TokoStatusActive})
+	db.Create(&model.Produk{ID: 1, IDToko: 1, NamaProduk: "Laptop", HargaKonsumen: "5000", Stok: 3, Status: model.ProdukStatusPublished})
+
+	addressRepo := repository.NewAddressRepository(db)
+	transaksiRepo := repository.NewTransaksiRepository(db)
+	alamat, _ := addressRepo.Save(model.Alamat{IDUser: 1, JudulAlamat: "Rumah", NamaPenerima: "Budi", DetailAlamat: "Jl. Mawar 1"})
+	uc := NewTransaksiUsecase(db, transaksiRepo, repository.NewDetailTrxRepository(db), repository.NewLogProdukRepository(db),
+		repository.NewLogAlamatRepository(db), repository.NewProdukRepository(db), repository.NewTokoRepository(db), addressRepo)
+
+	// without alamat id use default alamat
+	trx, err := uc.CreateTransaksi(1, 0, "transfer", []CartItemInput{{ProdukID: 1, Kuantitas: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alamat.NamaPenerima, alamat.DetailAlamat = "Siti", "Jl. Melati 9"
+	if _, err := addressRepo.Update(alamat); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := transaksiRepo.FindByUserAndTrxID(1, trx.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.LogAlamat == nil || saved.LogAlamat.IDAlamat != alamat.ID ||
+		saved.LogAlamat.NamaPenerima != "Budi" || saved.LogAlamat.DetailAlamat != "Jl. Mawar 1" {
+		t.Fatalf("expected alamat at checkout time, got %+v", saved.LogAlamat)
+	}
+}
@@ -0,0 +1,224 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+const totalRecoveryCodes = 10
+
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorUsecase interface {
+	Enroll(userID uint) (TwoFactorEnrollment, error)
+	Confirm(userID uint, code string) ([]string, error) // return recovery codes (plain, shown once)
+	Disable(userID uint, password, code string) error
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+}
+
+type twoFactorUsecase struct {
+	userRepo      repository.UserRepository
+	twoFactorRepo repository.TwoFactorRepository
+}
+
+func NewTwoFactorUsecase(userRepo repository.UserRepository, twoFactorRepo repository.TwoFactorRepository) TwoFactorUsecase {
+	return &twoFactorUsecase{userRepo, twoFactorRepo}
+}
+
+func totpIssuer() string {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		return "Rakamin Evermos"
+	}
+	return issuer
+}
+
+// create new secret, 2FA still not active until Confirm
+func (uc *twoFactorUsecase) Enroll(userID uint) (TwoFactorEnrollment, error) {
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil {
+		return TwoFactorEnrollment{}, errors.New("user not found")
+	}
+
+	twoFactor, err := uc.twoFactorRepo.FindByUserID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return TwoFactorEnrollment{}, fmt.Errorf("failed get 2FA setting: %w", err)
+	}
+	if twoFactor.Enabled {
+		return TwoFactorEnrollment{}, errors.New("2FA already enabled, disable it first")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return TwoFactorEnrollment{}, fmt.Errorf("failed generate 2FA secret: %w", err)
+	}
+
+	now := time.Now()
+	if twoFactor.ID == 0 {
+		twoFactor.IDUser = userID
+		twoFactor.CreatedAtDate = now
+	}
+	twoFactor.Secret = secret
+	twoFactor.LastUsedStep = 0
+	twoFactor.UpdatedAtDate = now
+
+	if _, err := uc.twoFactorRepo.Save(twoFactor); err != nil {
+		return TwoFactorEnrollment{}, fmt.Errorf("failed save 2FA setting: %w", err)
+	}
+
+	return TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+func (uc *twoFactorUsecase) Confirm(userID uint, code string) ([]string, error) {
+	twoFactor, err := uc.twoFactorRepo.FindByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("2FA not enrolled yet")
+		}
+		return nil, fmt.Errorf("failed get 2FA setting: %w", err)
+	}
+	if twoFactor.Enabled {
+		return nil, errors.New("2FA already enabled")
+	}
+
+	// only totp code accepted here, recovery code not exist yet
+	if err := verifyTOTP(uc.twoFactorRepo, &twoFactor, code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	twoFactor.Enabled = true
+	twoFactor.ConfirmedAt = &now
+	twoFactor.UpdatedAtDate = now
+	if _, err := uc.twoFactorRepo.Save(twoFactor); err != nil {
+		return nil, fmt.Errorf("failed enable 2FA: %w", err)
+	}
+
+	return uc.generateRecoveryCodes(userID)
+}
+
+func (uc *twoFactorUsecase) Disable(userID uint, password, code string) error {
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if !utils.CheckPasswordHash(password, user.KataSandi) {
+		return errors.New("password incorrect")
+	}
+
+	twoFactor, err := uc.getEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if err := verifySecondFactor(uc.twoFactorRepo, &twoFactor, code); err != nil {
+		return err
+	}
+
+	if err := uc.twoFactorRepo.DeleteByUserID(userID); err != nil {
+		return fmt.Errorf("failed disable 2FA: %w", err)
+	}
+	return nil
+}
+
+func (uc *twoFactorUsecase) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	twoFactor, err := uc.getEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyTOTP(uc.twoFactorRepo, &twoFactor, code); err != nil {
+		return nil, err
+	}
+	return uc.generateRecoveryCodes(userID)
+}
+
+func (uc *twoFactorUsecase) getEnabled(userID uint) (model.UserTwoFactor, error) {
+	twoFactor, err := uc.twoFactorRepo.FindByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return twoFactor, errors.New("2FA not enabled")
+		}
+		return twoFactor, fmt.Errorf("failed get 2FA setting: %w", err)
+	}
+	if !twoFactor.Enabled {
+		return twoFactor, errors.New("2FA not enabled")
+	}
+	return twoFactor, nil
+}
+
+func (uc *twoFactorUsecase) generateRecoveryCodes(userID uint) ([]string, error) {
+	plainCodes, err := utils.GenerateRecoveryCodes(totalRecoveryCodes)
+	if err != nil {
+		return nil, fmt.Errorf("failed generate recovery codes: %w", err)
+	}
+
+	now := time.Now()
+	codes := make([]model.RecoveryCode, 0, len(plainCodes))
+	for _, plain := range plainCodes {
+		hash, err := utils.HashPassword(plain)
+		if err != nil {
+			return nil, fmt.Errorf("failed hash recovery code: %w", err)
+		}
+		codes = append(codes, model.RecoveryCode{IDUser: userID, CodeHash: hash, CreatedAtDate: now})
+	}
+
+	if err := uc.twoFactorRepo.ReplaceRecoveryCodes(userID, codes); err != nil {
+		return nil, fmt.Errorf("failed save recovery codes: %w", err)
+	}
+	return plainCodes, nil
+}
+
+// accept totp code or one of recovery code
+func verifySecondFactor(twoFactorRepo repository.TwoFactorRepository, twoFactor *model.UserTwoFactor, code string) error {
+	code = strings.TrimSpace(code)
+	if !strings.Contains(code, "-") {
+		return verifyTOTP(twoFactorRepo, twoFactor, code)
+	}
+
+	codes, err := twoFactorRepo.FindUnusedRecoveryCodes(twoFactor.IDUser)
+	if err != nil {
+		return fmt.Errorf("failed get recovery codes: %w", err)
+	}
+	for _, recoveryCode := range codes {
+		if utils.CheckPasswordHash(strings.ToLower(code), recoveryCode.CodeHash) {
+			if err := twoFactorRepo.MarkRecoveryCodeUsed(recoveryCode.ID); err != nil {
+				return errors.New("2FA code not valid")
+			}
+			return nil
+		}
+	}
+	return errors.New("2FA code not valid")
+}
+
+func verifyTOTP(twoFactorRepo repository.TwoFactorRepository, twoFactor *model.UserTwoFactor, code string) error {
+	step, ok := utils.ValidateTOTPCode(twoFactor.Secret, code, time.Now(), 1)
+	if !ok || step <= twoFactor.LastUsedStep {
+		return errors.New("2FA code not valid")
+	}
+
+	now := time.Now()
+	used, err := twoFactorRepo.UseTOTPStep(twoFactor.ID, step, now)
+	if err != nil {
+		return fmt.Errorf("failed save 2FA setting: %w", err)
+	}
+	if !used {
+		return errors.New("2FA code not valid")
+	}
+	twoFactor.LastUsedStep = step
+	twoFactor.UpdatedAtDate = now
+	return nil
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+type fakeSecondFactorRepo struct {
+	repository.TwoFactorRepository
+	mu           sync.Mutex
+	lastUsedStep int64
+	codes        []model.RecoveryCode
+}
+
+func (r *fakeSecondFactorRepo) UseTOTPStep(twoFactorID uint, step int64, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lastUsedStep >= step {
+		return false, nil
+	}
+	r.lastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeSecondFactorRepo) FindUnusedRecoveryCodes(userID uint) ([]model.RecoveryCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unused := []model.RecoveryCode{}
+	for _, code := range r.codes {
+		if code.UsedAt == nil {
+			unused = append(unused, code)
+		}
+	}
+	return unused, nil
+}
+
+func (r *fakeSecondFactorRepo) MarkRecoveryCodeUsed(codeID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.codes {
+		if r.codes[i].ID == codeID && r.codes[i].UsedAt == nil {
+			now := time.Now()
+			r.codes[i].UsedAt = &now
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func TestVerifyTOTPRejectReplay(t *testing.T) {
+	secret, _ := utils.GenerateTOTPSecret()
+	code, _ := utils.GenerateTOTPCode(secret, utils.TOTPStep(time.Now()))
+	repo := &fakeSecondFactorRepo{}
+
+	// same code sent in parallel only accepted once
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			twoFactor := model.UserTwoFactor{ID: 1, IDUser: 1, Secret: secret}
+			if verifyTOTP(repo, &twoFactor, code) == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 1 {
+		t.Fatalf("expected code accepted once, got %d", accepted)
+	}
+
+	twoFactor := model.UserTwoFactor{ID: 1, IDUser: 1, Secret: secret, LastUsedStep: repo.lastUsedStep}
+	if err := verifyTOTP(repo, &twoFactor, code); err == nil {
+		t.Fatal("used code must be rejected")
+	}
+}
+
+func TestVerifySecondFactorRecoveryCodeOnce(t *testing.T) {
+	plain := "abcde-fghjk"
+	hash, err := utils.HashPassword(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := utils.HashPassword("zzzzz-zzzzz")
+	repo := &fakeSecondFactorRepo{codes: []model.RecoveryCode{{ID: 1, IDUser: 1, CodeHash: other}, {ID: 2, IDUser: 1, CodeHash: hash}}}
+	twoFactor := model.UserTwoFactor{ID: 1, IDUser: 1}
+
+	if err := verifySecondFactor(repo, &twoFactor, " ABCDE-FGHJK "); err != nil {
+		t.Fatalf("recovery code must be accepted: %v", err)
+	}
+	if repo.codes[1].UsedAt == nil || repo.codes[0].UsedAt != nil {
+		t.Fatalf("only matched code must be used, got %+v", repo.codes)
+	}
+	if err := verifySecondFactor(repo, &twoFactor, plain); err == nil {
+		t.Fatal("recovery code must be rejected the second time")
+	}
+}
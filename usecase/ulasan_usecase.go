@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+	"rakamin-evermos/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	minRating         = 1
+	maxRating         = 5
+	maxKomentarUlasan = 2000
+)
+
+var (
+	// input ulasan not valid, handler answer 400
+	ErrUlasanNotValid = errors.New("ulasan not valid")
+	// only user who bought the produk can rate it, handler answer 403
+	ErrUlasanNotBought = errors.New("only buyer of this produk can give ulasan")
+)
+
+type UlasanUsecase interface {
+	CreateUlasan(userID, produkID uint, rating int, komentar string) (model.UlasanProduk, error)
+	GetUlasan(produkID uint, pagination utils.PaginationInput) (utils.PaginationResult, error)
+}
+
+type ulasanUsecase struct {
+	ulasanRepo repository.UlasanRepository
+	produkRepo repository.ProdukRepository
+}
+
+func NewUlasanUsecase(ulasanRepo repository.UlasanRepository, produkRepo repository.ProdukRepository) UlasanUsecase {
+	return &ulasanUsecase{ulasanRepo, produkRepo}
+}
+
+// produk no longer sold can still be rated by who bought it
+func (uc *ulasanUsecase) CreateUlasan(userID, produkID uint, rating int, komentar string) (model.UlasanProduk, error) {
+	if rating < minRating || rating > maxRating {
+		return model.UlasanProduk{}, fmt.Errorf("%w: rating must be %d to %d", ErrUlasanNotValid, minRating, maxRating)
+	}
+	komentar = strings.TrimSpace(komentar)
+	if len([]rune(komentar)) > maxKomentarUlasan {
+		return model.UlasanProduk{}, fmt.Errorf("%w: komentar max %d character", ErrUlasanNotValid, maxKomentarUlasan)
+	}
+
+	if _, err := uc.produkRepo.FindByID(produkID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.UlasanProduk{}, errors.New("produk not found")
+		}
+		return model.UlasanProduk{}, fmt.Errorf("failed get produk: %w", err)
+	}
+	bought, err := uc.ulasanRepo.HasBought(userID, produkID)
+	if err != nil {
+		return model.UlasanProduk{}, fmt.Errorf("failed check trx: %w", err)
+	}
+	if !bought {
+		return model.UlasanProduk{}, ErrUlasanNotBought
+	}
+
+	now := time.Now()
+	ulasan, err := uc.ulasanRepo.Save(model.UlasanProduk{
+		IDUser:        userID,
+		IDProduk:      produkID,
+		Rating:        rating,
+		Komentar:      komentar,
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	})
+	if err != nil {
+		return ulasan, fmt.Errorf("failed save ulasan: %w", err)
+	}
+	return ulasan, nil
+}
+
+func (uc *ulasanUsecase) GetUlasan(produkID uint, pagination utils.PaginationInput) (utils.PaginationResult, error) {
+	ulasans, totalData, err := uc.ulasanRepo.FindByProdukID(produkID, pagination)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("failed get ulasan: %w", err)
+	}
+	return utils.GeneratePaginationResult(ulasans, totalData, pagination.Page, pagination.Limit), nil
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"rakamin-evermos/model"
+	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
+)
+
+type fakeUlasanRepo struct {
+	repository.UlasanRepository
+	bought  map[uint]bool // produk id bought by user 1
+	ulasans []model.UlasanProduk
+}
+
+func (r *fakeUlasanRepo) Save(ulasan model.UlasanProduk) (model.UlasanProduk, error) {
+	for i, existing := range r.ulasans {
+		if existing.IDUser == ulasan.IDUser && existing.IDProduk == ulasan.IDProduk {
+			ulasan.ID = existing.ID
+			r.ulasans[i] = ulasan
+			return ulasan, nil
+		}
+	}
+	ulasan.ID = uint(len(r.ulasans) + 1)
+	r.ulasans = append(r.ulasans, ulasan)
+	return ulasan, nil
+}
+
+func (r *fakeUlasanRepo) HasBought(userID, produkID uint) (bool, error) {
+	return userID == 1 && r.bought[produkID], nil
+}
+
+type fakeUlasanProdukRepo struct {
+	repository.ProdukRepository
+}
+
+func (r *fakeUlasanProdukRepo) FindByID(produkID uint) (model.Produk, error) {
+	if produkID > 2 {
+		return model.Produk{}, gorm.ErrRecordNotFound
+	}
+	return model.Produk{ID: produkID}, nil
+}
+
+func TestCreateUlasanOnlyByBuyer(t *testing.T) {
+	repo := &fakeUlasanRepo{bought: map[uint]bool{1: true}}
+	uc := NewUlasanUsecase(repo, &fakeUlasanProdukRepo{})
+
+	if _, err := uc.CreateUlasan(1, 1, 6, ""); !errors.Is(err, ErrUlasanNotValid) {
+		t.Fatalf("expected rating 6 refused, got %v", err)
+	}
+	if _, err := uc.CreateUlasan(1, 2, 5, ""); !errors.Is(err, ErrUlasanNotBought) {
+		t.Fatalf("expected produk not bought refused, got %v", err)
+	}
+	if _, err := uc.CreateUlasan(2, 1, 5, ""); !errors.Is(err, ErrUlasanNotBought) {
+		t.Fatalf("expected other user refused, got %v", err)
+	}
+	if _, err := uc.CreateUlasan(1, 9, 5, ""); err == nil {
+		t.Fatal("expected unknown produk refused")
+	}
+
+	if _, err := uc.CreateUlasan(1, 1, 4, " bagus "); err != nil {
+		t.Fatal(err)
+	}
+	// sent again replace the first one
+	if _, err := uc.CreateUlasan(1, 1, 2, "rusak"); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.ulasans) != 1 || repo.ulasans[0].Rating != 2 || repo.ulasans[0].Komentar != "rusak" {
+		t.Fatalf("expected one replaced ulasan, got %+v", repo.ulasans)
+	}
+}
@@ -7,19 +7,25 @@ import (
 
 	"rakamin-evermos/model" 
 	"rakamin-evermos/repository"
+
+	"gorm.io/gorm"
 )
 
 type UserUsecase interface {
 	GetProfile(userID uint) (model.User, error)
 	UpdateProfile(userID uint, updatedUser model.User) (model.User, error)
+
+	// for AuthMiddleware
+	CheckAccountActive(userID uint) error
 }
 
 type userUsecase struct {
-	userRepo repository.UserRepository
+	userRepo      repository.UserRepository
+	regionUsecase RegionUsecase
 }
 
-func NewUserUsecase(userRepo repository.UserRepository) UserUsecase {
-	return &userUsecase{userRepo}
+func NewUserUsecase(userRepo repository.UserRepository, regionUsecase RegionUsecase) UserUsecase {
+	return &userUsecase{userRepo, regionUsecase}
 }
 
 
@@ -37,6 +43,14 @@ func (uc *userUsecase) UpdateProfile(userID uint, updatedUser model.User) (model
 		return model.User{}, errors.New("profile user cant be found")
 	}
 
+	if updatedUser.IDProvinsi < 0 || updatedUser.IDKota < 0 {
+		return model.User{}, regionNotValid("region id cant be negative")
+	}
+	region := RegionInput{IDProvinsi: uint(updatedUser.IDProvinsi), IDKota: uint(updatedUser.IDKota)}
+	if err := uc.regionUsecase.ValidateRegion(region); err != nil {
+		return model.User{}, err
+	}
+
 	// the fields can be updated
 	existingUser.Nama = updatedUser.Nama
 	existingUser.NoTelp = updatedUser.NoTelp
@@ -55,4 +69,22 @@ func (uc *userUsecase) UpdateProfile(userID uint, updatedUser model.User) (model
 	}
 
 	return savedUser, nil
-}
\ No newline at end of file
+}
+
+// token stay valid after suspend, so status is checked on every request
+func (uc *userUsecase) CheckAccountActive(userID uint) error {
+	user, err := uc.userRepo.FindStatusByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("account not found")
+		}
+		return fmt.Errorf("failed check account: %w", err)
+	}
+	if user.AnonymizedAt != nil {
+		return errors.New("account has been deleted")
+	}
+	if user.SuspendedAt != nil {
+		return ErrAccountSuspended
+	}
+	return nil
+}
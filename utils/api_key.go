@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// api key format: rke_<prefix>_<secret>
+// prefix is not secret, used to show key in list
+const apiKeyPrefix = "rke_"
+
+func GenerateAPIKey() (rawKey string, prefix string, err error) {
+	buf := make([]byte, 30)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(buf)
+	// no "_" in prefix so the format stay parseable
+	encoded = strings.ReplaceAll(encoded, "_", "x")
+	prefix = apiKeyPrefix + encoded[:8]
+	return prefix + "_" + encoded[8:], prefix, nil
+}
+
+// api key has high entropy, sha256 is enough (no bcrypt, it is checked every request)
+func HashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func LooksLikeAPIKey(value string) bool {
+	return strings.HasPrefix(value, apiKeyPrefix)
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cursor from client broken, changed or from other list
+var ErrCursorNotValid = errors.New("cursor not valid")
+
+const (
+	defaultCursorLimit = 10
+	maxCursorLimit     = 100
+)
+
+// Cursor empty = first page
+type CursorInput struct {
+	Cursor string
+	Limit  int
+}
+
+// CursorPosition is sort key of last row in page, next page start after it.
+// Scope is list and sort the cursor made for
+type CursorPosition struct {
+	Scope string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    uint   `json:"id"`
+}
+
+// next_cursor nil = last page
+type CursorResult struct {
+	Data       interface{} `json:"data"`
+	NextCursor *string     `json:"next_cursor"`
+	HasMore    bool        `json:"has_more"`
+	Limit      int         `json:"limit"`
+}
+
+var (
+	cursorKey   []byte
+	cursorKeyMu sync.Mutex
+)
+
+// CURSOR_SECRET sign cursor, without it random key used and cursor only valid until restart
+func currentCursorKey() []byte {
+	cursorKeyMu.Lock()
+	defer cursorKeyMu.Unlock()
+	if cursorKey != nil {
+		return cursorKey
+	}
+	if secret := os.Getenv("CURSOR_SECRET"); secret != "" {
+		cursorKey = []byte(secret)
+		return cursorKey
+	}
+	cursorKey = make([]byte, 32)
+	if _, err := rand.Read(cursorKey); err != nil {
+		panic(fmt.Sprintf("failed generate cursor key: %v", err))
+	}
+	return cursorKey
+}
+
+func signCursor(payload string) string {
+	mac := hmac.New(sha256.New, currentCursorKey())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// opaque for client: base64 json + "." + hmac
+func EncodeCursor(position CursorPosition) (string, error) {
+	data, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + signCursor(payload), nil
+}
+
+// empty cursor return nil position (first page)
+func DecodeCursor(cursor string) (*CursorPosition, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	payload, signature, ok := strings.Cut(cursor, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signCursor(payload))) {
+		return nil, ErrCursorNotValid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrCursorNotValid
+	}
+	var position CursorPosition
+	if err := json.Unmarshal(data, &position); err != nil {
+		return nil, ErrCursorNotValid
+	}
+	return &position, nil
+}
+
+// cursor mode when query has 'cursor' (can be empty for first page), limit max 100
+func GetCursorFromQuery(c *gin.Context) (CursorInput, bool) {
+	cursor, ok := c.GetQuery("cursor")
+	if !ok {
+		return CursorInput{}, false
+	}
+
+	limit := defaultCursorLimit
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > maxCursorLimit {
+		limit = maxCursorLimit
+	}
+	return CursorInput{Cursor: cursor, Limit: limit}, true
+}
+
+// next nil = no page after this
+func GenerateCursorResult(data interface{}, next *CursorPosition, limit int) (CursorResult, error) {
+	result := CursorResult{Data: data, Limit: limit}
+	if next == nil {
+		return result, nil
+	}
+	cursor, err := EncodeCursor(*next)
+	if err != nil {
+		return result, fmt.Errorf("failed encode cursor: %w", err)
+	}
+	result.NextCursor = &cursor
+	result.HasMore = true
+	return result, nil
+}
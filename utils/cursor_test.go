@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor, err := EncodeCursor(CursorPosition{Scope: "produk:newest", Value: "2025-01-02T03:04:05Z", ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	position, err := DecodeCursor(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.Scope != "produk:newest" || position.Value != "2025-01-02T03:04:05Z" || position.ID != 42 {
+		t.Fatalf("unexpected position %+v", position)
+	}
+
+	if position, err := DecodeCursor(""); position != nil || err != nil {
+		t.Fatalf("empty cursor must be first page, got %+v, %v", position, err)
+	}
+}
+
+func TestCursorRejectTampered(t *testing.T) {
+	cursor, _ := EncodeCursor(CursorPosition{Scope: "trx", ID: 10})
+	forged, _ := EncodeCursor(CursorPosition{Scope: "trx", ID: 99})
+	payload, _, _ := strings.Cut(forged, ".")
+	_, signature, _ := strings.Cut(cursor, ".")
+
+	for _, bad := range []string{payload + "." + signature, payload, "not-a-cursor", cursor + "x"} {
+		if _, err := DecodeCursor(bad); !errors.Is(err, ErrCursorNotValid) {
+			t.Errorf("DecodeCursor(%q) error = %v, expected ErrCursorNotValid", bad, err)
+		}
+	}
+}
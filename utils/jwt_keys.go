@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JWTKey is one key in key set, PrivateKey nil mean verify only (retired key)
+type JWTKey struct {
+	Kid        string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// JWTKeySet hold signing key and all key still accepted for verify
+// if no asymmetric key configured, fallback to HS256 with JWT_SECRET
+// Issuer and Audience is put in every token and checked on validate
+type JWTKeySet struct {
+	SigningKey *JWTKey
+	Keys       map[string]*JWTKey
+	HMACSecret []byte
+	Issuer     string
+	Audience   string
+}
+
+const (
+	defaultJWTIssuer   = "rakamin-evermos"
+	defaultJWTAudience = "rakamin-evermos-api"
+)
+
+var (
+	jwtKeySet   *JWTKeySet
+	jwtKeySetMu sync.RWMutex
+)
+
+// load from env once, JWT_KEYS_DIR for RS256/EdDSA, else JWT_SECRET (HS256)
+func LoadJWTKeySetFromEnv() (*JWTKeySet, error) {
+	keySet := &JWTKeySet{
+		Keys:     map[string]*JWTKey{},
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+	}
+	if keySet.Issuer == "" {
+		keySet.Issuer = defaultJWTIssuer
+	}
+	if keySet.Audience == "" {
+		keySet.Audience = defaultJWTAudience
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		keySet.HMACSecret = []byte(secret)
+	}
+
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		if keySet.HMACSecret == nil {
+			return nil, fmt.Errorf("JWT_SECRET or JWT_KEYS_DIR not set in env")
+		}
+		return keySet, nil
+	}
+
+	keys, err := LoadJWTKeysFromDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	keySet.Keys = keys
+
+	signingKey, err := pickSigningKey(keys, os.Getenv("JWT_SIGNING_KID"))
+	if err != nil {
+		return nil, err
+	}
+	keySet.SigningKey = signingKey
+	return keySet, nil
+}
+
+// every <kid>.pem in dir, private key can sign and verify, public key only verify
+func LoadJWTKeysFromDir(dir string) (map[string]*JWTKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*JWTKey{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed read jwt key %s: %w", file, err)
+		}
+
+		kid := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".pem"), ".pub")
+		key, err := ParseJWTKeyPEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed parse jwt key %s: %w", file, err)
+		}
+
+		// private key win if both <kid>.pem and <kid>.pub.pem exist
+		if existing, ok := keys[kid]; ok && existing.PrivateKey != nil {
+			continue
+		}
+		keys[kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no jwt key found in %s", dir)
+	}
+	return keys, nil
+}
+
+func ParseJWTKeyPEM(kid string, data []byte) (*JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid pem")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &JWTKey{Kid: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T (only RSA and Ed25519)", parsed)
+	}
+	return key, nil
+}
+
+func pickSigningKey(keys map[string]*JWTKey, kid string) (*JWTKey, error) {
+	if kid != "" {
+		key, ok := keys[kid]
+		if !ok || key.PrivateKey == nil {
+			return nil, fmt.Errorf("private key for JWT_SIGNING_KID %q not found", kid)
+		}
+		return key, nil
+	}
+
+	var privateKids []string
+	for k, key := range keys {
+		if key.PrivateKey != nil {
+			privateKids = append(privateKids, k)
+		}
+	}
+	if len(privateKids) != 1 {
+		return nil, fmt.Errorf("found %d private jwt keys, set JWT_SIGNING_KID to choose one", len(privateKids))
+	}
+	sort.Strings(privateKids)
+	return keys[privateKids[0]], nil
+}
+
+// replace active key set (used on startup and in test)
+func SetJWTKeySet(keySet *JWTKeySet) {
+	jwtKeySetMu.Lock()
+	defer jwtKeySetMu.Unlock()
+	jwtKeySet = keySet
+}
+
+func currentJWTKeySet() (*JWTKeySet, error) {
+	jwtKeySetMu.RLock()
+	keySet := jwtKeySet
+	jwtKeySetMu.RUnlock()
+	if keySet != nil {
+		return keySet, nil
+	}
+
+	keySet, err := LoadJWTKeySetFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	SetJWTKeySet(keySet)
+	return keySet, nil
+}
+
+func (ks *JWTKeySet) sign(claims jwt.Claims) (string, error) {
+	if ks.SigningKey == nil {
+		if ks.HMACSecret == nil {
+			return "", fmt.Errorf("JWT_SECRET not set in env")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.HMACSecret)
+	}
+
+	token := jwt.NewWithClaims(ks.SigningKey.Method, claims)
+	token.Header["kid"] = ks.SigningKey.Kid
+	return token.SignedString(ks.SigningKey.PrivateKey)
+}
+
+// pick verify key from kid header, alg in header must match key type
+func (ks *JWTKeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// legacy HS256 token without kid
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && ks.HMACSecret != nil {
+			return ks.HMACSecret, nil
+		}
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	key, ok := ks.Keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+// public part of key set in JWKS format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func GetJWKS() (JWKS, error) {
+	keySet, err := currentJWTKeySet()
+	if err != nil {
+		return JWKS{}, err
+	}
+
+	kids := make([]string, 0, len(keySet.Keys))
+	for kid := range keySet.Keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		key := keySet.Keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks, nil
+}
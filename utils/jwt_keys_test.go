@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rsa private (PKCS1), ed25519 private (PKCS8) and rsa public only (retired key)
+func newTestKeyDir(t *testing.T) (string, *rsa.PrivateKey, *rsa.PrivateKey, ed25519.PrivateKey) {
+	t.Helper()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, "2024-01.pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, "2025-01.pem"), "PRIVATE KEY", edDER)
+
+	retiredKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&retiredKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, "2023-01.pub.pem"), "PUBLIC KEY", pubDER)
+
+	return dir, rsaKey, retiredKey, edKey
+}
+
+func newTestKeySet(t *testing.T, keys map[string]*JWTKey, signingKid string) *JWTKeySet {
+	t.Helper()
+	keySet := &JWTKeySet{Keys: keys, HMACSecret: []byte("legacy-secret"), Issuer: "test", Audience: "test-api"}
+	if signingKid != "" {
+		signingKey, err := pickSigningKey(keys, signingKid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keySet.SigningKey = signingKey
+	}
+	SetJWTKeySet(keySet)
+	t.Cleanup(func() { SetJWTKeySet(nil) })
+	return keySet
+}
+
+func TestLoadJWTKeysFromDir(t *testing.T) {
+	dir, _, _, _ := newTestKeyDir(t)
+	keys, err := LoadJWTKeysFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]struct {
+		alg     string
+		private bool
+	}{
+		"2023-01": {"RS256", false},
+		"2024-01": {"RS256", true},
+		"2025-01": {"EdDSA", true},
+	}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %d keys, got %d", len(expected), len(keys))
+	}
+	for kid, want := range expected {
+		key, ok := keys[kid]
+		if !ok {
+			t.Fatalf("key %s not loaded", kid)
+		}
+		if key.Method.Alg() != want.alg || (key.PrivateKey != nil) != want.private || key.PublicKey == nil {
+			t.Fatalf("key %s: unexpected %+v", kid, key)
+		}
+	}
+
+	if _, err := ParseJWTKeyPEM("x", []byte("not pem")); err == nil {
+		t.Fatal("invalid pem must be rejected")
+	}
+}
+
+func TestPickSigningKey(t *testing.T) {
+	dir, _, _, _ := newTestKeyDir(t)
+	keys, err := LoadJWTKeysFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pickSigningKey(keys, ""); err == nil {
+		t.Fatal("two private keys without kid must be rejected")
+	}
+	if key, err := pickSigningKey(keys, "2025-01"); err != nil || key.Kid != "2025-01" {
+		t.Fatalf("expected 2025-01, got %v %v", key, err)
+	}
+	for _, kid := range []string{"2023-01", "unknown"} {
+		if _, err := pickSigningKey(keys, kid); err == nil {
+			t.Fatalf("kid %s has no private key, must be rejected", kid)
+		}
+	}
+
+	delete(keys, "2024-01")
+	if key, err := pickSigningKey(keys, ""); err != nil || key.Kid != "2025-01" {
+		t.Fatalf("only private key must be picked, got %v %v", key, err)
+	}
+}
+
+func TestTokenVerifiedByKid(t *testing.T) {
+	dir, _, _, _ := newTestKeyDir(t)
+	keys, err := LoadJWTKeysFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// token signed before rotation still valid while old key in set
+	oldKeySet := newTestKeySet(t, keys, "2024-01")
+	oldToken, err := oldKeySet.sign(newClaims(oldKeySet, 7, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTestKeySet(t, keys, "2025-01")
+	newToken, err := GenerateToken(7, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tokenString := range map[string]string{"RS256": oldToken, "EdDSA": newToken} {
+		claims, err := ParseAccessToken(tokenString)
+		if err != nil || claims.UserID != 7 {
+			t.Fatalf("%s token must be valid, got %v %v", name, claims, err)
+		}
+	}
+
+	token, _, err := jwt.NewParser().ParseUnverified(newToken, &AccessClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != "2025-01" || token.Header["alg"] != "EdDSA" {
+		t.Fatalf("unexpected header %v", token.Header)
+	}
+
+	// key removed from set, token signed with it rejected
+	delete(keys, "2024-01")
+	if _, err := ParseAccessToken(oldToken); err == nil {
+		t.Fatal("token with unknown kid must be rejected")
+	}
+}
+
+func TestHS256TokenWithoutKid(t *testing.T) {
+	keySet := newTestKeySet(t, map[string]*JWTKey{}, "")
+	tokenString, err := GenerateToken(3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseAccessToken(tokenString)
+	if err != nil || claims.UserID != 3 || !claims.IsAdmin {
+		t.Fatalf("HS256 token must be valid, got %v %v", claims, err)
+	}
+
+	// asymmetric key configured, old HS256 token still accepted while JWT_SECRET set
+	dir, _, _, _ := newTestKeyDir(t)
+	keys, err := LoadJWTKeysFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTestKeySet(t, keys, "2025-01")
+	if _, err := ParseAccessToken(tokenString); err != nil {
+		t.Fatalf("legacy HS256 token must be valid: %v", err)
+	}
+
+	keySet.HMACSecret = nil
+	SetJWTKeySet(keySet)
+	if _, err := ParseAccessToken(tokenString); err == nil {
+		t.Fatal("HS256 token must be rejected without JWT_SECRET")
+	}
+}
+
+func TestRejectAlgConfusion(t *testing.T) {
+	dir, rsaKey, _, _ := newTestKeyDir(t)
+	keys, err := LoadJWTKeysFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySet := newTestKeySet(t, map[string]*JWTKey{}, "")
+	claims := newClaims(keySet, 1, time.Hour)
+
+	// RS256 token without kid against HS key set
+	rsToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseAccessToken(rsToken); err == nil {
+		t.Fatal("RS256 token must be rejected by HS256 key set")
+	}
+
+	// HS256 token signed with public rsa key as secret, kid of rsa key
+	newTestKeySet(t, keys, "2024-01")
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range [][]byte{pubDER, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})} {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = "2024-01"
+		hsToken, err := token.SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParseAccessToken(hsToken); err == nil {
+			t.Fatal("HS256 token with rsa kid must be rejected")
+		}
+	}
+}
+
+func TestGetJWKS(t *testing.T) {
+	dir, rsaKey, _, edKey := newTestKeyDir(t)
+	keys, err := LoadJWTKeysFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTestKeySet(t, keys, "2025-01")
+
+	jwks, err := GetJWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 3 || jwks.Keys[0].Kid != "2023-01" || jwks.Keys[1].Kid != "2024-01" || jwks.Keys[2].Kid != "2025-01" {
+		t.Fatalf("expected all keys sorted by kid, got %+v", jwks.Keys)
+	}
+
+	rsaJWK := jwks.Keys[1]
+	if rsaJWK.Kty != "RSA" || rsaJWK.Alg != "RS256" || rsaJWK.Use != "sig" || rsaJWK.E != "AQAB" ||
+		rsaJWK.N != base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()) {
+		t.Fatalf("unexpected rsa jwk %+v", rsaJWK)
+	}
+	edJWK := jwks.Keys[2]
+	if edJWK.Kty != "OKP" || edJWK.Crv != "Ed25519" || edJWK.Alg != "EdDSA" ||
+		edJWK.X != base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey)) {
+		t.Fatalf("unexpected ed25519 jwk %+v", edJWK)
+	}
+}
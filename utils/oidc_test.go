@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rakamin-evermos/utils/oidcmock"
+)
+
+func newTestOIDCProvider(t *testing.T) (*OIDCProvider, *oidcmock.Server) {
+	t.Helper()
+	idp, testServer, err := oidcmock.NewTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(testServer.Close)
+
+	idp.AddClient("evermos", "client-secret", "http://localhost/callback")
+	provider := &OIDCProvider{
+		Name:         "mock",
+		Issuer:       idp.Issuer,
+		ClientID:     "evermos",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://localhost/callback",
+		Scopes:       []string{"openid", "email"},
+	}
+	return provider, idp
+}
+
+func TestPKCEChallengeRFCVector(t *testing.T) {
+	// RFC 7636 appendix B
+	if got := PKCEChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Fatalf("unexpected challenge %s", got)
+	}
+}
+
+func TestOIDCProviderCodeFlow(t *testing.T) {
+	provider, _ := newTestOIDCProvider(t)
+	ctx := context.Background()
+
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := oidcmock.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state not returned, got %q", state)
+	}
+
+	idToken, err := provider.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, idToken, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "mock-user-1" || !claims.EmailVerified || claims.Email == "" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestOIDCProviderRejectsWrongVerifier(t *testing.T) {
+	provider, _ := newTestOIDCProvider(t)
+	ctx := context.Background()
+
+	_, challenge, _ := GeneratePKCE()
+	authURL, _ := provider.AuthCodeURL(ctx, "state", "nonce", challenge)
+	code, _, err := oidcmock.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherVerifier, _, _ := GeneratePKCE()
+	if _, err := provider.Exchange(ctx, code, otherVerifier); err == nil {
+		t.Fatal("exchange with wrong code_verifier must fail")
+	}
+}
+
+func TestOIDCProviderRefetchJWKSAfterRotation(t *testing.T) {
+	provider, idp := newTestOIDCProvider(t)
+	ctx := context.Background()
+
+	login := func() error {
+		verifier, challenge, _ := GeneratePKCE()
+		authURL, _ := provider.AuthCodeURL(ctx, "state", "nonce", challenge)
+		code, _, err := oidcmock.Authorize(authURL)
+		if err != nil {
+			return err
+		}
+		idToken, err := provider.Exchange(ctx, code, verifier)
+		if err != nil {
+			return err
+		}
+		_, err = provider.VerifyIDToken(ctx, idToken, "nonce")
+		return err
+	}
+
+	if err := login(); err != nil {
+		t.Fatal(err)
+	}
+	if err := idp.RotateKey("mock-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// jwks just fetched, unknown kid not fetched again until interval passed
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+	if err := login(); err == nil {
+		t.Fatal("unknown kid must not refetch jwks within interval")
+	}
+	now = now.Add(jwksRefetchInterval)
+	if err := login(); err != nil {
+		t.Fatalf("login after key rotation: %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+// Package oidcmock is a small in-process OpenID Connect provider for test and local development.
+// It support discovery, authorization code flow with PKCE (S256), token endpoint and JWKS.
+// /authorize auto approve the login as the user set with SetUser, no login page.
+package oidcmock
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// User is identity returned in id_token
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type client struct {
+	secret      string
+	redirectURL string
+}
+
+type authCode struct {
+	clientID      string
+	redirectURL   string
+	codeChallenge string
+	nonce         string
+	user          User
+	expiresAt     time.Time
+}
+
+type Server struct {
+	Issuer string
+
+	mu      sync.Mutex
+	key     *rsa.PrivateKey
+	kid     string
+	clients map[string]client
+	codes   map[string]authCode
+	user    User
+
+	// IDTokenHook can change claims before id_token signed, used to test bad token
+	IDTokenHook func(claims jwt.MapClaims)
+}
+
+// NewServer create provider with given issuer url, serve it with Handler()
+func NewServer(issuer string) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		Issuer:  issuer,
+		key:     key,
+		kid:     "mock-1",
+		clients: map[string]client{},
+		codes:   map[string]authCode{},
+		user:    User{Subject: "mock-user-1", Email: "mock.user@example.com", EmailVerified: true, Name: "Mock User"},
+	}, nil
+}
+
+// NewTestServer start provider in httptest server, issuer is the server url
+func NewTestServer() (*Server, *httptest.Server, error) {
+	var server *Server
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.Handler().ServeHTTP(w, r)
+	}))
+
+	server, err := NewServer(testServer.URL)
+	if err != nil {
+		testServer.Close()
+		return nil, nil, err
+	}
+	return server, testServer, nil
+}
+
+func (s *Server) AddClient(clientID, clientSecret, redirectURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[clientID] = client{secret: clientSecret, redirectURL: redirectURL}
+}
+
+// SetUser set who is "logged in" at the provider for next authorization
+func (s *Server) SetUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = user
+}
+
+// RotateKey replace signing key with new kid, old token cant be verified anymore
+func (s *Server) RotateKey(kid string) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = key
+	s.kid = kid
+	return nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("/authorize", s.handleAuthorize)
+	mux.HandleFunc("/token", s.handleToken)
+	mux.HandleFunc("/jwks", s.handleJWKS)
+	return mux
+}
+
+// Authorize follow authorization url like a browser, return code and state from redirect
+func Authorize(authURL string) (code string, state string, err error) {
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := httpClient.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return "", "", fmt.Errorf("authorize return status %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", "", err
+	}
+	if errCode := location.Query().Get("error"); errCode != "" {
+		return "", "", errors.New(errCode)
+	}
+	return location.Query().Get("code"), location.Query().Get("state"), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.Issuer,
+		"authorization_endpoint":                s.Issuer + "/authorize",
+		"token_endpoint":                        s.Issuer + "/token",
+		"jwks_uri":                              s.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registered, ok := s.clients[query.Get("client_id")]
+	if !ok || registered.redirectURL != query.Get("redirect_uri") {
+		// never redirect to unknown redirect_uri
+		writeError(w, http.StatusBadRequest, "invalid_client", "unknown client or redirect_uri")
+		return
+	}
+
+	redirect, _ := url.Parse(registered.redirectURL)
+	params := redirect.Query()
+	params.Set("state", query.Get("state"))
+
+	switch {
+	case query.Get("response_type") != "code":
+		params.Set("error", "unsupported_response_type")
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		params.Set("error", "invalid_request")
+	default:
+		code := randomString()
+		s.codes[code] = authCode{
+			clientID:      query.Get("client_id"),
+			redirectURL:   registered.redirectURL,
+			codeChallenge: query.Get("code_challenge"),
+			nonce:         query.Get("nonce"),
+			user:          s.user,
+			expiresAt:     time.Now().Add(time.Minute),
+		}
+		params.Set("code", code)
+	}
+
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "POST only")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	clientID, clientSecret, hasBasic := r.BasicAuth()
+	if hasBasic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registered, ok := s.clients[clientID]
+	if !ok || (registered.secret != "" && registered.secret != clientSecret) {
+		writeError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+
+	// code is single use, even when the exchange failed
+	code, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	if !ok || time.Now().After(code.expiresAt) || code.clientID != clientID || code.redirectURL != r.PostForm.Get("redirect_uri") {
+		writeError(w, http.StatusBadRequest, "invalid_grant", "code not valid")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != code.codeChallenge {
+		writeError(w, http.StatusBadRequest, "invalid_grant", "code_verifier not match")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.Issuer,
+		"sub":            code.user.Subject,
+		"aud":            clientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          code.nonce,
+		"email":          code.user.Email,
+		"email_verified": code.user.EmailVerified,
+		"name":           code.user.Name,
+	}
+	if s.IDTokenHook != nil {
+		s.IDTokenHook(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	publicKey := s.key.PublicKey
+	kid := s.kid
+	s.mu.Unlock()
+
+	// key_ops and x5c array like Azure AD / Okta, client must not choke on it
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]interface{}{{
+			"kty":     "RSA",
+			"use":     "sig",
+			"alg":     "RS256",
+			"kid":     kid,
+			"key_ops": []string{"verify"},
+			"x5c":     []string{},
+			"n":       base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			"e":       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}},
+	})
+}
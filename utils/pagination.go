@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"rakamin-evermos/model"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
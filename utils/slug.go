@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// lowercase ascii letter and digit joined by "-", e.g. "Budi's Toko!" -> "budi-s-toko"
+func Slugify(text string) string {
+	var builder strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(text) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			builder.WriteRune(r)
+			lastDash = false
+			continue
+		}
+		if !lastDash {
+			builder.WriteByte('-')
+			lastDash = true
+		}
+	}
+	return strings.TrimSuffix(builder.String(), "-")
+}
@@ -0,0 +1,18 @@
+package utils
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Budi's Toko":          "budi-s-toko",
+		"  Toko   Serba Ada  ": "toko-serba-ada",
+		"Kopi Kenangan 99!":    "kopi-kenangan-99",
+		"Café Ñandú":           "caf-and",
+		"---":                  "",
+	}
+	for input, expected := range cases {
+		if got := Slugify(input); got != expected {
+			t.Errorf("Slugify(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// format file of import / export produk
+const (
+	SpreadsheetCSV  = "csv"
+	SpreadsheetXLSX = "xlsx"
+)
+
+var ErrSpreadsheetFormat = errors.New("format must be csv or xlsx")
+
+// csv or xlsx from file name, empty when other
+func SpreadsheetFormatFromName(fileName string) string {
+	name := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(name, ".csv"):
+		return SpreadsheetCSV
+	case strings.HasSuffix(name, ".xlsx"):
+		return SpreadsheetXLSX
+	}
+	return ""
+}
+
+// xlsx is zip, limit unpacked size so small upload can't fill memory or disk
+const (
+	xlsxUnzipSizeLimit    = 100 << 20
+	xlsxUnzipXMLSizeLimit = 16 << 20 // sheet bigger than this unpacked to temp file
+)
+
+var ErrSpreadsheetTooManyRows = errors.New("spreadsheet has too many rows")
+
+// cell starting with these run as formula when opened in spreadsheet app
+const formulaPrefixes = "=+-@\t\r"
+
+// prefix ' so cell shown as text, not run as formula
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// reverse escapeFormula, exported file can be imported again unchanged
+func unescapeFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func isEmptyRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// all row of csv or first sheet of xlsx, header included.
+// stop with ErrSpreadsheetTooManyRows after maxRows non empty row
+func ReadSpreadsheet(path, format string, maxRows int) ([][]string, error) {
+	rows := [][]string{}
+	filled := 0
+	add := func(row []string) error {
+		if !isEmptyRow(row) {
+			filled++
+			if filled > maxRows {
+				return ErrSpreadsheetTooManyRows
+			}
+		}
+		for i, value := range row {
+			row[i] = unescapeFormula(value)
+		}
+		rows = append(rows, row)
+		return nil
+	}
+
+	switch format {
+	case SpreadsheetCSV:
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1 // empty cell at end of row can be missing
+		nextLine := 1
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed read csv: %w", err)
+			}
+			// csv reader skip blank line, keep it as empty row so row number same as in spreadsheet app
+			line, _ := reader.FieldPos(0)
+			for ; nextLine < line; nextLine++ {
+				rows = append(rows, []string{})
+			}
+			last := len(record) - 1
+			lastLine, _ := reader.FieldPos(last)
+			nextLine = lastLine + strings.Count(record[last], "\n") + 1
+			// excel save csv with utf-8 BOM
+			if len(rows) == 0 {
+				record[0] = strings.TrimPrefix(record[0], "\ufeff")
+			}
+			if err := add(record); err != nil {
+				return nil, err
+			}
+		}
+		return rows, nil
+	case SpreadsheetXLSX:
+		file, err := excelize.OpenFile(path, excelize.Options{
+			UnzipSizeLimit:    xlsxUnzipSizeLimit,
+			UnzipXMLSizeLimit: xlsxUnzipXMLSizeLimit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed read xlsx: %w", err)
+		}
+		defer file.Close()
+
+		// read row by row, whole sheet never loaded at once
+		sheetRows, err := file.Rows(file.GetSheetName(0))
+		if err != nil {
+			return nil, fmt.Errorf("failed read xlsx: %w", err)
+		}
+		defer sheetRows.Close()
+		for sheetRows.Next() {
+			row, err := sheetRows.Columns()
+			if err != nil {
+				return nil, fmt.Errorf("failed read xlsx: %w", err)
+			}
+			if err := add(row); err != nil {
+				return nil, err
+			}
+		}
+		if err := sheetRows.Error(); err != nil {
+			return nil, fmt.Errorf("failed read xlsx: %w", err)
+		}
+		// same as csv, trailing empty row not returned
+		for len(rows) > 0 && len(rows[len(rows)-1]) == 0 {
+			rows = rows[:len(rows)-1]
+		}
+		return rows, nil
+	}
+	return nil, ErrSpreadsheetFormat
+}
+
+// SpreadsheetWriter write row one by one, first row is header and every cell written as text.
+// Flush write what is left to the writer, Close release temp file of xlsx and must always be called
+type SpreadsheetWriter interface {
+	WriteRow(row []string) error
+	Flush() error
+	Close() error
+}
+
+func NewSpreadsheetWriter(w io.Writer, format string) (SpreadsheetWriter, error) {
+	switch format {
+	case SpreadsheetCSV:
+		return &csvSpreadsheetWriter{csv.NewWriter(w)}, nil
+	case SpreadsheetXLSX:
+		file := excelize.NewFile()
+		stream, err := file.NewStreamWriter(file.GetSheetName(0))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed write xlsx: %w", err)
+		}
+		return &xlsxSpreadsheetWriter{w: w, file: file, stream: stream}, nil
+	}
+	return nil, ErrSpreadsheetFormat
+}
+
+type csvSpreadsheetWriter struct {
+	writer *csv.Writer
+}
+
+func (sw *csvSpreadsheetWriter) WriteRow(row []string) error {
+	cells := make([]string, len(row))
+	for i, value := range row {
+		cells[i] = escapeFormula(value)
+	}
+	if err := sw.writer.Write(cells); err != nil {
+		return fmt.Errorf("failed write csv: %w", err)
+	}
+	return nil
+}
+
+func (sw *csvSpreadsheetWriter) Flush() error {
+	sw.writer.Flush()
+	if err := sw.writer.Error(); err != nil {
+		return fmt.Errorf("failed write csv: %w", err)
+	}
+	return nil
+}
+
+func (sw *csvSpreadsheetWriter) Close() error {
+	return nil
+}
+
+// stream writer keep row in temp file, sheet only written to w on Flush
+type xlsxSpreadsheetWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	next   int
+}
+
+func (sw *xlsxSpreadsheetWriter) WriteRow(row []string) error {
+	cells := make([]interface{}, len(row))
+	for i, value := range row {
+		cells[i] = escapeFormula(value)
+	}
+	sw.next++
+	cell, err := excelize.CoordinatesToCellName(1, sw.next)
+	if err != nil {
+		return fmt.Errorf("failed write xlsx: %w", err)
+	}
+	if err := sw.stream.SetRow(cell, cells); err != nil {
+		return fmt.Errorf("failed write xlsx: %w", err)
+	}
+	return nil
+}
+
+func (sw *xlsxSpreadsheetWriter) Flush() error {
+	if err := sw.stream.Flush(); err != nil {
+		return fmt.Errorf("failed write xlsx: %w", err)
+	}
+	if _, err := sw.file.WriteTo(sw.w); err != nil {
+		return fmt.Errorf("failed write xlsx: %w", err)
+	}
+	return nil
+}
+
+func (sw *xlsxSpreadsheetWriter) Close() error {
+	return sw.file.Close()
+}
+
+// all row at once, see SpreadsheetWriter
+func WriteSpreadsheet(w io.Writer, format string, rows [][]string) error {
+	writer, err := NewSpreadsheetWriter(w, format)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	for _, row := range rows {
+		if err := writer.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
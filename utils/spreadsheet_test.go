@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSpreadsheetRoundTrip(t *testing.T) {
+	rows := [][]string{
+		{"id", "nama_produk", "deskripsi"},
+		{"1", "Laptop", "ram 16GB, ssd"},
+		{"2", "Kaos", "baris 1\nbaris 2"},
+		{"3", "=HYPERLINK(\"http://evil\")", "-5% diskon"},
+	}
+	for _, format := range []string{SpreadsheetCSV, SpreadsheetXLSX} {
+		var buffer bytes.Buffer
+		if err := WriteSpreadsheet(&buffer, format, rows); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		path := filepath.Join(t.TempDir(), "produk."+format)
+		if err := os.WriteFile(path, buffer.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ReadSpreadsheet(path, format, 10)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, rows) {
+			t.Fatalf("%s: expected %q, got %q", format, rows, got)
+		}
+	}
+}
+
+func TestReadCSVKeepBlankRow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "produk.csv")
+	content := "\ufeffnama,deskripsi\n\"Laptop\",\"baris 1\nbaris 2\"\n\nKaos,katun\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := ReadSpreadsheet(path, SpreadsheetCSV, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"nama", "deskripsi"},
+		{"Laptop", "baris 1\nbaris 2"},
+		{},
+		{"Kaos", "katun"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("expected %q, got %q", want, rows)
+	}
+}
+
+func TestWriteSpreadsheetEscapeFormula(t *testing.T) {
+	rows := [][]string{
+		{"nama", "deskripsi"},
+		{"=1+1", "+62 812"},
+		{"@SUM(A1)", "\tcmd"},
+		{"Laptop", "a=b"},
+	}
+	var buffer bytes.Buffer
+	if err := WriteSpreadsheet(&buffer, SpreadsheetCSV, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := "nama,deskripsi\n'=1+1,'+62 812\n'@SUM(A1),'\tcmd\nLaptop,a=b\n"
+	if buffer.String() != want {
+		t.Fatalf("expected %q, got %q", want, buffer.String())
+	}
+}
+
+func TestReadSpreadsheetStopAfterMaxRows(t *testing.T) {
+	rows := [][]string{{"nama"}, {"Laptop"}, {}, {"Kaos"}, {"Topi"}}
+	for _, format := range []string{SpreadsheetCSV, SpreadsheetXLSX} {
+		var buffer bytes.Buffer
+		if err := WriteSpreadsheet(&buffer, format, rows); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		path := filepath.Join(t.TempDir(), "produk."+format)
+		if err := os.WriteFile(path, buffer.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		// empty row not counted
+		if got, err := ReadSpreadsheet(path, format, 4); err != nil || len(got) != 5 {
+			t.Fatalf("%s: expected 5 row, got %q %v", format, got, err)
+		}
+		if _, err := ReadSpreadsheet(path, format, 3); !errors.Is(err, ErrSpreadsheetTooManyRows) {
+			t.Fatalf("%s: expected ErrSpreadsheetTooManyRows, got %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Package testdb open in-memory sqlite database for repository and usecase test.
+// Only query without mysql only syntax (FIELD, MATCH AGAINST) can be tested with it, row lock is ignored.
+package testdb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Open return fresh database of the test with table of models, closed when test end
+func Open(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", "#", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one connection, so every query see the same in-memory database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	for _, m := range models {
+		if err := dropFulltextClass(db, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// sqlite has no FULLTEXT index, make it normal index in the schema cached by db.
+// AutoMigrate also create table of related model, so their schema changed too
+func dropFulltextClass(db *gorm.DB, m interface{}) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(m); err != nil {
+		return err
+	}
+	dropSchemaFulltext(stmt.Schema, map[*schema.Schema]bool{})
+	return nil
+}
+
+func dropSchemaFulltext(s *schema.Schema, done map[*schema.Schema]bool) {
+	if done[s] {
+		return
+	}
+	done[s] = true
+	for _, field := range s.Fields {
+		field.Tag = reflect.StructTag(strings.ReplaceAll(string(field.Tag), ",class:FULLTEXT", ""))
+	}
+	for _, rel := range s.Relationships.Relations {
+		dropSchemaFulltext(rel.FieldSchema, done)
+	}
+}
@@ -1,26 +1,55 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
-func GenerateToken(userID uint, isAdmin bool) (string, error) {
-	claims := jwt.MapClaims{}
-	claims["user_id"] = userID
-	claims["is_admin"] = isAdmin
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
+// short lived token after password ok but 2FA code still needed
+// cant be used as access token (ParseAccessToken reject token with purpose)
+const ChallengeTokenPurpose = "2fa_challenge"
+
+// AccessClaims is typed claims of our token
+// user_id kept for old client, sub is the source of truth
+type AccessClaims struct {
+	UserID  uint   `json:"user_id"`
+	IsAdmin bool   `json:"is_admin"`
+	Purpose string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func newClaims(keySet *JWTKeySet, userID uint, ttl time.Duration) AccessClaims {
+	now := time.Now()
+	return AccessClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    keySet.Issuer,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Audience:  jwt.ClaimStrings{keySet.Audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.New().String(),
+		},
+	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return "", fmt.Errorf("JWT_SECRET not set in env")
+// signed with active key from JWTKeySet (RS256/EdDSA with kid, or HS256 JWT_SECRET)
+func GenerateToken(userID uint, isAdmin bool) (string, error) {
+	keySet, err := currentJWTKeySet()
+	if err != nil {
+		return "", err
 	}
 
-	signedToken, err := token.SignedString([]byte(jwtSecret))
+	claims := newClaims(keySet, userID, time.Hour*24)
+	claims.IsAdmin = isAdmin
+
+	signedToken, err := keySet.sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -28,20 +57,78 @@ func GenerateToken(userID uint, isAdmin bool) (string, error) {
 	return signedToken, nil
 }
 
-func ValidateToken(tokenString string) (*jwt.Token, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
+func GenerateChallengeToken(userID uint) (string, error) {
+	keySet, err := currentJWTKeySet()
+	if err != nil {
+		return "", err
+	}
+
+	claims := newClaims(keySet, userID, time.Minute*5)
+	claims.Purpose = ChallengeTokenPurpose
+
+	return keySet.sign(claims)
+}
+
+// verify signature, exp/nbf/iat, and iss, aud, sub, jti against config
+func parseClaims(tokenString string) (*AccessClaims, error) {
+	keySet, err := currentJWTKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keySet.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("token is invalid")
+	}
+
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		return nil, errors.New("token missing exp or iat")
+	}
+	if !claims.VerifyIssuer(keySet.Issuer, true) {
+		return nil, errors.New("token has invalid issuer")
+	}
+	if !claims.VerifyAudience(keySet.Audience, true) {
+		return nil, errors.New("token has invalid audience")
+	}
+	if claims.ID == "" {
+		return nil, errors.New("token missing jti")
+	}
+
+	subject, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || subject == 0 {
+		return nil, errors.New("token has invalid subject")
+	}
+	if claims.UserID != 0 && claims.UserID != uint(subject) {
+		return nil, errors.New("token subject and user_id not match")
+	}
+	claims.UserID = uint(subject)
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// HS256 check 
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(jwtSecret), nil
-	})
+	return claims, nil
+}
 
+// for AuthMiddleware, challenge token is rejected
+func ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	claims, err := parseClaims(tokenString)
 	if err != nil {
 		return nil, err
 	}
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("token with purpose %q cant be used as access token", claims.Purpose)
+	}
+	return claims, nil
+}
 
-	return token, nil
-}
\ No newline at end of file
+func ValidateChallengeToken(tokenString string) (uint, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	if claims.Purpose != ChallengeTokenPurpose {
+		return 0, errors.New("challenge token not valid")
+	}
+	return claims.UserID, nil
+}
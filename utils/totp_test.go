@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B secret for SHA1, "12345678901234567890" in base32
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCodeRFC6238(t *testing.T) {
+	// 8 digit value from RFC, 6 digit code is the last 6 digit
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, v := range vectors {
+		code, err := GenerateTOTPCode(rfcTOTPSecret, TOTPStep(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Fatalf("time %d: expected %s, got %s", v.unix, v.code, code)
+		}
+	}
+}
+
+func TestValidateTOTPCodeSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := TOTPStep(now)
+
+	for _, offset := range []int64{-1, 0, 1} {
+		code, _ := GenerateTOTPCode(rfcTOTPSecret, current+offset)
+		step, ok := ValidateTOTPCode(rfcTOTPSecret, code, now, 1)
+		if !ok || step != current+offset {
+			t.Fatalf("offset %d: expected step %d accepted, got %d %v", offset, current+offset, step, ok)
+		}
+	}
+	for _, offset := range []int64{-2, 2} {
+		code, _ := GenerateTOTPCode(rfcTOTPSecret, current+offset)
+		if _, ok := ValidateTOTPCode(rfcTOTPSecret, code, now, 1); ok {
+			t.Fatalf("offset %d: code outside skew must be rejected", offset)
+		}
+	}
+	if _, ok := ValidateTOTPCode(rfcTOTPSecret, "12345", now, 1); ok {
+		t.Fatal("short code must be rejected")
+	}
+}